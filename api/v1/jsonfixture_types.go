@@ -0,0 +1,75 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// JsonFixtureSpec holds collections shared by the JsonServers of the namespace.
+type JsonFixtureSpec struct {
+	// Collections of records, or singular resources, by name. JsonServers add them to their
+	// configuration with spec.collections[].fixtureRef.
+	// +kubebuilder:validation:MinProperties=1
+	Collections map[string]FixtureCollection `json:"collections"`
+}
+
+// FixtureCollection is a collection of a JsonFixture.
+// +kubebuilder:validation:XValidation:rule="!has(self.columns) || (has(self.format) && self.format == 'CSV')",message="columns only apply to the CSV format"
+type FixtureCollection struct {
+	// Format of data: JSON, YAML, JSON5, NDJSON or CSV, see the collections of a JsonServer
+	// +kubebuilder:validation:Enum=JSON;YAML;JSON5;NDJSON;CSV
+	// +kubebuilder:default=JSON
+	// +optional
+	Format string `json:"format,omitempty"`
+
+	// Data is the collection, written in its format
+	// +kubebuilder:validation:MinLength=1
+	Data string `json:"data"`
+
+	// Columns types the values of CSV columns, which are strings otherwise
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Columns []CSVColumn `json:"columns,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// JsonFixture is the Schema for the jsonfixtures API. It holds collections, such as users,
+// that several JsonServers of its namespace serve along with their own collections. Changes
+// are rolled out to the JsonServers referencing it.
+type JsonFixture struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec JsonFixtureSpec `json:"spec"`
+}
+
+// +kubebuilder:object:root=true
+
+// JsonFixtureList contains a list of JsonFixture.
+type JsonFixtureList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []JsonFixture `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&JsonFixture{}, &JsonFixtureList{})
+}
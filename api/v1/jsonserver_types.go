@@ -17,22 +17,738 @@ limitations under the License.
 package v1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+// DefaultImage is the json-server container image run by the JsonServers. It is pinned to a
+// version so that upgrading the operator is the only way to upgrade the JsonServers using it.
+const DefaultImage = "backplane/json-server:0.17.4"
+
+// EphemeralNamespaceLabel marks the namespaces whose JsonServers expire after the operator-wide
+// ephemeral TTL when they set none, e.g. example.example.com/ephemeral=true
+const EphemeralNamespaceLabel = "example.example.com/ephemeral"
+
+// ActivatedAtAnnotation is set by the activator of an idle JsonServer to the time it received a
+// request, so that the pods are scaled back up
+const ActivatedAtAnnotation = "example.example.com/activated-at"
+
+// ResetRequestedAtAnnotation requests a reset of the data of a JsonServer to its configuration.
+// Any new value, e.g. the current time, triggers one reset.
+const ResetRequestedAtAnnotation = "example.example.com/reset-requested-at"
+
 // JsonServerSpec defines the desired state of JsonServer.
+// +kubebuilder:validation:XValidation:rule="!(has(self.jsonConfig) && has(self.dataFrom))",message="only one of jsonConfig or dataFrom can be set"
+// +kubebuilder:validation:XValidation:rule="has(self.jsonConfig) || has(self.dataFrom) || has(self.collections) || has(self.generate)",message="one of jsonConfig, dataFrom, collections or generate must be set"
 type JsonServerSpec struct {
 	// Replicas is the number of instances of the JsonServer to run
 	// +kubebuilder:validation:Minimum=1
 	Replicas int32 `json:"replicas"`
 
-	// JsonConfig is the JSON configuration to be served by the JsonServer
-	JsonConfig string `json:"jsonConfig"`
+	// Image is the json-server image, the operator default when empty. Pin a digest
+	// (image@sha256:...) for reproducible deployments.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// JsonConfig is the JSON configuration to be served by the JsonServer, written in the
+	// format of spec.format
+	// +optional
+	JsonConfig string `json:"jsonConfig,omitempty"`
+
+	// DataFrom reads the JSON configuration from another object or a URL instead of jsonConfig
+	// +optional
+	DataFrom *DataSource `json:"dataFrom,omitempty"`
+
+	// Format of jsonConfig, or of the document read from dataFrom: JSON, YAML or JSON5 (JSON
+	// with comments, trailing commas and unquoted keys). The operator converts it into the
+	// db.json served. Snapshots are always JSON.
+	// +kubebuilder:validation:Enum=JSON;YAML;JSON5
+	// +optional
+	Format string `json:"format,omitempty"`
+
+	// Collections adds collections, or singular resources, to the configuration by name, each
+	// written in its own format or read from another object. They are merged in the order of
+	// their names, and the configuration must not define them already.
+	// +optional
+	Collections map[string]CollectionSource `json:"collections,omitempty"`
+
+	// Generate adds collections of fake records, rendered from a template, to the configuration
+	// by name. Their names must not be used by the configuration or by spec.collections.
+	// +optional
+	Generate map[string]GeneratedCollection `json:"generate,omitempty"`
+
+	// RevisionHistoryLimit is the number of configuration revisions kept for rollbacks,
+	// including the one being served
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=10
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// RollbackTo serves the configuration revision with this hash, as listed in
+	// status.revisions, instead of the configuration of jsonConfig or dataFrom.
+	// Remove it to serve the configuration of the spec again.
+	// +kubebuilder:validation:Pattern=`^[0-9a-f]{16}$`
+	// +optional
+	RollbackTo string `json:"rollbackTo,omitempty"`
+
+	// ResetSchedule restores the data changed through the API to the configuration on a
+	// cron schedule, e.g. "0 3 * * *" or "@daily". Times are in UTC unless the schedule
+	// starts with CRON_TZ=<time zone>. The pods are rolled and their volumes seeded again.
+	// +optional
+	ResetSchedule string `json:"resetSchedule,omitempty"`
+
+	// TTLSecondsAfterCreation deletes the JsonServer this many seconds after its creation.
+	// JsonServers of namespaces labeled example.example.com/ephemeral=true default to the
+	// ephemeral TTL of the operator configuration.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TTLSecondsAfterCreation *int32 `json:"ttlSecondsAfterCreation,omitempty"`
+
+	// TTLSecondsAfterLastRequest deletes the JsonServer when its pods served no request for
+	// this many seconds. The requests are read from the logs of the pods.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TTLSecondsAfterLastRequest *int32 `json:"ttlSecondsAfterLastRequest,omitempty"`
+
+	// SnapshotOnExpiry captures the data of the JsonServer in a JsonServerSnapshot named
+	// <name>-expired-<first 8 characters of the uid> before deleting it at expiry. The snapshot
+	// is kept.
+	// +optional
+	SnapshotOnExpiry bool `json:"snapshotOnExpiry,omitempty"`
+
+	// Persistence keeps the changes made through the API on a persistent volume. The
+	// JsonServer then runs as a StatefulSet whose volumes are seeded with the configuration.
+	// +optional
+	Persistence *PersistenceSpec `json:"persistence,omitempty"`
+
+	// Consistency tells how replicas share the data changed through the API:
+	// SingleWriter sends writes to the first replica through the <name>-write Service and
+	// syncs the other replicas, which are read-only, from it, Sticky keeps each client on the same replica and
+	// ReadOnly rejects the requests changing the data. When empty each replica keeps its own copy.
+	// +kubebuilder:validation:Enum=SingleWriter;Sticky;ReadOnly
+	// +optional
+	Consistency string `json:"consistency,omitempty"`
+
+	// Expose makes the JsonServer reachable from outside the cluster through an Ingress
+	// or a Gateway API HTTPRoute
+	// +optional
+	Expose *ExposeSpec `json:"expose,omitempty"`
+
+	// Service customizes the Service exposing the json-server pods in the cluster
+	// +optional
+	Service *ServiceSpec `json:"service,omitempty"`
+
+	// Server configures json-server itself: routes, conventions of the data and options
+	// +optional
+	Server *ServerSpec `json:"server,omitempty"`
+
+	// PodTemplate customizes the json-server pods. It is merged onto the fields managed
+	// by the operator, which take precedence.
+	// +optional
+	PodTemplate *PodTemplateOverride `json:"podTemplate,omitempty"`
+
+	// Idle scales the pods to zero when they serve no request for a while. An activator then
+	// receives the traffic of the Service and scales the pods back up on the next request.
+	// +optional
+	Idle *IdleSpec `json:"idle,omitempty"`
+
+	// Autoscaling scales the pods between minReplicas and maxReplicas with a
+	// HorizontalPodAutoscaler. spec.replicas is ignored while it is set.
+	// +optional
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+
+	// DisruptionBudget limits the pods evicted at once, e.g. while nodes are drained, with a
+	// PodDisruptionBudget
+	// +optional
+	DisruptionBudget *DisruptionBudgetSpec `json:"disruptionBudget,omitempty"`
+
+	// Access restricts the clients of the json-server pods with a NetworkPolicy. The pods can
+	// be reached from any pod of the cluster when it is not set.
+	// +optional
+	Access *AccessSpec `json:"access,omitempty"`
+}
+
+// IdleSpec configures the scaling to zero of idle JsonServers.
+type IdleSpec struct {
+	// ScaleToZeroAfter is the time without request after which the pods are scaled to zero
+	// +kubebuilder:default="15m"
+	// +optional
+	ScaleToZeroAfter metav1.Duration `json:"scaleToZeroAfter,omitempty"`
+
+	// ActivationTimeout is how long the activator holds a request while the pods start
+	// +kubebuilder:default="2m"
+	// +optional
+	ActivationTimeout metav1.Duration `json:"activationTimeout,omitempty"`
+}
+
+// AutoscalingSpec configures the HorizontalPodAutoscaler of a JsonServer. Utilization targets
+// are relative to the resource requests of the json-server container, set in podTemplate.resources.
+// +kubebuilder:validation:XValidation:rule="!has(self.minReplicas) || self.minReplicas <= self.maxReplicas",message="minReplicas must not be greater than maxReplicas"
+type AutoscalingSpec struct {
+	// MinReplicas is the lower limit of the replicas
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the upper limit of the replicas
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// TargetCPUUtilizationPercentage is the average CPU utilization of the pods to aim for.
+	// 80 when neither target is set.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+
+	// TargetMemoryUtilizationPercentage is the average memory utilization of the pods to aim for
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+}
+
+// DisruptionBudgetSpec configures the PodDisruptionBudget of a JsonServer. At most one of
+// minAvailable and maxUnavailable can be set, maxUnavailable is 1 when neither is.
+// +kubebuilder:validation:XValidation:rule="!(has(self.minAvailable) && has(self.maxUnavailable))",message="only one of minAvailable or maxUnavailable can be set"
+type DisruptionBudgetSpec struct {
+	// MinAvailable is the number or percentage of pods that must stay available
+	// +optional
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// MaxUnavailable is the number or percentage of pods that can be unavailable
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// AccessSpec lists the clients allowed to reach the json-server pods on the port they serve.
+// The operator, the activator and the pods of the JsonServer itself are always allowed.
+type AccessSpec struct {
+	// From lists the peers allowed to reach the pods
+	// +optional
+	From []AccessPeer `json:"from,omitempty"`
+
+	// DefaultDeny only allows the peers of from. Otherwise the pods of the namespace of the
+	// JsonServer are allowed too.
+	// +optional
+	DefaultDeny bool `json:"defaultDeny,omitempty"`
+}
+
+// AccessPeer selects pods allowed to reach the json-server pods. With both selectors, the
+// pods selected by podSelector in the namespaces selected by namespaceSelector are allowed.
+// +kubebuilder:validation:XValidation:rule="has(self.namespaceSelector) || has(self.podSelector)",message="at least one of namespaceSelector or podSelector must be set"
+type AccessPeer struct {
+	// NamespaceSelector selects namespaces whose pods are allowed, all namespaces when empty
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// PodSelector selects the pods allowed, in the namespace of the JsonServer unless
+	// namespaceSelector is set
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+}
+
+// Service types of a JsonServer. Headless is a ClusterIP Service without cluster IP.
+const (
+	ServiceTypeClusterIP    = "ClusterIP"
+	ServiceTypeNodePort     = "NodePort"
+	ServiceTypeLoadBalancer = "LoadBalancer"
+	ServiceTypeHeadless     = "Headless"
+)
+
+// ServiceSpec describes the Service exposing the json-server pods.
+// +kubebuilder:validation:XValidation:rule="!has(self.nodePort) || self.type == 'NodePort' || self.type == 'LoadBalancer'",message="nodePort requires a NodePort or LoadBalancer Service"
+// +kubebuilder:validation:XValidation:rule="!has(self.externalTrafficPolicy) || self.type == 'NodePort' || self.type == 'LoadBalancer'",message="externalTrafficPolicy requires a NodePort or LoadBalancer Service"
+type ServiceSpec struct {
+	// Type of the Service: ClusterIP, NodePort, LoadBalancer or Headless.
+	// Switching from or to Headless recreates the Service.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer;Headless
+	// +kubebuilder:default=ClusterIP
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Port exposed by the Service
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=3000
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// PortName is the name of the Service port
+	// +kubebuilder:default=http
+	// +optional
+	PortName string `json:"portName,omitempty"`
+
+	// NodePort of NodePort and LoadBalancer Services, allocated by the cluster when empty
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	NodePort int32 `json:"nodePort,omitempty"`
+
+	// AppProtocol of the Service port
+	// +optional
+	AppProtocol *string `json:"appProtocol,omitempty"`
+
+	// Annotations of the Service, e.g. to configure a cloud load balancer
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// ExternalTrafficPolicy of NodePort and LoadBalancer Services
+	// +kubebuilder:validation:Enum=Cluster;Local
+	// +optional
+	ExternalTrafficPolicy corev1.ServiceExternalTrafficPolicy `json:"externalTrafficPolicy,omitempty"`
+
+	// IPFamilies of the Service. Changing the first family recreates the Service.
+	// +optional
+	IPFamilies []corev1.IPFamily `json:"ipFamilies,omitempty"`
+
+	// IPFamilyPolicy of the Service
+	// +optional
+	IPFamilyPolicy *corev1.IPFamilyPolicy `json:"ipFamilyPolicy,omitempty"`
+
+	// SessionAffinity of the Service, always ClientIP with the Sticky consistency mode
+	// +kubebuilder:validation:Enum=None;ClientIP
+	// +optional
+	SessionAffinity corev1.ServiceAffinity `json:"sessionAffinity,omitempty"`
+}
+
+// ServerSpec holds the json-server options.
+type ServerSpec struct {
+	// Routes rewrites the request paths before they are served. The rules are applied in
+	// the order they are listed, after the rule of the base path.
+	// +optional
+	Routes []Route `json:"routes,omitempty"`
+
+	// IDField is the field identifying the records of a collection, "id" by default
+	// +kubebuilder:validation:Pattern=`^[A-Za-z_$][A-Za-z0-9_$]*$`
+	// +optional
+	IDField string `json:"idField,omitempty"`
+
+	// ForeignKeySuffix marks the fields referencing a record of another collection,
+	// "Id" by default: "postId" references the "posts" collection
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_$]+$`
+	// +optional
+	ForeignKeySuffix string `json:"foreignKeySuffix,omitempty"`
+
+	// Delay is added to every response, e.g. to simulate a slow API
+	// +optional
+	Delay *metav1.Duration `json:"delay,omitempty"`
+
+	// ReadOnly rejects the requests changing the data
+	// +optional
+	ReadOnly bool `json:"readOnly,omitempty"`
+
+	// BasePath serves the API under a path prefix, e.g. "/api". It is applied before the routes.
+	// +kubebuilder:validation:Pattern=`^(/[A-Za-z0-9._~-]+)+$`
+	// +optional
+	BasePath string `json:"basePath,omitempty"`
+
+	// Static names a ConfigMap of the same namespace whose keys are served as static files
+	// +optional
+	Static *corev1.LocalObjectReference `json:"static,omitempty"`
+}
+
+// Route rewrites the request paths matching a pattern.
+type Route struct {
+	// Pattern matched against the request path, e.g. "/api/*" or "/:resource/:id/show"
+	// +kubebuilder:validation:MinLength=1
+	Pattern string `json:"pattern"`
+
+	// Target the matching paths are rewritten to, e.g. "/$1" or "/:resource/:id"
+	// +kubebuilder:validation:MinLength=1
+	Target string `json:"target"`
+}
+
+// PodTemplateOverride holds the pod settings that can be customized.
+type PodTemplateOverride struct {
+	// Labels added to the pods
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations added to the pods
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Resources of the json-server container
+	// +optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// LivenessProbe of the json-server container
+	// +optional
+	LivenessProbe *corev1.Probe `json:"livenessProbe,omitempty"`
+
+	// ReadinessProbe of the json-server container
+	// +optional
+	ReadinessProbe *corev1.Probe `json:"readinessProbe,omitempty"`
+
+	// StartupProbe of the json-server container
+	// +optional
+	StartupProbe *corev1.Probe `json:"startupProbe,omitempty"`
+
+	// SecurityContext of every container of the pods, including the ones added by the operator
+	// +optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+
+	// PodSecurityContext of the pods
+	// +optional
+	PodSecurityContext *corev1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+
+	// NodeSelector of the pods
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Tolerations of the pods
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Affinity of the pods
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// TopologySpreadConstraints of the pods
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// PriorityClassName of the pods
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Env added to the json-server container
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// ImagePullSecrets of the pods
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// ServiceAccountName of the pods
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+}
+
+// Consistency modes of a JsonServer with several replicas.
+const (
+	ConsistencySingleWriter = "SingleWriter"
+	ConsistencySticky       = "Sticky"
+	ConsistencyReadOnly     = "ReadOnly"
+)
+
+// Reseed policies of a persistent JsonServer.
+const (
+	// ReseedNever keeps the data of existing volumes when the configuration changes
+	ReseedNever = "Never"
+	// ReseedOnConfigChange replaces the data of existing volumes when the configuration changes
+	ReseedOnConfigChange = "OnConfigChange"
+)
+
+// PersistenceSpec describes the volumes holding the data of a persistent JsonServer.
+type PersistenceSpec struct {
+	// Size of the volume of each replica. Changing the size or the storage class recreates
+	// the StatefulSet and rolls the pods, which keep their volumes: only the volumes of new
+	// replicas get the new size and class.
+	// +kubebuilder:default="1Gi"
+	// +optional
+	Size resource.Quantity `json:"size,omitempty"`
+
+	// StorageClassName of the volumes, the cluster default when empty
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// ReseedPolicy tells whether a configuration change replaces the data of existing
+	// volumes (OnConfigChange) or is only used to seed new volumes (Never)
+	// +kubebuilder:validation:Enum=Never;OnConfigChange
+	// +kubebuilder:default=Never
+	// +optional
+	ReseedPolicy string `json:"reseedPolicy,omitempty"`
+}
+
+// Formats of the configuration and of the collections. NDJSON and CSV only hold collections.
+const (
+	FormatJSON   = "JSON"
+	FormatYAML   = "YAML"
+	FormatJSON5  = "JSON5"
+	FormatNDJSON = "NDJSON"
+	FormatCSV    = "CSV"
+)
+
+// CollectionSource is a collection of records, or a singular resource, served by a JsonServer.
+// It is written in the spec, or read from a ConfigMap, a JsonFixture or another JsonServer.
+// +kubebuilder:validation:XValidation:rule="[has(self.data), has(self.configMapKeyRef), has(self.fixtureRef), has(self.jsonServerRef)].filter(x, x).size() == 1",message="exactly one of data, configMapKeyRef, fixtureRef or jsonServerRef must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.columns) || (has(self.format) && self.format == 'CSV')",message="columns only apply to the CSV format"
+// +kubebuilder:validation:XValidation:rule="!has(self.columns) || has(self.data) || has(self.configMapKeyRef)",message="columns only apply to data and configMapKeyRef"
+type CollectionSource struct {
+	// Format of data, or of the key of configMapKeyRef: JSON, YAML or JSON5 hold an array of
+	// records, or an object for a singular resource. NDJSON holds one record per line and CSV
+	// one record per row, after a header row naming the fields.
+	// +kubebuilder:validation:Enum=JSON;YAML;JSON5;NDJSON;CSV
+	// +kubebuilder:default=JSON
+	// +optional
+	Format string `json:"format,omitempty"`
+
+	// Data is the collection, written in its format
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	Data string `json:"data,omitempty"`
+
+	// Columns types the values of CSV columns, which are strings otherwise
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Columns []CSVColumn `json:"columns,omitempty"`
+
+	// ConfigMapKeyRef reads the collection from a key of a ConfigMap in the namespace of the
+	// JsonServer, written in its format
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// FixtureRef reads a collection of a JsonFixture in the namespace of the JsonServer
+	// +optional
+	FixtureRef *CollectionReference `json:"fixtureRef,omitempty"`
+
+	// JsonServerRef reads a collection of the configuration served by another JsonServer of
+	// the namespace
+	// +optional
+	JsonServerRef *CollectionReference `json:"jsonServerRef,omitempty"`
+}
+
+// CollectionReference selects a collection of another object of the namespace.
+type CollectionReference struct {
+	// Name of the object
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Collection is the name of the collection in the object, the name of the collection
+	// being defined when empty
+	// +optional
+	Collection string `json:"collection,omitempty"`
+}
+
+// GeneratedCollection renders a collection of fake records. The same template, count and seed
+// always render the same records.
+type GeneratedCollection struct {
+	// Count is the number of records
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10000
+	Count int32 `json:"count"`
+
+	// Seed of the fake data, derived from the name of the collection when unset. The seed
+	// used is reported in status.generated.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Seed *int64 `json:"seed,omitempty"`
+
+	// Template renders one record as a JSON or YAML object with Go text/template, e.g.
+	// '{"id": {{ .ID }}, "name": {{ name | json }}, "teamId": {{ ref "teams" }}}'. It is
+	// executed with the Index (from 0) and the ID (from 1) of the record, and can call
+	// firstName, lastName, name, email [name], uuid, date [from to], datetime [from to],
+	// word, words n, sentence [n], paragraph [n], int min max, float min max, bool,
+	// pick values..., ref collection (the ID of a random record of another generated
+	// collection) and json value (to quote strings). Range actions can be nested two deep and
+	// loop a million times over all the records; templates cannot define or call templates.
+	// +kubebuilder:validation:MinLength=1
+	Template string `json:"template"`
+}
+
+// Types of the values of a CSV column.
+const (
+	ColumnString  = "string"
+	ColumnInteger = "integer"
+	ColumnNumber  = "number"
+	ColumnBoolean = "boolean"
+	ColumnJSON    = "json"
+)
+
+// CSVColumn types the values of a CSV column. Empty values of typed columns are null.
+type CSVColumn struct {
+	// Name of the column, as written in the header row
+	Name string `json:"name"`
+
+	// Type of the values: string, integer, number, boolean (true, false, yes or no) or json
+	// for JSON values such as arrays or nested objects
+	// +kubebuilder:validation:Enum=string;integer;number;boolean;json
+	Type string `json:"type"`
+}
+
+// DataSource references the JSON configuration to be served. Exactly one source must be set.
+// +kubebuilder:validation:XValidation:rule="[has(self.configMapKeyRef), has(self.secretKeyRef), has(self.http), has(self.snapshotRef)].filter(x, x).size() == 1",message="exactly one of configMapKeyRef, secretKeyRef, http or snapshotRef must be set"
+type DataSource struct {
+	// ConfigMapKeyRef selects a key of a ConfigMap in the namespace of the JsonServer
+	// +optional
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// SecretKeyRef selects a key of a Secret in the namespace of the JsonServer
+	// +optional
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+
+	// HTTP downloads the JSON configuration from a URL
+	// +optional
+	HTTP *HTTPSource `json:"http,omitempty"`
+
+	// SnapshotRef seeds the JsonServer with the data captured by a JsonServerSnapshot
+	// of its namespace
+	// +optional
+	SnapshotRef *corev1.LocalObjectReference `json:"snapshotRef,omitempty"`
+}
+
+// HTTPSource downloads the JSON configuration from an HTTP(S) URL.
+type HTTPSource struct {
+	// URL of the JSON document
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+
+	// RefreshInterval is how often the document is downloaded again
+	// +kubebuilder:default="5m"
+	// +optional
+	RefreshInterval *metav1.Duration `json:"refreshInterval,omitempty"`
 }
 
+// Kinds of object exposing a JsonServer.
+const (
+	ExposeIngress   = "Ingress"
+	ExposeHTTPRoute = "HTTPRoute"
+)
+
+// ExposeSpec describes how the JsonServer is reachable from outside the cluster.
+// +kubebuilder:validation:XValidation:rule="self.type != 'HTTPRoute' || (has(self.parentRefs) && size(self.parentRefs) > 0)",message="parentRefs are required to expose a JsonServer through an HTTPRoute"
+// +kubebuilder:validation:XValidation:rule="self.type != 'HTTPRoute' || !has(self.ingressClassName)",message="ingressClassName only applies to Ingresses"
+// +kubebuilder:validation:XValidation:rule="self.type != 'HTTPRoute' || !has(self.tls) || (!has(self.tls.secretName) && !has(self.tls.issuer) && !has(self.tls.clusterIssuer))",message="the certificate of an HTTPRoute is configured on its Gateway listener"
+type ExposeSpec struct {
+	// Type of the object exposing the JsonServer: Ingress or HTTPRoute
+	// +kubebuilder:validation:Enum=Ingress;HTTPRoute
+	// +kubebuilder:default=Ingress
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Host the JsonServer is reachable at
+	// +kubebuilder:validation:MinLength=1
+	Host string `json:"host"`
+
+	// Path prefix the JsonServer is reachable at
+	// +kubebuilder:validation:Pattern=`^/`
+	// +kubebuilder:default="/"
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// IngressClassName of the Ingress, the cluster default when empty
+	// +optional
+	IngressClassName *string `json:"ingressClassName,omitempty"`
+
+	// ParentRefs are the Gateways the HTTPRoute attaches to
+	// +optional
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+
+	// TLS serves the JsonServer over HTTPS. For HTTPRoutes the certificate is configured
+	// on the Gateway listener and an empty tls section only tells the URL uses https.
+	// +optional
+	TLS *ExposeTLS `json:"tls,omitempty"`
+}
+
+// ParentReference identifies a Gateway an HTTPRoute attaches to.
+type ParentReference struct {
+	// Name of the Gateway
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the Gateway, the namespace of the JsonServer when empty
+	// +optional
+	Namespace *string `json:"namespace,omitempty"`
+
+	// SectionName is the name of the Gateway listener to attach to
+	// +optional
+	SectionName *string `json:"sectionName,omitempty"`
+}
+
+// ExposeTLS describes the certificate of an Ingress.
+// +kubebuilder:validation:XValidation:rule="!(has(self.issuer) && has(self.clusterIssuer))",message="only one of issuer or clusterIssuer can be set"
+type ExposeTLS struct {
+	// SecretName is the Secret holding the certificate, <name>-tls when empty
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// Issuer is the cert-manager Issuer of the namespace issuing the certificate
+	// +optional
+	Issuer string `json:"issuer,omitempty"`
+
+	// ClusterIssuer is the cert-manager ClusterIssuer issuing the certificate
+	// +optional
+	ClusterIssuer string `json:"clusterIssuer,omitempty"`
+}
+
+// Kinds of source reported in SourceStatus.
+const (
+	SourceKindInline    = "Inline"
+	SourceKindConfigMap = "ConfigMap"
+	SourceKindSecret    = "Secret"
+	SourceKindHTTP      = "HTTP"
+	SourceKindRevision  = "Revision"
+	SourceKindSnapshot  = "Snapshot"
+)
+
+// SourceStatus describes the source the served configuration was resolved from.
+type SourceStatus struct {
+	// Kind is the kind of source: Inline, ConfigMap, Secret, HTTP, Snapshot or Revision
+	Kind string `json:"kind"`
+
+	// Name is the name of the ConfigMap, Secret or JsonServerSnapshot, the URL, or the
+	// ConfigMap of the revision
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Revision identifies the resolved content: the generation of the JsonServer for inline
+	// configurations, the resourceVersion of a ConfigMap or Secret, the ETag (content hash
+	// when missing) of a downloaded document, the checksum of a snapshot or the revision hash
+	// +optional
+	Revision string `json:"revision,omitempty"`
+
+	// LastSyncTime is when the current revision of the source was read
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// ConfigRevision is a configuration served by a JsonServer, kept in an immutable ConfigMap
+// named <name>-<hash>.
+type ConfigRevision struct {
+	// Hash is the content hash of the configuration, to be set in spec.rollbackTo
+	Hash string `json:"hash"`
+
+	// CreationTime is when the configuration was first served
+	CreationTime metav1.Time `json:"creationTime"`
+
+	// Author is the field manager that last wrote the configuration, e.g. kubectl-edit
+	// +optional
+	Author string `json:"author,omitempty"`
+}
+
+// GeneratedCollectionStatus reports a collection rendered from spec.generate.
+type GeneratedCollectionStatus struct {
+	// Name of the collection
+	Name string `json:"name"`
+
+	// Seed the records were rendered with, to be set in spec.generate to render them again
+	Seed int64 `json:"seed"`
+
+	// Count is the number of records rendered
+	Count int32 `json:"count"`
+}
+
+// Condition types reported in JsonServerStatus.Conditions.
+const (
+	// ConditionConfigValid tells whether spec.jsonConfig could be served by json-server.
+	ConditionConfigValid = "ConfigValid"
+	// ConditionResourcesReconciled tells whether the owned ConfigMap, Deployment and Service were written.
+	ConditionResourcesReconciled = "ResourcesReconciled"
+	// ConditionAvailable mirrors the availability of the owned Deployment.
+	ConditionAvailable = "Available"
+	// ConditionProgressing is true while a rollout of the owned Deployment is in flight.
+	ConditionProgressing = "Progressing"
+	// ConditionReady is true once every desired replica serves the current configuration.
+	ConditionReady = "Ready"
+)
+
 // JsonServerStatus defines the observed state of JsonServer.
 type JsonServerStatus struct {
 	// +kubebuilder:validation:Enum=Synced;Error
@@ -44,14 +760,101 @@ type JsonServerStatus struct {
 	// Replicas is the current number of replicas for this JsonServer
 	Replicas int32 `json:"replicas,omitempty"`
 
+	// ReadyReplicas is the number of json-server pods that are ready to serve requests
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed by the controller
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// ConfigHash is the content hash of the configuration rolled out to the pods.
+	// It matches the example.example.com/config-hash annotation on the pod template.
+	// +optional
+	ConfigHash string `json:"configHash,omitempty"`
+
+	// Source is the resolved source of the served configuration
+	// +optional
+	Source *SourceStatus `json:"source,omitempty"`
+
+	// CurrentRevision is the hash of the configuration revision being served
+	// +optional
+	CurrentRevision string `json:"currentRevision,omitempty"`
+
+	// UnrecordedRevision is the hash of the configuration being served when it is too large to
+	// be kept as a revision, and cannot be rolled back to
+	// +optional
+	UnrecordedRevision string `json:"unrecordedRevision,omitempty"`
+
+	// Revisions are the configuration revisions kept for rollbacks, newest first
+	// +optional
+	Revisions []ConfigRevision `json:"revisions,omitempty"`
+
+	// Generated lists the collections rendered from spec.generate, with their seed
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Generated []GeneratedCollectionStatus `json:"generated,omitempty"`
+
+	// LastResetTime is when the data was last restored to the configuration
+	// +optional
+	LastResetTime *metav1.Time `json:"lastResetTime,omitempty"`
+
+	// NextResetTime is when the reset schedule restores the data next
+	// +optional
+	NextResetTime *metav1.Time `json:"nextResetTime,omitempty"`
+
+	// LastResetRequest is the value of the reset-requested-at annotation last handled
+	// +optional
+	LastResetRequest string `json:"lastResetRequest,omitempty"`
+
+	// LastRequestTime is when the pods last served a request, as of the last expiry check
+	// +optional
+	LastRequestTime *metav1.Time `json:"lastRequestTime,omitempty"`
+
+	// IdleSince is when the pods were scaled to zero for lack of requests, unset while they run
+	// +optional
+	IdleSince *metav1.Time `json:"idleSince,omitempty"`
+
+	// ExpirationTime is when the JsonServer is deleted according to its TTLs
+	// +optional
+	ExpirationTime *metav1.Time `json:"expirationTime,omitempty"`
+
+	// Image is the json-server image the pods run
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// ImageDigest is the digest the image resolved to on the running pods. It is only
+	// reported, pin a digest in spec.image to run the same image on every pod.
+	// +optional
+	ImageDigest string `json:"imageDigest,omitempty"`
+
+	// URL the JsonServer is reachable at from outside the cluster
+	// +optional
+	URL string `json:"url,omitempty"`
+
 	// Selector is the label selector for pods. This is used to find matching pods for scaling purposes.
 	// +optional
 	Selector string `json:"selector,omitempty"`
+
+	// Conditions represent the latest available observations of the JsonServer state
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas,selectorpath=.status.selector
+// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".spec.replicas",description="Number of replicas"
+// +kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicas",description="Number of ready replicas"
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.state",description="Current status"
+// +kubebuilder:printcolumn:name="Message",type="string",JSONPath=".status.message",description="Status message"
+// +kubebuilder:printcolumn:name="Revision",type="string",JSONPath=".status.currentRevision",description="Configuration revision being served",priority=1
+// +kubebuilder:printcolumn:name="Expires",type="string",format="date-time",JSONPath=".status.expirationTime",description="When the JsonServer is deleted"
+// +kubebuilder:printcolumn:name="URL",type="string",JSONPath=".status.url",description="URL the JsonServer is exposed at"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // JsonServer is the Schema for the jsonservers API.
 type JsonServer struct {
@@ -63,11 +866,6 @@ type JsonServer struct {
 }
 
 // +kubebuilder:object:root=true
-// +kubebuilder:subresource:status
-// +kubebuilder:printcolumn:name="Replicas",type="integer",JSONPath=".spec.replicas",description="Number of replicas"
-// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.state",description="Current status"
-// +kubebuilder:printcolumn:name="Message",type="string",JSONPath=".status.message",description="Status message"
-// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // JsonServerList contains a list of JsonServer.
 type JsonServerList struct {
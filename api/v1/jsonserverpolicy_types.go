@@ -0,0 +1,86 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// JsonServerPolicySpec defines the constraints the admission webhook enforces on
+// the JsonServers of the selected namespaces.
+type JsonServerPolicySpec struct {
+	// NamespaceSelector selects the namespaces the policy applies to. An empty
+	// selector selects every namespace.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// NamePatterns are regular expressions, JsonServer names must match at least one of them.
+	// The patterns match whole names, as if they started with ^ and ended with $.
+	// +optional
+	NamePatterns []string `json:"namePatterns,omitempty"`
+
+	// MaxReplicas is the maximum number of replicas of a JsonServer
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+
+	// MaxConfigSize is the maximum size of spec.jsonConfig and of the data of spec.collections together
+	// +optional
+	MaxConfigSize *resource.Quantity `json:"maxConfigSize,omitempty"`
+
+	// AllowedImages are glob patterns (e.g. "registry.example.com/*") the json-server
+	// image must match one of
+	// +optional
+	AllowedImages []string `json:"allowedImages,omitempty"`
+
+	// AllowedRegistries are glob patterns (e.g. "*.example.com") the registry of the
+	// json-server image must match one of. Images of Docker Hub use the "docker.io" registry.
+	// +optional
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+
+	// RequiredLabels are the label keys every JsonServer must carry
+	// +optional
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Max Replicas",type="integer",JSONPath=".spec.maxReplicas",description="Maximum number of replicas"
+// +kubebuilder:printcolumn:name="Max Config Size",type="string",JSONPath=".spec.maxConfigSize",description="Maximum size of the jsonConfig"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// JsonServerPolicy is the Schema for the jsonserverpolicies API.
+type JsonServerPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec JsonServerPolicySpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// JsonServerPolicyList contains a list of JsonServerPolicy.
+type JsonServerPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []JsonServerPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&JsonServerPolicy{}, &JsonServerPolicyList{})
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Kinds of object a snapshot is stored in.
+const (
+	SnapshotStorageConfigMap = "ConfigMap"
+	SnapshotStorageSecret    = "Secret"
+)
+
+// Phases of a JsonServerSnapshot.
+const (
+	SnapshotPending   = "Pending"
+	SnapshotCompleted = "Completed"
+	SnapshotFailed    = "Failed"
+)
+
+// JsonServerSnapshotSpec selects the JsonServer whose data is captured.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="the spec of a snapshot is immutable"
+type JsonServerSnapshotSpec struct {
+	// JsonServerName is the JsonServer of the namespace whose data is captured
+	// +kubebuilder:validation:MinLength=1
+	JsonServerName string `json:"jsonServerName"`
+
+	// Storage is the kind of object the data is stored in: ConfigMap or Secret.
+	// The object is named after the snapshot.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +kubebuilder:default=ConfigMap
+	// +optional
+	Storage string `json:"storage,omitempty"`
+}
+
+// JsonServerSnapshotStatus describes the captured data.
+type JsonServerSnapshotStatus struct {
+	// Phase of the snapshot: Pending until a pod of the JsonServer could be read,
+	// then Completed or Failed
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Message details the phase
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Pod is the json-server pod the data was captured from
+	// +optional
+	Pod string `json:"pod,omitempty"`
+
+	// CaptureTime is when the data was captured
+	// +optional
+	CaptureTime *metav1.Time `json:"captureTime,omitempty"`
+
+	// Size of the captured document in bytes
+	// +optional
+	Size int64 `json:"size,omitempty"`
+
+	// Checksum of the captured document, e.g. sha256:...
+	// +optional
+	Checksum string `json:"checksum,omitempty"`
+
+	// DataRef is the ConfigMap or Secret holding the document in its db.json key
+	// +optional
+	DataRef *SnapshotDataRef `json:"dataRef,omitempty"`
+}
+
+// SnapshotDataRef references the object a snapshot is stored in.
+type SnapshotDataRef struct {
+	// Kind of the object: ConfigMap or Secret
+	Kind string `json:"kind"`
+
+	// Name of the object
+	Name string `json:"name"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="JsonServer",type="string",JSONPath=".spec.jsonServerName",description="JsonServer the data was captured from"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase",description="Phase of the snapshot"
+// +kubebuilder:printcolumn:name="Size",type="integer",JSONPath=".status.size",description="Size of the captured document in bytes"
+// +kubebuilder:printcolumn:name="Checksum",type="string",JSONPath=".status.checksum",description="Checksum of the captured document",priority=1
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// JsonServerSnapshot is the Schema for the jsonserversnapshots API. It captures the data
+// of a running JsonServer, including the changes made through the json-server API, so that
+// other JsonServers can be seeded with it.
+type JsonServerSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   JsonServerSnapshotSpec   `json:"spec"`
+	Status JsonServerSnapshotStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// JsonServerSnapshotList contains a list of JsonServerSnapshot.
+type JsonServerSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []JsonServerSnapshot `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&JsonServerSnapshot{}, &JsonServerSnapshotList{})
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/activator"
+)
+
+var (
+	scheme   = runtime.NewScheme()
+	setupLog = ctrl.Log.WithName("setup")
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(examplev1.AddToScheme(scheme))
+}
+
+// The activator receives the requests of a JsonServer scaled to zero. The operator runs one
+// for each JsonServer with an idle section.
+func main() {
+	var bindAddress, name, namespace, selector string
+	var targetPort int
+	var activationTimeout time.Duration
+	flag.StringVar(&bindAddress, "bind-address", ":3000", "The address the activator receives the requests on.")
+	flag.StringVar(&name, "jsonserver", "", "The name of the JsonServer to activate.")
+	flag.StringVar(&namespace, "namespace", "", "The namespace of the JsonServer.")
+	flag.StringVar(&selector, "selector", "", "The label selector of the json-server pods, e.g. app=name,managed-by=jsonserver-operator.")
+	flag.IntVar(&targetPort, "target-port", 3000, "The port json-server listens on.")
+	flag.DurationVar(&activationTimeout, "activation-timeout", 2*time.Minute,
+		"How long a request is held until a json-server pod is ready.")
+	opts := zap.Options{}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	if name == "" || namespace == "" {
+		setupLog.Error(errors.New("--jsonserver and --namespace are required"), "invalid flags")
+		os.Exit(1)
+	}
+	podLabels, err := labels.ConvertSelectorToLabelsMap(selector)
+	if err != nil || len(podLabels) == 0 {
+		setupLog.Error(err, "invalid --selector", "selector", selector)
+		os.Exit(1)
+	}
+
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		setupLog.Error(err, "unable to create client")
+		os.Exit(1)
+	}
+
+	logger := ctrl.Log.WithName("activator")
+	server := &http.Server{
+		Addr: bindAddress,
+		Handler: &activator.Activator{
+			Client:     c,
+			Namespace:  namespace,
+			Name:       name,
+			Selector:   podLabels,
+			TargetPort: targetPort,
+			Timeout:    activationTimeout,
+		},
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(_ net.Listener) context.Context {
+			return ctrl.LoggerInto(context.Background(), logger)
+		},
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			setupLog.Error(err, "unable to shut down the activator")
+		}
+	}()
+
+	setupLog.Info("starting activator", "jsonserver", name, "namespace", namespace, "address", bindAddress)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		setupLog.Error(err, "problem running activator")
+		os.Exit(1)
+	}
+}
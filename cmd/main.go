@@ -21,6 +21,7 @@ import (
 	"flag"
 	"os"
 	"path/filepath"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -28,6 +29,7 @@ import (
 
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
@@ -38,6 +40,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/config"
 	"jsonserver-operator/internal/controller"
 	webhookexamplev1 "jsonserver-operator/internal/webhook/v1"
 	// +kubebuilder:scaffold:imports
@@ -64,6 +67,8 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var configFile, defaultImage, allowedRegistries, activatorImage, allowedURLHosts string
+	var ephemeralTTL time.Duration
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -82,6 +87,22 @@ func main() {
 	flag.StringVar(&metricsCertKey, "metrics-cert-key", "tls.key", "The name of the metrics server key file.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&configFile, "config", "",
+		"The operator configuration file holding the defaultImage and allowedRegistries of the JsonServers.")
+	flag.StringVar(&defaultImage, "default-image", "",
+		"The json-server image of the JsonServers that set no image. Overrides "+config.EnvDefaultImage+" and the configuration file.")
+	flag.StringVar(&allowedRegistries, "allowed-registries", "",
+		"Comma separated glob patterns of the registries json-server images can be pulled from. "+
+			"Overrides "+config.EnvAllowedRegistries+" and the configuration file.")
+	flag.DurationVar(&ephemeralTTL, "ephemeral-ttl", 0,
+		"The time after their creation the JsonServers of namespaces labeled "+examplev1.EphemeralNamespaceLabel+"=true "+
+			"are deleted when they set no TTL. Overrides "+config.EnvEphemeralTTL+" and the configuration file.")
+	flag.StringVar(&activatorImage, "activator-image", "",
+		"The image of the activator receiving the requests of the JsonServers scaled to zero, usually the operator image. "+
+			"Overrides "+config.EnvActivatorImage+" and the configuration file.")
+	flag.StringVar(&allowedURLHosts, "allowed-url-hosts", "",
+		"Comma separated glob patterns of the hosts the documents of dataFrom.http can be downloaded from. "+
+			"Overrides "+config.EnvAllowedURLHosts+" and the configuration file.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -90,6 +111,39 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	// The flags take precedence over the environment, which takes precedence over the configuration file
+	operatorConfig, err := config.Load(configFile)
+	if err != nil {
+		setupLog.Error(err, "unable to load the operator configuration")
+		os.Exit(1)
+	}
+	if err := operatorConfig.ApplyEnv(os.LookupEnv); err != nil {
+		setupLog.Error(err, "invalid operator configuration")
+		os.Exit(1)
+	}
+	if defaultImage != "" {
+		operatorConfig.DefaultImage = defaultImage
+	}
+	if allowedRegistries != "" {
+		operatorConfig.AllowedRegistries = config.SplitList(allowedRegistries)
+	}
+	if ephemeralTTL != 0 {
+		operatorConfig.EphemeralTTL.Duration = ephemeralTTL
+	}
+	if activatorImage != "" {
+		operatorConfig.ActivatorImage = activatorImage
+	}
+	if allowedURLHosts != "" {
+		operatorConfig.AllowedURLHosts = config.SplitList(allowedURLHosts)
+	}
+	if err := operatorConfig.Validate(); err != nil {
+		setupLog.Error(err, "invalid operator configuration")
+		os.Exit(1)
+	}
+	setupLog.Info("Loaded operator configuration", "defaultImage", operatorConfig.DefaultImage,
+		"allowedRegistries", operatorConfig.AllowedRegistries, "ephemeralTTL", operatorConfig.EphemeralTTL.Duration,
+		"activatorImage", operatorConfig.ActivatorImage, "allowedURLHosts", operatorConfig.AllowedURLHosts)
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -203,19 +257,47 @@ func main() {
 		os.Exit(1)
 	}
 
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create clientset")
+		os.Exit(1)
+	}
+
 	if err = (&controller.JsonServerReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		Recorder:        mgr.GetEventRecorderFor("jsonserver-controller"),
+		APIReader:       mgr.GetAPIReader(),
+		DefaultImage:    operatorConfig.DefaultImage,
+		EphemeralTTL:    operatorConfig.EphemeralTTL.Duration,
+		PodLogs:         clientset.CoreV1(),
+		ActivatorImage:  operatorConfig.ActivatorImage,
+		AllowedURLHosts: operatorConfig.AllowedURLHosts,
+		// Set from the downward API in config/manager, empty when running out of the cluster
+		OperatorNamespace: os.Getenv("POD_NAMESPACE"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "JsonServer")
 		os.Exit(1)
 	}
+	if err = (&controller.JsonServerSnapshotReconciler{
+		Client:    mgr.GetClient(),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("jsonserversnapshot-controller"),
+		APIReader: mgr.GetAPIReader(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "JsonServerSnapshot")
+		os.Exit(1)
+	}
 	// nolint:goconst
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
-		if err = webhookexamplev1.SetupJsonServerWebhookWithManager(mgr); err != nil {
+		if err = webhookexamplev1.SetupJsonServerWebhookWithManager(mgr, operatorConfig); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "JsonServer")
 			os.Exit(1)
 		}
+		if err = webhookexamplev1.SetupJsonServerPolicyWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "JsonServerPolicy")
+			os.Exit(1)
+		}
 	}
 	// +kubebuilder:scaffold:builder
 
@@ -0,0 +1,171 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package activator receives the requests of a JsonServer scaled to zero. It activates the
+// JsonServer, holds the requests until one of its pods is ready and forwards them to it.
+package activator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+const (
+	// activationInterval is the minimum time between two activations of the JsonServer, so
+	// that a burst of held requests patches it once
+	activationInterval = 2 * time.Second
+	// pollInterval is how often the pods are listed while a request is held
+	pollInterval = 500 * time.Millisecond
+	// retryAfterSeconds is the Retry-After of the requests that timed out
+	retryAfterSeconds = 5
+)
+
+// Activator is the HTTP handler standing in for the pods of a JsonServer scaled to zero
+type Activator struct {
+	// Client patches the JsonServer and lists its pods
+	Client client.Client
+	// Namespace and Name identify the JsonServer
+	Namespace string
+	Name      string
+	// Selector matches the json-server pods
+	Selector map[string]string
+	// TargetPort is the port json-server listens on
+	TargetPort int
+	// Timeout is how long a request is held until a pod is ready
+	Timeout time.Duration
+	// Transport forwards the requests to the pods, http.DefaultTransport when it is not set
+	Transport http.RoundTripper
+
+	mu             sync.Mutex
+	lastActivation time.Time
+}
+
+// ServeHTTP holds the request until a pod of the JsonServer is ready and forwards it, or
+// answers 503 Service Unavailable once the activation timed out
+func (a *Activator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	log := logf.FromContext(req.Context()).WithValues("method", req.Method, "path", req.URL.Path)
+
+	ctx, cancel := context.WithTimeout(req.Context(), a.Timeout)
+	defer cancel()
+	target, err := a.waitForPod(ctx)
+	if err != nil {
+		log.Error(err, "No json-server pod ready")
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+		http.Error(w, fmt.Sprintf("JsonServer %s is starting, retry later", a.Name), http.StatusServiceUnavailable)
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(target)
+			r.SetXForwarded()
+			r.Out.Host = r.In.Host
+		},
+		Transport: a.Transport,
+	}
+	proxy.ServeHTTP(w, req)
+}
+
+// waitForPod returns the URL of a ready json-server pod, activating the JsonServer while
+// there is none
+func (a *Activator) waitForPod(ctx context.Context) (*url.URL, error) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		pod, err := a.readyPod(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if pod != nil {
+			return &url.URL{Scheme: "http", Host: net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(a.TargetPort))}, nil
+		}
+		if err := a.activate(ctx); err != nil {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no pod ready after %s: %w", a.Timeout, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// readyPod returns the first ready json-server pod by name, nil when there is none. The
+// writer of single-writer JsonServers, the first pod of their StatefulSet, is ready first.
+func (a *Activator) readyPod(ctx context.Context) (*corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := a.Client.List(ctx, pods, client.InNamespace(a.Namespace), client.MatchingLabels(a.Selector)); err != nil {
+		return nil, fmt.Errorf("failed to list json-server pods: %w", err)
+	}
+	sort.Slice(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp == nil && pod.Status.PodIP != "" && podReady(pod) {
+			return pod, nil
+		}
+	}
+	return nil, nil
+}
+
+// podReady reports whether the pod passes its readiness probe
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// activate records the request in the activated-at annotation of the JsonServer, from which
+// the operator scales it back up
+func (a *Activator) activate(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	if now.Sub(a.lastActivation) < activationInterval {
+		return nil
+	}
+
+	jsonServer := &examplev1.JsonServer{}
+	jsonServer.Namespace = a.Namespace
+	jsonServer.Name = a.Name
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q}}}`,
+		examplev1.ActivatedAtAnnotation, now.UTC().Format(time.RFC3339))
+	if err := a.Client.Patch(ctx, jsonServer, client.RawPatch(types.MergePatchType, []byte(patch))); err != nil {
+		return fmt.Errorf("failed to activate JsonServer %s: %w", a.Name, err)
+	}
+	a.lastActivation = now
+	logf.FromContext(ctx).Info("JsonServer activated", "jsonserver", a.Name)
+	return nil
+}
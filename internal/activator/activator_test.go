@@ -0,0 +1,135 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package activator
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+var _ = Describe("Activator", func() {
+	var (
+		ctx        context.Context
+		k8sClient  client.Client
+		backend    *httptest.Server
+		activator  *Activator
+		jsonServer *examplev1.JsonServer
+	)
+
+	readyPod := func(name, ip string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "app-sample"}},
+			Status: corev1.PodStatus{
+				PodIP:      ip,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		scheme := runtime.NewScheme()
+		utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+		utilruntime.Must(examplev1.AddToScheme(scheme))
+		jsonServer = &examplev1.JsonServer{ObjectMeta: metav1.ObjectMeta{Name: "app-sample", Namespace: "default"}}
+		k8sClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(jsonServer).Build()
+
+		backend = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, "served "+r.URL.Path+" for "+r.Host)
+		}))
+		DeferCleanup(backend.Close)
+
+		// Every pod IP resolves to the test backend
+		dialer := &net.Dialer{}
+		activator = &Activator{
+			Client:     k8sClient,
+			Namespace:  "default",
+			Name:       "app-sample",
+			Selector:   map[string]string{"app": "app-sample"},
+			TargetPort: 3000,
+			Timeout:    2 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, network, backend.Listener.Addr().String())
+				},
+			},
+		}
+	})
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "http://app-sample.default.svc"+path, nil)
+		activator.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	activatedAt := func() string {
+		Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(jsonServer), jsonServer)).To(Succeed())
+		return jsonServer.Annotations[examplev1.ActivatedAtAnnotation]
+	}
+
+	It("forwards the requests to a ready pod without activating the JsonServer", func() {
+		Expect(k8sClient.Create(ctx, readyPod("app-sample-abc", "10.0.0.1"))).To(Succeed())
+
+		response := serve("/people")
+		Expect(response.Code).To(Equal(http.StatusOK))
+		Expect(response.Body.String()).To(Equal("served /people for app-sample.default.svc"))
+		Expect(activatedAt()).To(BeEmpty())
+	})
+
+	It("activates the JsonServer and holds the request until a pod is ready", func() {
+		go func() {
+			defer GinkgoRecover()
+			time.Sleep(700 * time.Millisecond)
+			Expect(k8sClient.Create(ctx, readyPod("app-sample-abc", "10.0.0.1"))).To(Succeed())
+		}()
+
+		response := serve("/people/1")
+		Expect(response.Code).To(Equal(http.StatusOK))
+		Expect(response.Body.String()).To(HavePrefix("served /people/1"))
+		Expect(time.Parse(time.RFC3339, activatedAt())).To(BeTemporally("~", time.Now(), 5*time.Second))
+	})
+
+	It("ignores the pods that are not ready", func() {
+		pod := readyPod("app-sample-abc", "10.0.0.1")
+		pod.Status.Conditions[0].Status = corev1.ConditionFalse
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		Expect(k8sClient.Create(ctx, readyPod("app-sample-def", ""))).To(Succeed())
+		activator.Timeout = time.Second
+
+		response := serve("/people")
+		Expect(response.Code).To(Equal(http.StatusServiceUnavailable))
+		Expect(response.Header().Get("Retry-After")).To(Equal("5"))
+		Expect(activatedAt()).NotTo(BeEmpty())
+	})
+})
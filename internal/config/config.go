@@ -0,0 +1,210 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config loads the operator-wide defaults of the JsonServers.
+package config
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/image"
+)
+
+// Environment variables overriding the configuration file
+const (
+	EnvDefaultImage      = "JSONSERVER_DEFAULT_IMAGE"
+	EnvAllowedRegistries = "JSONSERVER_ALLOWED_REGISTRIES"
+	EnvEphemeralTTL      = "JSONSERVER_EPHEMERAL_TTL"
+	EnvActivatorImage    = "JSONSERVER_ACTIVATOR_IMAGE"
+	EnvAllowedURLHosts   = "JSONSERVER_ALLOWED_URL_HOSTS"
+)
+
+// Config holds the operator-wide defaults of the JsonServers
+type Config struct {
+	// DefaultImage is the json-server image of the JsonServers that set no spec.image
+	DefaultImage string `json:"defaultImage,omitempty"`
+
+	// AllowedRegistries are the registries the json-server images can be pulled from,
+	// as glob patterns such as "*.example.com". Any registry is allowed when empty.
+	AllowedRegistries []string `json:"allowedRegistries,omitempty"`
+
+	// EphemeralTTL is the time after their creation the JsonServers of the namespaces labeled
+	// example.example.com/ephemeral=true are deleted, when they set no TTL. Zero disables it.
+	EphemeralTTL metav1.Duration `json:"ephemeralTTL,omitempty"`
+
+	// ActivatorImage is the image of the activator receiving the requests of the JsonServers
+	// scaled to zero, the operator image. Idle JsonServers are never scaled to zero without it.
+	ActivatorImage string `json:"activatorImage,omitempty"`
+
+	// AllowedURLHosts are the hosts the documents of spec.dataFrom.http can be downloaded from,
+	// as glob patterns such as "*.example.com". Any host is allowed when empty. Only the hosts
+	// matching a pattern may resolve to private addresses, such as the Services of the cluster.
+	AllowedURLHosts []string `json:"allowedURLHosts,omitempty"`
+}
+
+// Default returns the built-in configuration
+func Default() Config {
+	return Config{DefaultImage: examplev1.DefaultImage}
+}
+
+// Load reads the configuration file at path on top of the built-in configuration.
+// An empty path returns the built-in configuration. The configuration is not validated, the
+// environment and the flags may still override it, see Validate.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse configuration file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ApplyEnv overrides the configuration with the environment variables that are set
+func (c *Config) ApplyEnv(lookupEnv func(string) (string, bool)) error {
+	if value, ok := lookupEnv(EnvDefaultImage); ok && value != "" {
+		c.DefaultImage = value
+	}
+	if value, ok := lookupEnv(EnvAllowedRegistries); ok && value != "" {
+		c.AllowedRegistries = SplitList(value)
+	}
+	if value, ok := lookupEnv(EnvEphemeralTTL); ok && value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", EnvEphemeralTTL, err)
+		}
+		c.EphemeralTTL.Duration = ttl
+	}
+	if value, ok := lookupEnv(EnvActivatorImage); ok && value != "" {
+		c.ActivatorImage = value
+	}
+	if value, ok := lookupEnv(EnvAllowedURLHosts); ok && value != "" {
+		c.AllowedURLHosts = SplitList(value)
+	}
+	return nil
+}
+
+// Validate checks the default image is a valid reference from an allowed registry, the
+// activator image a valid reference, the ephemeral TTL is not negative and the URL host
+// patterns are valid
+func (c *Config) Validate() error {
+	if c.EphemeralTTL.Duration < 0 {
+		return fmt.Errorf("ephemeralTTL must not be negative")
+	}
+	for _, pattern := range c.AllowedURLHosts {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid allowedURLHosts pattern %q: %w", pattern, err)
+		}
+	}
+	if c.ActivatorImage != "" {
+		if _, err := image.Registry(c.ActivatorImage); err != nil {
+			return fmt.Errorf("invalid activatorImage: %w", err)
+		}
+	}
+	if c.DefaultImage == "" {
+		return fmt.Errorf("defaultImage must not be empty")
+	}
+	allowed, err := image.RegistryAllowed(c.DefaultImage, c.AllowedRegistries)
+	if err != nil {
+		return fmt.Errorf("invalid defaultImage: %w", err)
+	}
+	if !allowed {
+		return fmt.Errorf("defaultImage %q is not pulled from one of the allowed registries %s",
+			c.DefaultImage, strings.Join(c.AllowedRegistries, ", "))
+	}
+	return nil
+}
+
+// CheckURL checks the document of a URL of spec.dataFrom.http can be downloaded: the URL is
+// an http or https URL whose host matches one of AllowedURLHosts. It reports whether the host
+// matched a pattern, which lets it resolve to private addresses, see CheckAddress.
+func (c *Config) CheckURL(rawURL string) (bool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false, fmt.Errorf("scheme %q is not allowed, use http or https", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return false, fmt.Errorf("URL has no host")
+	}
+	if len(c.AllowedURLHosts) == 0 {
+		return false, nil
+	}
+	if c.HostAllowed(u.Hostname()) {
+		return true, nil
+	}
+	return false, fmt.Errorf("host %q does not match one of %s", u.Hostname(), strings.Join(c.AllowedURLHosts, ", "))
+}
+
+// HostAllowed reports whether the host matches one of AllowedURLHosts
+func (c *Config) HostAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range c.AllowedURLHosts {
+		if matched, err := path.Match(strings.ToLower(pattern), host); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAddress checks a URL of spec.dataFrom.http may be downloaded from the address its host
+// resolved to. Loopback, link-local, such as the metadata endpoint of cloud providers,
+// unspecified and multicast addresses are always denied. Private addresses, such as the
+// Services and the API server of the cluster, are only allowed for the hosts matching one of
+// AllowedURLHosts.
+func CheckAddress(addr netip.Addr, hostAllowed bool) error {
+	addr = addr.Unmap()
+	switch {
+	case addr.IsLoopback(), addr.IsLinkLocalUnicast(), addr.IsLinkLocalMulticast(),
+		addr.IsInterfaceLocalMulticast(), addr.IsUnspecified(), addr.IsMulticast():
+		return fmt.Errorf("address %s is not allowed", addr)
+	case !hostAllowed && (addr.IsPrivate() || sharedAddressSpace.Contains(addr)):
+		return fmt.Errorf("private address %s is only allowed for the hosts of allowedURLHosts", addr)
+	}
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, used as a private range by some clusters
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// SplitList splits a comma separated list, ignoring the empty entries
+func SplitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
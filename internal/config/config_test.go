@@ -0,0 +1,129 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config_test
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/config"
+)
+
+var _ = Describe("Operator configuration", func() {
+	writeConfig := func(content string) string {
+		path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		Expect(os.WriteFile(path, []byte(content), 0o600)).To(Succeed())
+		return path
+	}
+
+	It("defaults to the built-in image", func() {
+		cfg, err := config.Load("")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.DefaultImage).To(Equal(examplev1.DefaultImage))
+		Expect(cfg.AllowedRegistries).To(BeEmpty())
+	})
+
+	It("reads the configuration file", func() {
+		cfg, err := config.Load(writeConfig("defaultImage: registry.example.com/json-server:0.17.4\nallowedRegistries: [registry.example.com]\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.DefaultImage).To(Equal("registry.example.com/json-server:0.17.4"))
+		Expect(cfg.AllowedRegistries).To(Equal([]string{"registry.example.com"}))
+	})
+
+	It("reads the ephemeral TTL", func() {
+		cfg, err := config.Load(writeConfig("ephemeralTTL: 24h\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.EphemeralTTL.Duration).To(Equal(24 * time.Hour))
+
+		cfg, err = config.Load(writeConfig("ephemeralTTL: -1h\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Validate()).To(MatchError(ContainSubstring("ephemeralTTL must not be negative")))
+	})
+
+	It("rejects unknown fields and default images from other registries", func() {
+		_, err := config.Load(writeConfig("defaultImages: foo\n"))
+		Expect(err).To(HaveOccurred())
+
+		cfg, err := config.Load(writeConfig("allowedRegistries: [registry.example.com]\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Validate()).To(MatchError(ContainSubstring("is not pulled from one of the allowed registries")))
+	})
+
+	It("is validated once the environment is applied", func() {
+		cfg, err := config.Load(writeConfig("allowedRegistries: [registry.example.com]\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.ApplyEnv(func(key string) (string, bool) {
+			return map[string]string{
+				config.EnvDefaultImage: "registry.example.com/json-server:0.17.4",
+			}[key], true
+		})).To(Succeed())
+		Expect(cfg.Validate()).To(Succeed())
+	})
+
+	It("restricts the URLs and the addresses documents are downloaded from", func() {
+		cfg, err := config.Load(writeConfig("allowedURLHosts: ['*.example.com', 'fixtures.default.svc']\n"))
+		Expect(err).NotTo(HaveOccurred())
+
+		hostAllowed, err := cfg.CheckURL("https://api.example.com/db.json")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hostAllowed).To(BeTrue())
+		_, err = cfg.CheckURL("https://example.org/db.json")
+		Expect(err).To(MatchError(ContainSubstring(`host "example.org" does not match one of *.example.com, fixtures.default.svc`)))
+		_, err = cfg.CheckURL("file:///etc/passwd")
+		Expect(err).To(MatchError(ContainSubstring(`scheme "file" is not allowed`)))
+
+		Expect(config.CheckAddress(netip.MustParseAddr("93.184.216.34"), false)).To(Succeed())
+		Expect(config.CheckAddress(netip.MustParseAddr("10.96.0.1"), true)).To(Succeed())
+		Expect(config.CheckAddress(netip.MustParseAddr("10.96.0.1"), false)).To(MatchError(ContainSubstring("private address")))
+		Expect(config.CheckAddress(netip.MustParseAddr("169.254.169.254"), true)).To(MatchError(ContainSubstring("not allowed")))
+		Expect(config.CheckAddress(netip.MustParseAddr("::ffff:127.0.0.1"), true)).To(MatchError(ContainSubstring("not allowed")))
+
+		cfg, err = config.Load(writeConfig("allowedURLHosts: ['[example.com']\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfg.Validate()).To(MatchError(ContainSubstring("invalid allowedURLHosts pattern")))
+	})
+
+	It("is overridden by the environment", func() {
+		cfg := config.Default()
+		Expect(cfg.ApplyEnv(func(key string) (string, bool) {
+			return map[string]string{
+				config.EnvDefaultImage:      "mirror.example.com/json-server",
+				config.EnvAllowedRegistries: "mirror.example.com, ,*.example.org",
+				config.EnvEphemeralTTL:      "36h",
+				config.EnvActivatorImage:    "chickenbeef/jsonserver-operator:0.2.0",
+			}[key], true
+		})).To(Succeed())
+		Expect(cfg.DefaultImage).To(Equal("mirror.example.com/json-server"))
+		Expect(cfg.AllowedRegistries).To(Equal([]string{"mirror.example.com", "*.example.org"}))
+		Expect(cfg.EphemeralTTL.Duration).To(Equal(36 * time.Hour))
+		Expect(cfg.ActivatorImage).To(Equal("chickenbeef/jsonserver-operator:0.2.0"))
+		Expect(cfg.Validate()).To(Succeed())
+
+		cfg.ActivatorImage = "Operator:latest"
+		Expect(cfg.Validate()).To(MatchError(ContainSubstring("invalid activatorImage")))
+
+		Expect(cfg.ApplyEnv(func(key string) (string, bool) {
+			return map[string]string{config.EnvEphemeralTTL: "a day"}[key], true
+		})).To(MatchError(ContainSubstring(config.EnvEphemeralTTL)))
+	})
+})
@@ -0,0 +1,136 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+// stepAccess is the reconcile step of the NetworkPolicies restricting the clients of the pods
+const stepAccess = "Access"
+
+// operatorPodLabels are the labels of the operator pods, as set in config/manager
+var operatorPodLabels = map[string]string{
+	"control-plane":          "controller-manager",
+	"app.kubernetes.io/name": "jsonserver-operator",
+}
+
+// reconcileAccess ensures the NetworkPolicies of the json-server pods and of their activator
+// exist when the spec has an access section, and removes them otherwise. The activator
+// receives the requests of idle JsonServers, it lets in the same clients as the pods.
+func (r *JsonServerReconciler) reconcileAccess(ctx context.Context, jsonServer *examplev1.JsonServer) error {
+	access := jsonServer.Spec.Access
+	if access == nil {
+		if err := r.deleteOwned(ctx, jsonServer, jsonServer.Name, &networkingv1.NetworkPolicy{}); err != nil {
+			return err
+		}
+		return r.deleteOwned(ctx, jsonServer, activatorName(jsonServer), &networkingv1.NetworkPolicy{})
+	}
+
+	clients := accessPeers(access)
+	// Snapshots are read from the pods by the operator, the activator forwards the requests
+	// of idle JsonServers and the replicas of single-writer JsonServers sync from the writer
+	peers := append(slices.Clone(clients),
+		networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{MatchLabels: activatorLabels(jsonServer)}},
+		networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{MatchLabels: getResourceLabels(jsonServer)}},
+	)
+	if r.OperatorNamespace != "" {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelMetadataName: r.OperatorNamespace}},
+			PodSelector:       &metav1.LabelSelector{MatchLabels: operatorPodLabels},
+		})
+	}
+	if err := r.reconcileNetworkPolicy(ctx, jsonServer, jsonServer.Name, getResourceLabels(jsonServer), peers); err != nil {
+		return err
+	}
+
+	if !r.scalesToZero(jsonServer) {
+		return r.deleteOwned(ctx, jsonServer, activatorName(jsonServer), &networkingv1.NetworkPolicy{})
+	}
+	return r.reconcileNetworkPolicy(ctx, jsonServer, activatorName(jsonServer), activatorLabels(jsonServer), clients)
+}
+
+// accessPeers returns the clients allowed by the access section
+func accessPeers(access *examplev1.AccessSpec) []networkingv1.NetworkPolicyPeer {
+	peers := make([]networkingv1.NetworkPolicyPeer, 0, len(access.From)+1)
+	for _, peer := range access.From {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{
+			NamespaceSelector: peer.NamespaceSelector.DeepCopy(),
+			PodSelector:       peer.PodSelector.DeepCopy(),
+		})
+	}
+	// A pod selector without namespace selector selects the pods of the same namespace
+	if !access.DefaultDeny {
+		peers = append(peers, networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{}})
+	}
+	return peers
+}
+
+// reconcileNetworkPolicy ensures the named NetworkPolicy only lets the peers reach the port
+// json-server, or the activator, listens on in the selected pods. Without peer, the pods
+// cannot be reached at all.
+func (r *JsonServerReconciler) reconcileNetworkPolicy(ctx context.Context, jsonServer *examplev1.JsonServer, name string,
+	podLabels map[string]string, peers []networkingv1.NetworkPolicyPeer) error {
+	log := logf.FromContext(ctx)
+
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: jsonServer.Namespace,
+			Labels:    getResourceLabels(jsonServer),
+		},
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, policy, func() error {
+		if err := controllerutil.SetControllerReference(jsonServer, policy, r.Scheme); err != nil {
+			return err
+		}
+
+		policy.Spec.PodSelector = metav1.LabelSelector{MatchLabels: podLabels}
+		policy.Spec.PolicyTypes = []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+		policy.Spec.Ingress = nil
+		if len(peers) > 0 {
+			policy.Spec.Ingress = []networkingv1.NetworkPolicyIngressRule{
+				{
+					From: peers,
+					Ports: []networkingv1.NetworkPolicyPort{
+						{Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(containerPort))},
+					},
+				},
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error(err, "Failed to create or update NetworkPolicy", "networkPolicy", name)
+		return err
+	}
+
+	log.Info("NetworkPolicy reconciled", "networkPolicy", name, "operation", op)
+	return nil
+}
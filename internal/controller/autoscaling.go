@@ -0,0 +1,179 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+const (
+	// stepAutoscaling is the reconcile step of the HorizontalPodAutoscaler
+	stepAutoscaling = "Autoscaling"
+	// stepDisruptionBudget is the reconcile step of the PodDisruptionBudget
+	stepDisruptionBudget = "DisruptionBudget"
+	// defaultTargetCPUUtilization is the CPU target of the autoscaling sections that set no target
+	defaultTargetCPUUtilization = 80
+)
+
+// desiredReplicas returns the replicas of the workload given its current ones, nil when it does
+// not exist yet: zero while the JsonServer is idle, spec.replicas, the scale subresource, unless
+// the JsonServer autoscales. The replicas of autoscaled JsonServers are left to their
+// HorizontalPodAutoscaler, which does not scale workloads at zero replicas: new or activated
+// workloads start at the minimum.
+func desiredReplicas(jsonServer *examplev1.JsonServer, current *int32) int32 {
+	if jsonServer.Status.IdleSince != nil {
+		return 0
+	}
+	autoscaling := jsonServer.Spec.Autoscaling
+	if autoscaling == nil {
+		return jsonServer.Spec.Replicas
+	}
+	if current == nil || *current == 0 {
+		return minReplicas(autoscaling)
+	}
+	return *current
+}
+
+// minReplicas returns the lower limit of the autoscaled replicas
+func minReplicas(autoscaling *examplev1.AutoscalingSpec) int32 {
+	if autoscaling.MinReplicas != nil {
+		return *autoscaling.MinReplicas
+	}
+	return 1
+}
+
+// reconcileAutoscaler ensures the HorizontalPodAutoscaler of the workload exists when the
+// JsonServer autoscales, and removes it otherwise
+func (r *JsonServerReconciler) reconcileAutoscaler(ctx context.Context, jsonServer *examplev1.JsonServer) error {
+	log := logf.FromContext(ctx)
+	autoscaling := jsonServer.Spec.Autoscaling
+	if autoscaling == nil {
+		return r.deleteOwned(ctx, jsonServer, jsonServer.Name, &autoscalingv2.HorizontalPodAutoscaler{})
+	}
+
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jsonServer.Name,
+			Namespace: jsonServer.Namespace,
+			Labels:    getResourceLabels(jsonServer),
+		},
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, hpa, func() error {
+		if err := controllerutil.SetControllerReference(jsonServer, hpa, r.Scheme); err != nil {
+			return err
+		}
+
+		kind := "Deployment"
+		if runsAsStatefulSet(jsonServer) {
+			kind = "StatefulSet"
+		}
+		hpa.Spec.ScaleTargetRef = autoscalingv2.CrossVersionObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       kind,
+			Name:       jsonServer.Name,
+		}
+		hpa.Spec.MinReplicas = ptr.To(minReplicas(autoscaling))
+		hpa.Spec.MaxReplicas = autoscaling.MaxReplicas
+
+		cpu := autoscaling.TargetCPUUtilizationPercentage
+		if cpu == nil && autoscaling.TargetMemoryUtilizationPercentage == nil {
+			cpu = ptr.To(int32(defaultTargetCPUUtilization))
+		}
+		hpa.Spec.Metrics = nil
+		if cpu != nil {
+			hpa.Spec.Metrics = append(hpa.Spec.Metrics, utilizationMetric(corev1.ResourceCPU, *cpu))
+		}
+		if memory := autoscaling.TargetMemoryUtilizationPercentage; memory != nil {
+			hpa.Spec.Metrics = append(hpa.Spec.Metrics, utilizationMetric(corev1.ResourceMemory, *memory))
+		}
+		return nil
+	})
+	if err != nil {
+		log.Error(err, "Failed to create or update HorizontalPodAutoscaler")
+		return err
+	}
+
+	log.Info("HorizontalPodAutoscaler reconciled", "operation", op)
+	return nil
+}
+
+// utilizationMetric returns the metric of an average utilization target of a pod resource
+func utilizationMetric(name corev1.ResourceName, percentage int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2.MetricTarget{
+				Type:               autoscalingv2.UtilizationMetricType,
+				AverageUtilization: ptr.To(percentage),
+			},
+		},
+	}
+}
+
+// reconcileDisruptionBudget ensures the PodDisruptionBudget of the json-server pods exists when
+// the spec has a disruptionBudget section, and removes it otherwise
+func (r *JsonServerReconciler) reconcileDisruptionBudget(ctx context.Context, jsonServer *examplev1.JsonServer) error {
+	log := logf.FromContext(ctx)
+	budget := jsonServer.Spec.DisruptionBudget
+	if budget == nil {
+		return r.deleteOwned(ctx, jsonServer, jsonServer.Name, &policyv1.PodDisruptionBudget{})
+	}
+
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jsonServer.Name,
+			Namespace: jsonServer.Namespace,
+			Labels:    getResourceLabels(jsonServer),
+		},
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, pdb, func() error {
+		if err := controllerutil.SetControllerReference(jsonServer, pdb, r.Scheme); err != nil {
+			return err
+		}
+
+		pdb.Spec.Selector = &metav1.LabelSelector{MatchLabels: getResourceLabels(jsonServer)}
+		pdb.Spec.MinAvailable = budget.MinAvailable
+		pdb.Spec.MaxUnavailable = budget.MaxUnavailable
+		if budget.MinAvailable == nil && budget.MaxUnavailable == nil {
+			pdb.Spec.MaxUnavailable = ptr.To(intstr.FromInt32(1))
+		}
+		// Pods that are not ready serve nothing, evicting them must not block node drains
+		pdb.Spec.UnhealthyPodEvictionPolicy = ptr.To(policyv1.AlwaysAllow)
+		return nil
+	})
+	if err != nil {
+		log.Error(err, "Failed to create or update PodDisruptionBudget")
+		return err
+	}
+
+	log.Info("PodDisruptionBudget reconciled", "operation", op)
+	return nil
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/payload"
+)
+
+const (
+	// checksumKey holds the SHA-256 of a compressed document, checked once it is reassembled
+	checksumKey = "db.json.sha256"
+	// chunkLabel holds the index of the chunk kept in a chunk ConfigMap
+	chunkLabel = "example.example.com/chunk"
+	// chunksAnnotation holds the number of chunks of a compressed document on the ConfigMap
+	// of the JsonServer, which keeps the first one
+	chunksAnnotation = "example.example.com/chunks"
+	// chunksVolumeName is the volume of the chunks the unpack init container reassembles
+	chunksVolumeName = "chunks"
+)
+
+// unpackScript reassembles the chunks of a compressed document into /data/db.json. Chunks
+// updated while the pod starts fail the checksum, the init container is restarted until
+// they are consistent.
+const unpackScript = `set -e
+cat /chunks/db.json.gz.* | gunzip > /data/db.json.tmp
+echo "$(cat /chunks/db.json.sha256)  /data/db.json.tmp" | sha256sum -c -
+mv /data/db.json.tmp /data/db.json
+`
+
+// chunkKey is the key of a chunk of a compressed document. The keys sort in the order the
+// chunks are concatenated.
+func chunkKey(index int) string {
+	return fmt.Sprintf("db.json.gz.%03d", index)
+}
+
+// chunkName is the name of the ConfigMap holding a chunk past the first one
+func chunkName(name string, index int) string {
+	return fmt.Sprintf("%s-chunk-%d", name, index)
+}
+
+// checksum returns the SHA-256 of a document as printed by sha256sum
+func checksum(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// chunkCount returns the number of chunks of the compressed document of the ConfigMap, or of
+// the data Secret, of the JsonServer, zero when it holds the document as is
+func chunkCount(obj metav1.Object) int {
+	count, _ := strconv.Atoi(obj.GetAnnotations()[chunksAnnotation])
+	return count
+}
+
+// setDocument stores the document in the ConfigMap of the JsonServer: as is, or the first
+// chunk of the compressed document along with its checksum and number of chunks
+func setDocument(configMap *corev1.ConfigMap, data string, chunks [][]byte) {
+	if len(chunks) == 0 {
+		configMap.Data["db.json"] = data
+		delete(configMap.Data, checksumKey)
+		delete(configMap.Annotations, chunksAnnotation)
+		configMap.BinaryData = nil
+		return
+	}
+	delete(configMap.Data, "db.json")
+	configMap.Data[checksumKey] = checksum(data)
+	metav1.SetMetaDataAnnotation(&configMap.ObjectMeta, chunksAnnotation, strconv.Itoa(len(chunks)))
+	configMap.BinaryData = map[string][]byte{chunkKey(0): chunks[0]}
+}
+
+// clearDocument removes the document from the ConfigMap of the JsonServer, kept in its data
+// Secret instead
+func clearDocument(configMap *corev1.ConfigMap) {
+	delete(configMap.Data, "db.json")
+	delete(configMap.Data, checksumKey)
+	delete(configMap.Annotations, chunksAnnotation)
+	configMap.BinaryData = nil
+}
+
+// readDocument returns the document stored in the ConfigMap of the JsonServer, reassembling
+// the chunks of compressed documents. It reports false when the document or one of its
+// chunks is missing, or when the chunks do not match the checksum.
+func (r *JsonServerReconciler) readDocument(ctx context.Context, configMap *corev1.ConfigMap) (string, bool) {
+	count := chunkCount(configMap)
+	if count == 0 {
+		data, ok := configMap.Data["db.json"]
+		return data, ok
+	}
+
+	compressed := slices.Clone(configMap.BinaryData[chunkKey(0)])
+	for index := 1; index < count; index++ {
+		chunk := &corev1.ConfigMap{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: configMap.Namespace, Name: chunkName(configMap.Name, index)}, chunk); err != nil {
+			return "", false
+		}
+		compressed = append(compressed, chunk.BinaryData[chunkKey(index)]...)
+	}
+	data, err := payload.Decompress(compressed)
+	if err != nil || checksum(data) != configMap.Data[checksumKey] {
+		return "", false
+	}
+	return data, true
+}
+
+// readSecretDocument returns the document stored in a Secret laid out like the data Secret of
+// the JsonServers, which keeps compressed documents in a single chunk. It reports false when
+// the document is missing or does not match the checksum.
+func readSecretDocument(secret *corev1.Secret) (string, bool) {
+	switch chunkCount(secret) {
+	case 0:
+		data, ok := secret.Data["db.json"]
+		return string(data), ok
+	case 1:
+		data, err := payload.Decompress(secret.Data[chunkKey(0)])
+		if err != nil || checksum(data) != string(secret.Data[checksumKey]) {
+			return "", false
+		}
+		return data, true
+	default:
+		return "", false
+	}
+}
+
+// reconcileChunks ensures the ConfigMaps holding the chunks of a compressed document past the
+// first one exist, and removes the chunks left over by larger documents
+func (r *JsonServerReconciler) reconcileChunks(ctx context.Context, jsonServer *examplev1.JsonServer, chunks [][]byte) error {
+	log := logf.FromContext(ctx)
+
+	for index := 1; index < len(chunks); index++ {
+		chunk := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      chunkName(jsonServer.Name, index),
+				Namespace: jsonServer.Namespace,
+				Labels:    getResourceLabels(jsonServer),
+			},
+		}
+		op, err := controllerutil.CreateOrUpdate(ctx, r.Client, chunk, func() error {
+			if err := controllerutil.SetControllerReference(jsonServer, chunk, r.Scheme); err != nil {
+				return err
+			}
+			chunk.Labels[chunkLabel] = strconv.Itoa(index)
+			chunk.Data = nil
+			chunk.BinaryData = map[string][]byte{chunkKey(index): chunks[index]}
+			return nil
+		})
+		if err != nil {
+			log.Error(err, "Failed to create or update chunk ConfigMap", "chunk", index)
+			return err
+		}
+		log.Info("Chunk ConfigMap reconciled", "chunk", index, "operation", op)
+	}
+
+	existing := &corev1.ConfigMapList{}
+	if err := r.List(ctx, existing, client.InNamespace(jsonServer.Namespace),
+		client.MatchingLabels(getResourceLabels(jsonServer)), client.HasLabels{chunkLabel}); err != nil {
+		log.Error(err, "Failed to list chunk ConfigMaps")
+		return err
+	}
+	for i := range existing.Items {
+		chunk := &existing.Items[i]
+		index, err := strconv.Atoi(chunk.Labels[chunkLabel])
+		if !metav1.IsControlledBy(chunk, jsonServer) || (err == nil && index > 0 && index < len(chunks)) {
+			continue
+		}
+		if err := r.Delete(ctx, chunk, client.Preconditions{UID: &chunk.UID}); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Failed to delete chunk ConfigMap", "configMap", chunk.Name)
+			return err
+		}
+		log.Info("Chunk ConfigMap deleted", "configMap", chunk.Name)
+	}
+	return nil
+}
+
+// documentVolume returns the volume the document is read from: the ConfigMap of the
+// JsonServer when it holds the document as is, a projection of the chunks of the compressed
+// document and of its checksum otherwise. Documents read from Secrets are read from the data
+// Secret instead.
+func documentVolume(name string, configMap *corev1.ConfigMap, secret *corev1.Secret) corev1.Volume {
+	if secret != nil {
+		return secretDocumentVolume(name, secret)
+	}
+	count := chunkCount(configMap)
+	if count == 0 {
+		return corev1.Volume{
+			Name: name,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name},
+				},
+			},
+		}
+	}
+
+	sources := []corev1.VolumeProjection{
+		{
+			ConfigMap: &corev1.ConfigMapProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name},
+				Items: []corev1.KeyToPath{
+					{Key: chunkKey(0), Path: chunkKey(0)},
+					{Key: checksumKey, Path: checksumKey},
+				},
+			},
+		},
+	}
+	for index := 1; index < count; index++ {
+		sources = append(sources, corev1.VolumeProjection{
+			ConfigMap: &corev1.ConfigMapProjection{
+				LocalObjectReference: corev1.LocalObjectReference{Name: chunkName(configMap.Name, index)},
+				Items:                []corev1.KeyToPath{{Key: chunkKey(index), Path: chunkKey(index)}},
+			},
+		})
+	}
+	return corev1.Volume{
+		Name:         name,
+		VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: sources}},
+	}
+}
+
+// unpackContainer returns the init container reassembling the compressed document into the
+// "json-config" volume json-server serves
+func unpackContainer(image string) corev1.Container {
+	return corev1.Container{
+		Name:    "unpack",
+		Image:   image,
+		Command: []string{"sh", "-c", unpackScript},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "json-config", MountPath: "/data"},
+			{Name: chunksVolumeName, MountPath: "/chunks", ReadOnly: true},
+		},
+	}
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/fixture"
+)
+
+// resolveCollections reads the collections of the spec from their sources, in the order of
+// their names
+func (r *JsonServerReconciler) resolveCollections(ctx context.Context, jsonServer *examplev1.JsonServer) (map[string]fixture.Input, *reconcileError) {
+	inputs := make(map[string]fixture.Input, len(jsonServer.Spec.Collections))
+	for _, name := range slices.Sorted(maps.Keys(jsonServer.Spec.Collections)) {
+		input, rerr := r.resolveCollection(ctx, jsonServer, name)
+		if rerr != nil {
+			return nil, rerr
+		}
+		inputs[name] = input
+	}
+	return inputs, nil
+}
+
+// resolveCollection reads a collection of the spec: written in the spec, or read from a key of
+// a ConfigMap, from a JsonFixture or from the configuration served by another JsonServer
+func (r *JsonServerReconciler) resolveCollection(ctx context.Context, jsonServer *examplev1.JsonServer, name string) (fixture.Input, *reconcileError) {
+	collection := jsonServer.Spec.Collections[name]
+	switch {
+	case collection.ConfigMapKeyRef != nil:
+		ref := collection.ConfigMapKeyRef
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: jsonServer.Namespace, Name: ref.Name}, configMap); err != nil {
+			return fixture.Input{}, sourceError(fmt.Sprintf("ConfigMap %q", ref.Name), err)
+		}
+		data, ok := configMap.Data[ref.Key]
+		if !ok {
+			return fixture.Input{}, missingKeyError(fmt.Sprintf("ConfigMap %q", ref.Name), ref.Key)
+		}
+		return fixture.NewInput(fmt.Sprintf("key %q of ConfigMap %q", ref.Key, ref.Name), collection.Format, data, collection.Columns), nil
+
+	case collection.FixtureRef != nil:
+		ref := collection.FixtureRef
+		key := referencedCollection(ref, name)
+		jsonFixture := &examplev1.JsonFixture{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: jsonServer.Namespace, Name: ref.Name}, jsonFixture); err != nil {
+			return fixture.Input{}, sourceError(fmt.Sprintf("JsonFixture %q", ref.Name), err)
+		}
+		fixtureCollection, ok := jsonFixture.Spec.Collections[key]
+		if !ok {
+			return fixture.Input{}, missingCollectionError(fmt.Sprintf("JsonFixture %q", ref.Name), key)
+		}
+		return fixture.NewInput(fmt.Sprintf("collection %q of JsonFixture %q", key, ref.Name),
+			fixtureCollection.Format, fixtureCollection.Data, fixtureCollection.Columns), nil
+
+	case collection.JsonServerRef != nil:
+		return r.resolveServedCollection(ctx, jsonServer, name)
+
+	default:
+		return fixture.NewInput(fixture.CollectionPath(name).String(), collection.Format, collection.Data, collection.Columns), nil
+	}
+}
+
+// resolveServedCollection reads a collection of the configuration served by another
+// JsonServer, as held in its ConfigMap
+func (r *JsonServerReconciler) resolveServedCollection(ctx context.Context, jsonServer *examplev1.JsonServer, name string) (fixture.Input, *reconcileError) {
+	ref := jsonServer.Spec.Collections[name].JsonServerRef
+	key := referencedCollection(ref, name)
+	description := fmt.Sprintf("JsonServer %q", ref.Name)
+	if ref.Name == jsonServer.Name {
+		return fixture.Input{}, &reconcileError{Step: stepDataSource, Reason: reasonSourceNotFound,
+			Err: fmt.Errorf("spec.collections[%s] cannot read the collections the JsonServer serves itself", name)}
+	}
+
+	served := &examplev1.JsonServer{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: jsonServer.Namespace, Name: ref.Name}, served); err != nil {
+		return fixture.Input{}, sourceError(description, err)
+	}
+	// The ConfigMap of the JsonServer is watched, writing it triggers a new reconcile
+	notServed := &reconcileError{Step: stepDataSource, Reason: reasonSourceNotFound,
+		Err: fmt.Errorf("%s does not serve its configuration yet", description)}
+	configMap := &corev1.ConfigMap{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: served.Namespace, Name: served.Name}, configMap); apierrors.IsNotFound(err) {
+		return fixture.Input{}, notServed
+	} else if err != nil {
+		return fixture.Input{}, sourceError(description, err)
+	}
+	if _, ok := configMap.Annotations[dataSecretAnnotation]; ok && metav1.IsControlledBy(configMap, served) {
+		return fixture.Input{}, &reconcileError{Step: stepDataSource, Reason: reasonSourceNotFound,
+			Err: fmt.Errorf("%s reads its data from a Secret, its collections cannot be shared", description)}
+	}
+	data, ok := r.readDocument(ctx, configMap)
+	if !ok || !metav1.IsControlledBy(configMap, served) {
+		return fixture.Input{}, notServed
+	}
+
+	source := fmt.Sprintf("collection %q of %s", key, description)
+	document, err := fixture.Decode(fixture.Input{Source: source, Data: data})
+	if err != nil {
+		return fixture.Input{}, &reconcileError{Step: stepDataSource, Reason: reasonConversionFailed, Err: err}
+	}
+	collections, _ := document.(map[string]any)
+	value, ok := collections[key]
+	if !ok {
+		return fixture.Input{}, missingCollectionError(description, key)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fixture.Input{}, &reconcileError{Step: stepDataSource, Reason: reasonConversionFailed, Err: err}
+	}
+	return fixture.Input{Source: source, Format: fixture.JSON, Data: string(encoded)}, nil
+}
+
+// referencedCollection returns the name of the collection read from another object, the name
+// of the collection being defined unless the reference names another one
+func referencedCollection(ref *examplev1.CollectionReference, name string) string {
+	if ref.Collection != "" {
+		return ref.Collection
+	}
+	return name
+}
+
+// missingCollectionError reports a collection missing from a JsonFixture or a JsonServer
+func missingCollectionError(description, collection string) *reconcileError {
+	return &reconcileError{Step: stepDataSource, Reason: reasonSourceNotFound,
+		Err: fmt.Errorf("%s has no collection %q", description, collection)}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+// Reasons used for the JsonServer status conditions
+const (
+	reasonValid               = "Valid"
+	reasonInvalidJSON         = "InvalidJSON"
+	reasonInvalidStructure    = "InvalidStructure"
+	reasonConfigTooLarge      = "ConfigTooLarge"
+	reasonConversionFailed    = "ConversionFailed"
+	reasonGenerationFailed    = "GenerationFailed"
+	reasonReconciled          = "Reconciled"
+	reasonReconcileFailed     = "ReconcileFailed"
+	reasonMinimumReplicas     = "MinimumReplicasAvailable"
+	reasonReplicasUnavailable = "ReplicasUnavailable"
+	reasonRollingOut          = "RollingOut"
+	reasonRolloutComplete     = "RolloutComplete"
+	reasonDeadlineExceeded    = "ProgressDeadlineExceeded"
+	reasonReady               = "AllReplicasReady"
+	reasonNotReady            = "NotReady"
+	reasonPending             = "Pending"
+)
+
+// setCondition records a condition for the current generation of the JsonServer
+func setCondition(jsonServer *examplev1.JsonServer, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&jsonServer.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: jsonServer.Generation,
+	})
+}
+
+// workloadState is the observed state of the Deployment or StatefulSet running the json-server pods
+type workloadState struct {
+	desired           int32
+	readyReplicas     int32
+	availableReplicas int32
+	updatedReplicas   int32
+	// available reports whether the workload runs its minimum number of available replicas
+	available bool
+	// rolledOut reports whether all replicas run the latest pod template and are available
+	rolledOut bool
+	// stalledMessage explains why the rollout stopped progressing, if it did
+	stalledMessage string
+}
+
+// deploymentState returns the observed state of a Deployment
+func deploymentState(deployment *appsv1.Deployment) workloadState {
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+	status := deployment.Status
+	state := workloadState{
+		desired:           desired,
+		readyReplicas:     status.ReadyReplicas,
+		availableReplicas: status.AvailableReplicas,
+		updatedReplicas:   status.UpdatedReplicas,
+		rolledOut: status.ObservedGeneration >= deployment.Generation &&
+			status.UpdatedReplicas == desired &&
+			status.Replicas == desired &&
+			status.AvailableReplicas == desired,
+	}
+	if available := deploymentCondition(deployment, appsv1.DeploymentAvailable); available != nil {
+		state.available = available.Status == corev1.ConditionTrue
+	}
+	if progressing := deploymentCondition(deployment, appsv1.DeploymentProgressing); progressing != nil &&
+		progressing.Reason == reasonDeadlineExceeded {
+		state.stalledMessage = progressing.Message
+	}
+	return state
+}
+
+// statefulSetState returns the observed state of a StatefulSet. A StatefulSet has no Available
+// condition: like a rolling update, it tolerates one unavailable replica.
+func statefulSetState(statefulSet *appsv1.StatefulSet) workloadState {
+	desired := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		desired = *statefulSet.Spec.Replicas
+	}
+	status := statefulSet.Status
+	return workloadState{
+		desired:           desired,
+		readyReplicas:     status.ReadyReplicas,
+		availableReplicas: status.AvailableReplicas,
+		updatedReplicas:   status.UpdatedReplicas,
+		available:         status.AvailableReplicas > 0 && status.AvailableReplicas >= desired-1,
+		rolledOut: status.ObservedGeneration >= statefulSet.Generation &&
+			status.UpdatedReplicas == desired &&
+			status.Replicas == desired &&
+			status.AvailableReplicas == desired &&
+			status.CurrentRevision == status.UpdateRevision,
+	}
+}
+
+// setWorkloadConditions derives the Available, Progressing and Ready conditions
+// (and the ready replica count) from the observed state of the owned workload
+func setWorkloadConditions(jsonServer *examplev1.JsonServer, workload workloadState) {
+	desired := workload.desired
+	jsonServer.Status.ReadyReplicas = workload.readyReplicas
+
+	// Available
+	if workload.available {
+		setCondition(jsonServer, examplev1.ConditionAvailable, metav1.ConditionTrue, reasonMinimumReplicas,
+			fmt.Sprintf("%d/%d replicas available", workload.availableReplicas, desired))
+	} else {
+		setCondition(jsonServer, examplev1.ConditionAvailable, metav1.ConditionFalse, reasonReplicasUnavailable,
+			fmt.Sprintf("%d/%d replicas available", workload.availableReplicas, desired))
+	}
+
+	// Progressing
+	switch {
+	case workload.stalledMessage != "":
+		setCondition(jsonServer, examplev1.ConditionProgressing, metav1.ConditionFalse, reasonDeadlineExceeded, workload.stalledMessage)
+	case !workload.rolledOut:
+		setCondition(jsonServer, examplev1.ConditionProgressing, metav1.ConditionTrue, reasonRollingOut,
+			fmt.Sprintf("%d/%d replicas updated", workload.updatedReplicas, desired))
+	default:
+		setCondition(jsonServer, examplev1.ConditionProgressing, metav1.ConditionFalse, reasonRolloutComplete,
+			fmt.Sprintf("%d/%d replicas updated", workload.updatedReplicas, desired))
+	}
+
+	// Ready
+	if workload.rolledOut && workload.readyReplicas == desired {
+		setCondition(jsonServer, examplev1.ConditionReady, metav1.ConditionTrue, reasonReady,
+			fmt.Sprintf("%d/%d replicas ready", workload.readyReplicas, desired))
+	} else {
+		setCondition(jsonServer, examplev1.ConditionReady, metav1.ConditionFalse, reasonNotReady,
+			fmt.Sprintf("%d/%d replicas ready", workload.readyReplicas, desired))
+	}
+}
+
+// setNotReady marks the workload conditions as unknown when the reconcile stopped
+// before the Deployment could be observed
+func setNotReady(jsonServer *examplev1.JsonServer, reason, message string) {
+	if meta.FindStatusCondition(jsonServer.Status.Conditions, examplev1.ConditionAvailable) == nil {
+		setCondition(jsonServer, examplev1.ConditionAvailable, metav1.ConditionUnknown, reasonPending, message)
+	}
+	if meta.FindStatusCondition(jsonServer.Status.Conditions, examplev1.ConditionProgressing) == nil {
+		setCondition(jsonServer, examplev1.ConditionProgressing, metav1.ConditionUnknown, reasonPending, message)
+	}
+	setCondition(jsonServer, examplev1.ConditionReady, metav1.ConditionFalse, reason, message)
+}
+
+// deploymentCondition returns the Deployment condition of the given type, if any
+func deploymentCondition(deployment *appsv1.Deployment, conditionType appsv1.DeploymentConditionType) *appsv1.DeploymentCondition {
+	for i := range deployment.Status.Conditions {
+		if deployment.Status.Conditions[i].Type == conditionType {
+			return &deployment.Status.Conditions[i]
+		}
+	}
+	return nil
+}
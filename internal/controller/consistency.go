@@ -0,0 +1,173 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+const (
+	// stepWriteService is the reconcile step of the Service of single-writer JsonServers
+	stepWriteService = "WriteService"
+	// syncIntervalSeconds is how often the read replicas of a single-writer JsonServer sync their data
+	syncIntervalSeconds = 5
+	// readReplicaKey is the ConfigMap key of the json-server middleware of single-writer JsonServers
+	readReplicaKey = "read-replica.js"
+)
+
+// syncScript copies the data of the writer to the read replicas. json-server watches
+// /data/db.json and reloads it when the file changes.
+const syncScript = `while true; do
+  if [ "$POD_NAME" != "$WRITER_POD" ] && wget -q -O /data/db.json.sync "http://$WRITE_SERVICE:$WRITE_PORT/db"; then
+    if ! cmp -s /data/db.json.sync /data/db.json; then
+      mv /data/db.json.sync /data/db.json
+    fi
+  fi
+  sleep "$SYNC_INTERVAL"
+done
+`
+
+// readReplicaMiddleware rejects the writes reaching the read replicas through the main Service
+// instead of letting the next sync overwrite them. HEAD requests and the CORS preflight
+// OPTIONS requests are served like GET requests. json-server loads it with
+// --middlewares, so that the entrypoint of the image is kept and every replica gets the same
+// arguments.
+const readReplicaMiddleware = `module.exports = (req, res, next) => {
+  if (process.env.POD_NAME === process.env.WRITER_POD || ['GET', 'HEAD', 'OPTIONS'].includes(req.method)) {
+    next()
+  } else {
+    res.sendStatus(403)
+  }
+}
+`
+
+// runsAsStatefulSet reports whether the pods of the JsonServer need stable identities,
+// either to keep their volume or to elect the writer
+func runsAsStatefulSet(jsonServer *examplev1.JsonServer) bool {
+	return jsonServer.Spec.Persistence != nil || jsonServer.Spec.Consistency == examplev1.ConsistencySingleWriter
+}
+
+// writeServiceName is the name of the Service receiving the writes of a single-writer JsonServer
+func writeServiceName(jsonServer *examplev1.JsonServer) string {
+	return jsonServer.Name + "-write"
+}
+
+// writerPodName is the name of the replica accepting the writes of a single-writer JsonServer
+func writerPodName(jsonServer *examplev1.JsonServer) string {
+	return jsonServer.Name + "-0"
+}
+
+// applyConsistency adapts the pod template to the consistency mode of the JsonServer
+func applyConsistency(jsonServer *examplev1.JsonServer, template *corev1.PodTemplateSpec, configMap *corev1.ConfigMap) {
+	server := &template.Spec.Containers[0]
+
+	switch jsonServer.Spec.Consistency {
+	case examplev1.ConsistencyReadOnly:
+		// The server section may have made the JsonServer read-only already
+		if !slices.Contains(server.Args, "--read-only") {
+			server.Args = append([]string{"--read-only"}, server.Args...)
+		}
+
+	case examplev1.ConsistencySingleWriter:
+		podEnv := []corev1.EnvVar{
+			{
+				Name: "POD_NAME",
+				ValueFrom: &corev1.EnvVarSource{
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.name"},
+				},
+			},
+			{Name: "WRITER_POD", Value: writerPodName(jsonServer)},
+		}
+
+		// The writer is the only replica accepting writes, the others serve its data
+		server.Args = append([]string{"--watch", "--middlewares", serverConfigDir + "/" + readReplicaKey}, server.Args...)
+		mountServerConfig(template, configMap, readReplicaKey)
+		server.Env = append(server.Env, podEnv...)
+		template.Spec.Containers = append(template.Spec.Containers, corev1.Container{
+			Name:    "sync",
+			Image:   server.Image,
+			Command: []string{"sh", "-c", syncScript},
+			Env: append(podEnv,
+				corev1.EnvVar{Name: "WRITE_SERVICE", Value: writeServiceName(jsonServer)},
+				corev1.EnvVar{Name: "WRITE_PORT", Value: fmt.Sprint(servicePort(jsonServer))},
+				corev1.EnvVar{Name: "SYNC_INTERVAL", Value: fmt.Sprint(syncIntervalSeconds)},
+			),
+			VolumeMounts: append([]corev1.VolumeMount(nil), server.VolumeMounts...),
+		})
+	}
+}
+
+// reconcileWriteService ensures the Service selecting the writer of a single-writer JsonServer
+// exists, and removes it in the other modes. While the JsonServer is scaled to zero it selects
+// the activator, which forwards to the first ready pod, the writer.
+func (r *JsonServerReconciler) reconcileWriteService(ctx context.Context, jsonServer *examplev1.JsonServer, toActivator bool) error {
+	log := logf.FromContext(ctx)
+
+	if jsonServer.Spec.Consistency != examplev1.ConsistencySingleWriter {
+		return r.deleteOwned(ctx, jsonServer, writeServiceName(jsonServer), &corev1.Service{})
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      writeServiceName(jsonServer),
+			Namespace: jsonServer.Namespace,
+			Labels:    getResourceLabels(jsonServer),
+		},
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
+		if err := controllerutil.SetControllerReference(jsonServer, service, r.Scheme); err != nil {
+			return err
+		}
+
+		selector := getResourceLabels(jsonServer)
+		selector[appsv1.StatefulSetPodNameLabel] = writerPodName(jsonServer)
+		if toActivator {
+			selector = activatorLabels(jsonServer)
+		}
+
+		service.Spec.Selector = selector
+		service.Spec.Ports = []corev1.ServicePort{
+			{
+				Port:       servicePort(jsonServer),
+				TargetPort: intstr.FromInt32(containerPort),
+				Protocol:   corev1.ProtocolTCP,
+			},
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Error(err, "Failed to create or update write Service")
+		return err
+	}
+
+	log.Info("Write Service reconciled", "operation", op)
+	return nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+const (
+	// stepDataSecret is the reconcile step writing the data Secret
+	stepDataSecret = "DataSecret"
+	// dataSecretAnnotation names the data Secret on the ConfigMap of a JsonServer whose document
+	// is kept there
+	dataSecretAnnotation = "example.example.com/data-secret"
+)
+
+// dataSecretName is the name of the Secret holding the document of a JsonServer whose data is
+// read from a Secret. Such documents are never copied into a ConfigMap, which anyone allowed
+// to read the ConfigMaps of the namespace could read.
+func dataSecretName(jsonServer *examplev1.JsonServer) string {
+	return jsonServer.Name + "-data"
+}
+
+// reconcileDataSecret ensures the data Secret holds the document read from a Secret. Unlike
+// ConfigMaps, the document is kept in a single Secret, as is or compressed into one chunk.
+func (r *JsonServerReconciler) reconcileDataSecret(ctx context.Context, jsonServer *examplev1.JsonServer, data *resolvedData, chunks [][]byte) (*corev1.Secret, error) {
+	log := logf.FromContext(ctx)
+
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Namespace: jsonServer.Namespace, Name: dataSecretName(jsonServer)}
+	// Read without the cache, like the Secrets of spec.dataFrom, see uncachedReader
+	err := r.uncachedReader().Get(ctx, key, secret)
+	if client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Failed to get data Secret")
+		return nil, err
+	}
+	exists := err == nil
+	// Never overwrite a Secret the JsonServer did not create
+	if exists && !metav1.IsControlledBy(secret, jsonServer) {
+		return nil, fmt.Errorf("secret %q already exists and is not owned by the JsonServer", key.Name)
+	}
+
+	desired := secret.DeepCopy()
+	desired.Name, desired.Namespace = key.Name, key.Namespace
+	desired.Labels = getResourceLabels(jsonServer)
+	desired.Type = corev1.SecretTypeOpaque
+	if err := controllerutil.SetControllerReference(jsonServer, desired, r.Scheme); err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		desired.Data = map[string][]byte{"db.json": []byte(data.Data)}
+		delete(desired.Annotations, chunksAnnotation)
+	} else {
+		desired.Data = map[string][]byte{
+			chunkKey(0): chunks[0],
+			checksumKey: []byte(checksum(data.Data)),
+		}
+		metav1.SetMetaDataAnnotation(&desired.ObjectMeta, chunksAnnotation, strconv.Itoa(len(chunks)))
+	}
+
+	switch {
+	case !exists:
+		err = r.Create(ctx, desired)
+	case !equality.Semantic.DeepEqual(secret, desired):
+		err = r.Update(ctx, desired)
+	default:
+		return secret, nil
+	}
+	if err != nil {
+		log.Error(err, "Failed to create or update data Secret")
+		return nil, err
+	}
+
+	log.Info("Data Secret reconciled", "created", !exists)
+	return desired, nil
+}
+
+// deleteDataSecret removes the data Secret of a JsonServer that no longer reads its data from
+// a Secret
+func (r *JsonServerReconciler) deleteDataSecret(ctx context.Context, jsonServer *examplev1.JsonServer) error {
+	// Owned Secrets are only watched for their metadata
+	secret := &metav1.PartialObjectMetadata{}
+	secret.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+	key := client.ObjectKey{Namespace: jsonServer.Namespace, Name: dataSecretName(jsonServer)}
+	if err := r.Get(ctx, key, secret); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(secret, jsonServer) {
+		return nil
+	}
+	if err := r.Delete(ctx, secret, client.Preconditions{UID: &secret.UID}); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	logf.FromContext(ctx).Info("Data Secret deleted")
+	return nil
+}
+
+// secretDocumentVolume returns the volume the document kept in the data Secret is read from
+func secretDocumentVolume(name string, secret *corev1.Secret) corev1.Volume {
+	items := []corev1.KeyToPath{{Key: "db.json", Path: "db.json"}}
+	if chunkCount(secret) > 0 {
+		items = []corev1.KeyToPath{
+			{Key: chunkKey(0), Path: chunkKey(0)},
+			{Key: checksumKey, Path: checksumKey},
+		}
+	}
+	return corev1.Volume{
+		Name: name,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: secret.Name, Items: items},
+		},
+	}
+}
@@ -0,0 +1,459 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"syscall"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/config"
+)
+
+// Reasons used when the configuration source cannot be read
+const (
+	reasonSourceNotFound    = "SourceNotFound"
+	reasonSourceUnavailable = "SourceUnavailable"
+	reasonSourceForbidden   = "SourceForbidden"
+)
+
+// Field indexes of the JsonServers reading their data or their collections from another object
+const (
+	configMapRefIndex  = ".spec.dataFrom.configMapKeyRef.name"
+	secretRefIndex     = ".spec.dataFrom.secretKeyRef.name"
+	snapshotRefIndex   = ".spec.dataFrom.snapshotRef.name"
+	fixtureRefIndex    = ".spec.collections.fixtureRef.name"
+	jsonServerRefIndex = ".spec.collections.jsonServerRef.name"
+)
+
+const (
+	// stepDataSource is the reconcile step resolving the configuration source
+	stepDataSource = "DataSource"
+	// defaultRefreshInterval is used for HTTP sources without a refresh interval
+	defaultRefreshInterval = 5 * time.Minute
+	// maxDownloadSize bounds the documents downloaded from HTTP sources
+	maxDownloadSize = 10 << 20
+)
+
+// maxDownloadRedirects is the number of redirects followed when downloading HTTP sources
+const maxDownloadRedirects = 10
+
+// resolvedData is the JSON configuration resolved from the spec of a JsonServer
+type resolvedData struct {
+	// Data is the JSON document to serve
+	Data string
+	// Description names the source in status messages
+	Description string
+	// Source is reported in the JsonServer status
+	Source examplev1.SourceStatus
+	// RequeueAfter is set for sources that must be read again periodically
+	RequeueAfter time.Duration
+	// Author is the field manager that last wrote the data, when known
+	Author string
+	// Sensitive is set for the data read from Secrets, kept in the data Secret of the
+	// JsonServer instead of its ConfigMap and never recorded in revisions
+	Sensitive bool
+	// Composition is the hash of the inputs the last download kept in the ConfigMap was
+	// composed from, see composedAnnotation. It is empty for documents read as they are.
+	Composition string
+}
+
+// resolveData reads the JSON configuration from the source selected in the spec, or from
+// the revision selected by spec.rollbackTo
+func (r *JsonServerReconciler) resolveData(ctx context.Context, jsonServer *examplev1.JsonServer) (*resolvedData, *reconcileError) {
+	dataFrom := jsonServer.Spec.DataFrom
+
+	switch {
+	case jsonServer.Spec.RollbackTo != "":
+		return r.resolveRevision(ctx, jsonServer)
+
+	case dataFrom == nil:
+		return &resolvedData{
+			Data:        jsonServer.Spec.JsonConfig,
+			Description: "spec.jsonConfig",
+			Source: examplev1.SourceStatus{
+				Kind:     examplev1.SourceKindInline,
+				Revision: strconv.FormatInt(jsonServer.Generation, 10),
+			},
+			Author: lastManager(jsonServer.ManagedFields, "f:spec", "f:jsonConfig"),
+		}, nil
+
+	case dataFrom.ConfigMapKeyRef != nil:
+		ref := dataFrom.ConfigMapKeyRef
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, client.ObjectKey{Namespace: jsonServer.Namespace, Name: ref.Name}, configMap); err != nil {
+			return nil, sourceError(fmt.Sprintf("ConfigMap %q", ref.Name), err)
+		}
+		data, ok := configMap.Data[ref.Key]
+		if !ok {
+			return nil, missingKeyError(fmt.Sprintf("ConfigMap %q", ref.Name), ref.Key)
+		}
+		return &resolvedData{
+			Data:        data,
+			Description: fmt.Sprintf("key %q of ConfigMap %q", ref.Key, ref.Name),
+			Source: examplev1.SourceStatus{
+				Kind:         examplev1.SourceKindConfigMap,
+				Name:         ref.Name,
+				Revision:     configMap.ResourceVersion,
+				LastSyncTime: syncTime(jsonServer, examplev1.SourceKindConfigMap, ref.Name, configMap.ResourceVersion),
+			},
+			Author: lastManager(configMap.ManagedFields, "f:data", "f:"+ref.Key),
+		}, nil
+
+	case dataFrom.SecretKeyRef != nil:
+		ref := dataFrom.SecretKeyRef
+		secret := &corev1.Secret{}
+		if err := r.uncachedReader().Get(ctx, client.ObjectKey{Namespace: jsonServer.Namespace, Name: ref.Name}, secret); err != nil {
+			return nil, sourceError(fmt.Sprintf("Secret %q", ref.Name), err)
+		}
+		data, ok := secret.Data[ref.Key]
+		if !ok {
+			return nil, missingKeyError(fmt.Sprintf("Secret %q", ref.Name), ref.Key)
+		}
+		return &resolvedData{
+			Data:        string(data),
+			Description: fmt.Sprintf("key %q of Secret %q", ref.Key, ref.Name),
+			Source: examplev1.SourceStatus{
+				Kind:         examplev1.SourceKindSecret,
+				Name:         ref.Name,
+				Revision:     secret.ResourceVersion,
+				LastSyncTime: syncTime(jsonServer, examplev1.SourceKindSecret, ref.Name, secret.ResourceVersion),
+			},
+			Author:    lastManager(secret.ManagedFields, "f:data", "f:"+ref.Key),
+			Sensitive: true,
+		}, nil
+
+	case dataFrom.HTTP != nil:
+		return r.resolveHTTP(ctx, jsonServer)
+
+	case dataFrom.SnapshotRef != nil:
+		return r.resolveSnapshot(ctx, jsonServer)
+	}
+
+	return nil, &reconcileError{Step: stepDataSource, Reason: reasonSourceNotFound,
+		Err: fmt.Errorf("spec.dataFrom does not select any source")}
+}
+
+// resolveHTTP downloads the JSON configuration from the URL of the spec. The copy held
+// in the owned ConfigMap is reused until the refresh interval elapsed.
+func (r *JsonServerReconciler) resolveHTTP(ctx context.Context, jsonServer *examplev1.JsonServer) (*resolvedData, *reconcileError) {
+	source := jsonServer.Spec.DataFrom.HTTP
+	refreshInterval := httpRefreshInterval(source)
+	description := fmt.Sprintf("document at %s", source.URL)
+
+	// Reuse the last download while it is fresh
+	if last := jsonServer.Status.Source; last != nil && last.Kind == examplev1.SourceKindHTTP && last.Name == source.URL &&
+		last.LastSyncTime != nil && time.Since(last.LastSyncTime.Time) < refreshInterval {
+		configMap := &corev1.ConfigMap{}
+		err := r.Get(ctx, client.ObjectKey{Namespace: jsonServer.Namespace, Name: jsonServer.Name}, configMap)
+		if err == nil {
+			if data, ok := r.readDocument(ctx, configMap); ok {
+				return &resolvedData{
+					Data:         data,
+					Description:  description,
+					Source:       *last.DeepCopy(),
+					RequeueAfter: refreshInterval - time.Since(last.LastSyncTime.Time),
+					Composition:  configMap.Annotations[composedAnnotation],
+				}, nil
+			}
+		}
+	}
+	return r.downloadHTTP(ctx, jsonServer)
+}
+
+// downloadHTTP downloads the JSON configuration from the URL of the spec
+func (r *JsonServerReconciler) downloadHTTP(ctx context.Context, jsonServer *examplev1.JsonServer) (*resolvedData, *reconcileError) {
+	source := jsonServer.Spec.DataFrom.HTTP
+	refreshInterval := httpRefreshInterval(source)
+	description := fmt.Sprintf("document at %s", source.URL)
+
+	unavailable := func(err error) *reconcileError {
+		return &reconcileError{Step: stepDataSource, Reason: reasonSourceUnavailable, Transient: true,
+			Err: fmt.Errorf("failed to download %s: %w", source.URL, err)}
+	}
+
+	// The webhook checks the URL too, the operator configuration may have changed since
+	if _, err := r.downloadConfig().CheckURL(source.URL); err != nil {
+		return nil, &reconcileError{Step: stepDataSource, Reason: reasonSourceForbidden, RetryAfter: refreshInterval,
+			Err: fmt.Errorf("%s cannot be downloaded: %w", source.URL, err)}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL, nil)
+	if err != nil {
+		return nil, &reconcileError{Step: stepDataSource, Reason: reasonSourceNotFound, Err: err}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.downloadClient().Do(req)
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, unavailable(fmt.Errorf("unexpected status %s", resp.Status))
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, unavailable(err)
+	}
+	if len(body) > maxDownloadSize {
+		return nil, unavailable(fmt.Errorf("document is larger than %d bytes", maxDownloadSize))
+	}
+
+	revision := resp.Header.Get("ETag")
+	if revision == "" {
+		sum := sha256.Sum256(body)
+		revision = hex.EncodeToString(sum[:])[:16]
+	}
+	now := metav1.Now()
+	return &resolvedData{
+		Data:        string(body),
+		Description: description,
+		Source: examplev1.SourceStatus{
+			Kind:         examplev1.SourceKindHTTP,
+			Name:         source.URL,
+			Revision:     revision,
+			LastSyncTime: &now,
+		},
+		RequeueAfter: refreshInterval,
+	}, nil
+}
+
+// downloadConfig returns the operator configuration restricting the URLs of HTTP sources
+func (r *JsonServerReconciler) downloadConfig() *config.Config {
+	return &config.Config{AllowedURLHosts: r.AllowedURLHosts}
+}
+
+// downloadClient returns the client downloading the documents of HTTP sources. Unless the
+// reconciler has a client configured, it follows the redirects to the URLs allowed by
+// config.CheckURL only, and connects to the addresses allowed by config.CheckAddress only,
+// checked once the host is resolved so that a host cannot resolve to another address later.
+func (r *JsonServerReconciler) downloadClient() *http.Client {
+	if r.HTTPClient != nil {
+		return r.HTTPClient
+	}
+
+	downloadConfig := r.downloadConfig()
+	dialContext := func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		hostAllowed := downloadConfig.HostAllowed(host)
+		dialer := &net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(_, address string, _ syscall.RawConn) error {
+				resolved, err := netip.ParseAddrPort(address)
+				if err != nil {
+					return err
+				}
+				return config.CheckAddress(resolved.Addr(), hostAllowed)
+			},
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		// No proxy, the addresses checked are the ones of the hosts of the URLs
+		Transport: &http.Transport{
+			DialContext:         dialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			DisableKeepAlives:   true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxDownloadRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxDownloadRedirects)
+			}
+			_, err := downloadConfig.CheckURL(req.URL.String())
+			return err
+		},
+	}
+}
+
+// httpRefreshInterval returns how often the document of an HTTP source is downloaded again
+func httpRefreshInterval(source *examplev1.HTTPSource) time.Duration {
+	if source.RefreshInterval != nil && source.RefreshInterval.Duration > 0 {
+		return source.RefreshInterval.Duration
+	}
+	return defaultRefreshInterval
+}
+
+// resolveSnapshot reads the data captured by the JsonServerSnapshot of the spec
+func (r *JsonServerReconciler) resolveSnapshot(ctx context.Context, jsonServer *examplev1.JsonServer) (*resolvedData, *reconcileError) {
+	name := jsonServer.Spec.DataFrom.SnapshotRef.Name
+	description := fmt.Sprintf("JsonServerSnapshot %q", name)
+
+	snapshot := &examplev1.JsonServerSnapshot{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: jsonServer.Namespace, Name: name}, snapshot); err != nil {
+		return nil, sourceError(description, err)
+	}
+	if snapshot.Status.Phase != examplev1.SnapshotCompleted || snapshot.Status.DataRef == nil {
+		// Snapshots are watched, the completion of the capture triggers a new reconcile
+		return nil, &reconcileError{Step: stepDataSource, Reason: reasonSourceNotFound,
+			Err: fmt.Errorf("%s is not completed: %s", description, snapshot.Status.Message)}
+	}
+
+	var data string
+	var ok bool
+	sensitive := false
+	ref := snapshot.Status.DataRef
+	key := client.ObjectKey{Namespace: jsonServer.Namespace, Name: ref.Name}
+	switch ref.Kind {
+	case examplev1.SnapshotStorageSecret:
+		secret := &corev1.Secret{}
+		if err := r.uncachedReader().Get(ctx, key, secret); err != nil {
+			return nil, sourceError(fmt.Sprintf("Secret %q of %s", ref.Name, description), err)
+		}
+		data, ok = readSecretDocument(secret)
+		sensitive = true
+	default:
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, key, configMap); err != nil {
+			return nil, sourceError(fmt.Sprintf("ConfigMap %q of %s", ref.Name, description), err)
+		}
+		// Compressed and split across several ConfigMaps like the data of the JsonServers
+		data, ok = r.readDocument(ctx, configMap)
+	}
+	if !ok {
+		return nil, &reconcileError{Step: stepDataSource, Reason: reasonSourceNotFound,
+			Err: fmt.Errorf("the data of %s is missing or does not match its checksum", description)}
+	}
+
+	return &resolvedData{
+		Data:        data,
+		Description: description,
+		Source: examplev1.SourceStatus{
+			Kind:         examplev1.SourceKindSnapshot,
+			Name:         name,
+			Revision:     snapshot.Status.Checksum,
+			LastSyncTime: syncTime(jsonServer, examplev1.SourceKindSnapshot, name, snapshot.Status.Checksum),
+		},
+		Author:    lastManager(snapshot.ManagedFields, "f:spec", "f:jsonServerName"),
+		Sensitive: sensitive,
+	}, nil
+}
+
+// syncTime returns the time the revision of a source was first read. It is kept while the
+// revision does not change so that reading the same revision does not rewrite the status.
+func syncTime(jsonServer *examplev1.JsonServer, kind, name, revision string) *metav1.Time {
+	if last := jsonServer.Status.Source; last != nil && last.LastSyncTime != nil &&
+		last.Kind == kind && last.Name == name && last.Revision == revision {
+		return last.LastSyncTime.DeepCopy()
+	}
+	now := metav1.Now()
+	return &now
+}
+
+// uncachedReader returns the reader used for Secrets and Pods. Secrets are only watched for
+// their metadata and Pods are not watched, so that the manager does not cache every Secret
+// and Pod of the cluster.
+func (r *JsonServerReconciler) uncachedReader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
+
+// sourceError classifies an error reading a referenced ConfigMap or Secret
+func sourceError(description string, err error) *reconcileError {
+	if apierrors.IsNotFound(err) {
+		// The referenced object is watched, its creation triggers a new reconcile
+		return &reconcileError{Step: stepDataSource, Reason: reasonSourceNotFound,
+			Err: fmt.Errorf("%s not found", description)}
+	}
+	return classifyError(stepDataSource, fmt.Errorf("failed to read %s: %w", description, err))
+}
+
+// missingKeyError reports a key missing from a referenced ConfigMap or Secret
+func missingKeyError(description, key string) *reconcileError {
+	return &reconcileError{Step: stepDataSource, Reason: reasonSourceNotFound,
+		Err: fmt.Errorf("%s has no key %q", description, key)}
+}
+
+// indexDataSourceRefs indexes the JsonServers by the ConfigMaps, Secrets and snapshots their
+// data is read from, and by the ConfigMaps, JsonFixtures and JsonServers their collections are
+// read from
+func indexDataSourceRefs(index string) client.IndexerFunc {
+	return func(obj client.Object) []string {
+		jsonServer, ok := obj.(*examplev1.JsonServer)
+		if !ok {
+			return nil
+		}
+		var names []string
+		if dataFrom := jsonServer.Spec.DataFrom; dataFrom != nil {
+			switch {
+			case index == configMapRefIndex && dataFrom.ConfigMapKeyRef != nil:
+				names = append(names, dataFrom.ConfigMapKeyRef.Name)
+			case index == secretRefIndex && dataFrom.SecretKeyRef != nil:
+				names = append(names, dataFrom.SecretKeyRef.Name)
+			case index == snapshotRefIndex && dataFrom.SnapshotRef != nil:
+				names = append(names, dataFrom.SnapshotRef.Name)
+			}
+		}
+		for _, collection := range jsonServer.Spec.Collections {
+			switch {
+			case index == configMapRefIndex && collection.ConfigMapKeyRef != nil:
+				names = append(names, collection.ConfigMapKeyRef.Name)
+			case index == fixtureRefIndex && collection.FixtureRef != nil:
+				names = append(names, collection.FixtureRef.Name)
+			case index == jsonServerRefIndex && collection.JsonServerRef != nil:
+				names = append(names, collection.JsonServerRef.Name)
+			}
+		}
+		slices.Sort(names)
+		return slices.Compact(names)
+	}
+}
+
+// jsonServersReferencing maps a ConfigMap, Secret, JsonServerSnapshot or JsonFixture to the
+// JsonServers reading their data or their collections from it
+func (r *JsonServerReconciler) jsonServersReferencing(indexes ...string) func(context.Context, client.Object) []reconcile.Request {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		var requests []reconcile.Request
+		for _, index := range indexes {
+			jsonServers := &examplev1.JsonServerList{}
+			if err := r.List(ctx, jsonServers, client.InNamespace(obj.GetNamespace()),
+				client.MatchingFields{index: obj.GetName()}); err != nil {
+				return nil
+			}
+			for _, jsonServer := range jsonServers.Items {
+				request := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&jsonServer)}
+				if !slices.Contains(requests, request) {
+					requests = append(requests, request)
+				}
+			}
+		}
+		return requests
+	}
+}
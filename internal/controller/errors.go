@@ -0,0 +1,141 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// Reasons used for classified reconcile errors
+const (
+	reasonOwnershipConflict  = "OwnershipConflict"
+	reasonQuotaExceeded      = "QuotaExceeded"
+	reasonForbidden          = "Forbidden"
+	reasonAPIConflict        = "APIConflict"
+	reasonInvalidPodTemplate = "InvalidPodTemplate"
+	reasonInvalidResource    = "InvalidResource"
+	reasonResourceTooLarge   = "ResourceTooLarge"
+	reasonAPIUnavailable     = "APIUnavailable"
+	reasonAPINotInstalled    = "APINotInstalled"
+)
+
+// Reconcile steps reported in reconcile errors
+const (
+	stepConfigMap  = "ConfigMap"
+	stepDeployment = "Deployment"
+	stepService    = "Service"
+)
+
+// Delays before retrying errors that backing off would not fix any sooner
+const (
+	quotaRetryDelay      = time.Minute
+	forbiddenRetryDelay  = 5 * time.Minute
+	conflictRetryDelay   = 5 * time.Minute
+	missingAPIRetryDelay = 5 * time.Minute
+)
+
+// reconcileError is a failure of one reconcile step, classified by its cause so
+// that it can be surfaced as a condition reason and an Event and retried accordingly
+type reconcileError struct {
+	// Step is the reconcile step that failed, e.g. "Deployment"
+	Step string
+	// Reason is the CamelCase reason used for the condition and the Event
+	Reason string
+	// Transient errors are retried with the controller's exponential backoff
+	Transient bool
+	// RetryAfter requeues non-transient errors after a fixed delay. Zero waits
+	// for the next change of the JsonServer or one of its owned objects.
+	RetryAfter time.Duration
+	// Err is the underlying error
+	Err error
+}
+
+func (e *reconcileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Step, e.Err)
+}
+
+func (e *reconcileError) Unwrap() error {
+	return e.Err
+}
+
+// result returns the result and error Reconcile should hand back to the
+// controller runtime for the error class
+func (e *reconcileError) result() (ctrl.Result, error) {
+	switch {
+	case e.Reason == reasonAPIConflict:
+		// Stale cache, the next attempt with a fresh read will most likely succeed
+		return ctrl.Result{Requeue: true}, nil
+	case e.Transient:
+		return ctrl.Result{}, e
+	default:
+		return ctrl.Result{RequeueAfter: e.RetryAfter}, nil
+	}
+}
+
+// classifyError maps an error returned while reconciling a step to a reconcileError
+func classifyError(step string, err error) *reconcileError {
+	var rerr *reconcileError
+	if errors.As(err, &rerr) {
+		return rerr
+	}
+
+	rerr = &reconcileError{Step: step, Reason: reasonReconcileFailed, Transient: true, Err: err}
+
+	var alreadyOwned *controllerutil.AlreadyOwnedError
+	switch {
+	case errors.As(err, &alreadyOwned):
+		rerr.Reason = reasonOwnershipConflict
+		rerr.Transient = false
+		rerr.RetryAfter = conflictRetryDelay
+	case apierrors.IsForbidden(err) && strings.Contains(err.Error(), "exceeded quota"):
+		rerr.Reason = reasonQuotaExceeded
+		rerr.Transient = false
+		rerr.RetryAfter = quotaRetryDelay
+	case apierrors.IsForbidden(err):
+		rerr.Reason = reasonForbidden
+		rerr.Transient = false
+		rerr.RetryAfter = forbiddenRetryDelay
+	case meta.IsNoMatchError(err):
+		rerr.Reason = reasonAPINotInstalled
+		rerr.Transient = false
+		rerr.RetryAfter = missingAPIRetryDelay
+	case apierrors.IsConflict(err), apierrors.IsAlreadyExists(err):
+		rerr.Reason = reasonAPIConflict
+	case apierrors.IsInvalid(err) && (step == stepDeployment || step == stepStatefulSet):
+		rerr.Reason = reasonInvalidPodTemplate
+		rerr.Transient = false
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		rerr.Reason = reasonInvalidResource
+		rerr.Transient = false
+	case apierrors.IsRequestEntityTooLargeError(err):
+		rerr.Reason = reasonResourceTooLarge
+		rerr.Transient = false
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err), apierrors.IsTooManyRequests(err),
+		apierrors.IsServiceUnavailable(err), apierrors.IsInternalError(err):
+		rerr.Reason = reasonAPIUnavailable
+	}
+
+	return rerr
+}
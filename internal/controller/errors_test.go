@@ -0,0 +1,88 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+var _ = Describe("Reconcile errors", func() {
+	configMaps := schema.GroupResource{Resource: "configmaps"}
+	deployments := schema.GroupKind{Group: "apps", Kind: "Deployment"}
+
+	DescribeTable("classifying errors",
+		func(step string, err error, reason string, transient bool) {
+			rerr := classifyError(step, err)
+			Expect(rerr.Reason).To(Equal(reason))
+			Expect(rerr.Transient).To(Equal(transient))
+			Expect(errors.Is(rerr, err)).To(BeTrue())
+
+			result, returned := rerr.result()
+			switch {
+			case reason == reasonAPIConflict:
+				Expect(returned).NotTo(HaveOccurred())
+				Expect(result.Requeue).To(BeTrue())
+			case transient:
+				Expect(returned).To(MatchError(rerr))
+			default:
+				Expect(returned).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(rerr.RetryAfter))
+			}
+		},
+		Entry("ownership conflict", stepConfigMap,
+			&controllerutil.AlreadyOwnedError{Object: &corev1.ConfigMap{}, Owner: metav1.OwnerReference{Kind: "Other"}},
+			reasonOwnershipConflict, false),
+		Entry("quota exceeded", stepDeployment,
+			apierrors.NewForbidden(configMaps, "app-x", fmt.Errorf("exceeded quota: compute-resources")),
+			reasonQuotaExceeded, false),
+		Entry("forbidden", stepService,
+			apierrors.NewForbidden(configMaps, "app-x", fmt.Errorf("no RBAC policy matched")),
+			reasonForbidden, false),
+		Entry("API not installed", stepExpose,
+			&meta.NoKindMatchError{GroupKind: schema.GroupKind{Group: "gateway.networking.k8s.io", Kind: "HTTPRoute"}},
+			reasonAPINotInstalled, false),
+		Entry("API conflict", stepConfigMap,
+			apierrors.NewConflict(configMaps, "app-x", fmt.Errorf("the object has been modified")),
+			reasonAPIConflict, true),
+		Entry("invalid pod template", stepDeployment,
+			apierrors.NewInvalid(deployments, "app-x", field.ErrorList{field.Required(field.NewPath("spec", "template"), "")}),
+			reasonInvalidPodTemplate, false),
+		Entry("invalid resource", stepService,
+			apierrors.NewInvalid(deployments, "app-x", field.ErrorList{field.Required(field.NewPath("spec", "ports"), "")}),
+			reasonInvalidResource, false),
+		Entry("too large", stepConfigMap,
+			apierrors.NewRequestEntityTooLargeError("limit is 1048576"),
+			reasonResourceTooLarge, false),
+		Entry("API unavailable", stepService,
+			apierrors.NewServiceUnavailable("etcd leader changed"),
+			reasonAPIUnavailable, true),
+		Entry("unknown", stepService,
+			fmt.Errorf("connection reset by peer"),
+			reasonReconcileFailed, true),
+	)
+})
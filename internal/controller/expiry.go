@@ -0,0 +1,300 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+const (
+	// stepExpiry is the reconcile step deleting the JsonServers past their TTL
+	stepExpiry = "Expiry"
+	// expiredFromLabel holds the name of the JsonServer a snapshot was captured from at expiry
+	expiredFromLabel = "example.example.com/expired-from"
+	// expirySnapshotPollInterval is how often the snapshot taken at expiry is checked
+	expirySnapshotPollInterval = 5 * time.Second
+	// expirySnapshotTimeout is how long the deletion waits for the snapshot taken at expiry
+	expirySnapshotTimeout = 5 * time.Minute
+	// maxRequestLogLines is the number of log lines of each pod searched for requests
+	maxRequestLogLines = 1000
+)
+
+var (
+	// requestLogPattern matches the requests logged by json-server, e.g. "GET /people 200 3.456 ms - 45"
+	requestLogPattern = regexp.MustCompile(`^(GET|HEAD|POST|PUT|PATCH|DELETE|OPTIONS) /`)
+	// colorPattern matches the ANSI colors of the json-server logs
+	colorPattern = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+// reconcileExpiry deletes the JsonServer once it outlived one of its TTLs, capturing its data
+// first when the spec asks for it. It records the expiration time in the status and returns
+// the delay until the next expiry check, zero without TTL, and whether the JsonServer was deleted.
+func (r *JsonServerReconciler) reconcileExpiry(ctx context.Context, jsonServer *examplev1.JsonServer) (time.Duration, bool, error) {
+	log := logf.FromContext(ctx)
+	now := time.Now()
+
+	afterCreation, err := r.ttlAfterCreation(ctx, jsonServer)
+	if err != nil {
+		return 0, false, err
+	}
+	afterLastRequest := jsonServer.Spec.TTLSecondsAfterLastRequest
+
+	var expiration *time.Time
+	if afterCreation != nil {
+		expiration = ptr.To(jsonServer.CreationTimestamp.Add(*afterCreation))
+	}
+	if afterLastRequest != nil {
+		idleExpiration := r.idleExpiration(jsonServer, *afterLastRequest)
+		// The logs are only read when the JsonServer looks idle, a request since the last
+		// check pushes the expiration back
+		if !idleExpiration.After(now) && (expiration == nil || idleExpiration.Before(*expiration)) {
+			if err := r.observeRequests(ctx, jsonServer); err != nil {
+				return 0, false, err
+			}
+			idleExpiration = r.idleExpiration(jsonServer, *afterLastRequest)
+		}
+		if expiration == nil || idleExpiration.Before(*expiration) {
+			expiration = &idleExpiration
+		}
+	}
+
+	if expiration == nil {
+		jsonServer.Status.ExpirationTime = nil
+		return 0, false, nil
+	}
+	jsonServer.Status.ExpirationTime = ptr.To(metav1.NewTime(*expiration).Rfc3339Copy())
+	if expiration.After(now) {
+		return expiration.Sub(now), false, nil
+	}
+
+	if jsonServer.Spec.SnapshotOnExpiry {
+		// The data is read from a running pod, idle JsonServers are scaled back up until it is
+		// captured, see reconcileIdle
+		if jsonServer.Status.IdleSince != nil {
+			jsonServer.Status.IdleSince = nil
+			r.Recorder.Event(jsonServer, corev1.EventTypeNormal, "Activated",
+				fmt.Sprintf("JsonServer expired, scaling back to %d replicas to capture its data", desiredReplicas(jsonServer, nil)))
+		}
+		captured, err := r.snapshotOnExpiry(ctx, jsonServer)
+		if err != nil {
+			return 0, false, err
+		}
+		if !captured {
+			return expirySnapshotPollInterval, false, nil
+		}
+	}
+
+	if err := r.Delete(ctx, jsonServer, client.Preconditions{UID: &jsonServer.UID}); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Failed to delete expired JsonServer")
+		return 0, false, err
+	}
+	r.Recorder.Event(jsonServer, corev1.EventTypeNormal, "Expired",
+		fmt.Sprintf("JsonServer expired at %s and was deleted", expiration.UTC().Format(time.RFC3339)))
+	log.Info("Expired JsonServer deleted", "expirationTime", expiration)
+	return 0, true, nil
+}
+
+// ttlAfterCreation returns the TTL after creation of the spec or, in ephemeral namespaces,
+// of the operator configuration. Nil when the JsonServer does not expire after its creation.
+func (r *JsonServerReconciler) ttlAfterCreation(ctx context.Context, jsonServer *examplev1.JsonServer) (*time.Duration, error) {
+	if ttl := jsonServer.Spec.TTLSecondsAfterCreation; ttl != nil {
+		return ptr.To(time.Duration(*ttl) * time.Second), nil
+	}
+	if r.EphemeralTTL <= 0 {
+		return nil, nil
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: jsonServer.Namespace}, namespace); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to get namespace")
+		return nil, err
+	}
+	if namespace.Labels[examplev1.EphemeralNamespaceLabel] != "true" {
+		return nil, nil
+	}
+	return ptr.To(r.EphemeralTTL), nil
+}
+
+// idleExpiration returns when the JsonServer expires if its pods serve no more request
+func (r *JsonServerReconciler) idleExpiration(jsonServer *examplev1.JsonServer, ttlSeconds int32) time.Time {
+	return lastActivity(jsonServer).Add(time.Duration(ttlSeconds) * time.Second)
+}
+
+// observeRequests records in the status the time of the last request logged by the running
+// json-server pods. Requests are not observed when the reconciler cannot read pod logs.
+func (r *JsonServerReconciler) observeRequests(ctx context.Context, jsonServer *examplev1.JsonServer) error {
+	log := logf.FromContext(ctx)
+	if r.PodLogs == nil {
+		return nil
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.uncachedReader().List(ctx, pods, client.InNamespace(jsonServer.Namespace),
+		client.MatchingLabels(getResourceLabels(jsonServer))); err != nil {
+		log.Error(err, "Failed to list json-server pods")
+		return err
+	}
+
+	since := jsonServer.Status.LastRequestTime
+	latest := since
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		opts := &corev1.PodLogOptions{
+			Container:  "json-server",
+			Timestamps: true,
+			SinceTime:  since,
+			TailLines:  ptr.To(int64(maxRequestLogLines)),
+		}
+		logs, err := r.PodLogs.Pods(pod.Namespace).GetLogs(pod.Name, opts).Stream(ctx)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			log.Error(err, "Failed to read json-server logs", "pod", pod.Name)
+			return err
+		}
+		last, found := lastRequestTime(logs)
+		_ = logs.Close()
+		if found && (latest == nil || last.After(latest.Time)) {
+			latest = ptr.To(metav1.NewTime(last).Rfc3339Copy())
+		}
+	}
+
+	jsonServer.Status.LastRequestTime = latest
+	return nil
+}
+
+// lastRequestTime returns the time of the last request of the logs of a json-server container,
+// read with timestamps
+func lastRequestTime(logs io.Reader) (time.Time, bool) {
+	var last time.Time
+	found := false
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		timestamp, line, ok := strings.Cut(scanner.Text(), " ")
+		if !ok || !requestLogPattern.MatchString(colorPattern.ReplaceAllString(line, "")) {
+			continue
+		}
+		logged, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			continue
+		}
+		last, found = logged, true
+	}
+	return last, found
+}
+
+// snapshotOnExpiry captures the data of an expired JsonServer before it is deleted. It
+// reports whether the JsonServer can be deleted, i.e. the snapshot is done or timed out.
+func (r *JsonServerReconciler) snapshotOnExpiry(ctx context.Context, jsonServer *examplev1.JsonServer) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	// Not owned by the JsonServer, the snapshot outlives it
+	snapshot := &examplev1.JsonServerSnapshot{}
+	key := client.ObjectKey{Namespace: jsonServer.Namespace, Name: expirySnapshotName(jsonServer)}
+	err := r.Get(ctx, key, snapshot)
+	if apierrors.IsNotFound(err) {
+		snapshot = &examplev1.JsonServerSnapshot{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      key.Name,
+				Namespace: key.Namespace,
+				Labels:    map[string]string{expiredFromLabel: jsonServer.Name},
+			},
+			Spec: examplev1.JsonServerSnapshotSpec{JsonServerName: jsonServer.Name},
+		}
+		// The data read from Secrets is captured in a Secret too
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(ctx, client.ObjectKeyFromObject(jsonServer), configMap); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Failed to get ConfigMap")
+			return false, err
+		}
+		if _, ok := configMap.Annotations[dataSecretAnnotation]; ok {
+			snapshot.Spec.Storage = examplev1.SnapshotStorageSecret
+		}
+		if err := r.Create(ctx, snapshot); err != nil {
+			log.Error(err, "Failed to create JsonServerSnapshot")
+			return false, err
+		}
+		r.Recorder.Event(jsonServer, corev1.EventTypeNormal, "Expiring",
+			fmt.Sprintf("JsonServer expired, capturing its data in JsonServerSnapshot %s before deleting it", snapshot.Name))
+		return false, nil
+	}
+	if err != nil {
+		log.Error(err, "Failed to get JsonServerSnapshot")
+		return false, err
+	}
+
+	switch {
+	case snapshot.Status.Phase == examplev1.SnapshotCompleted:
+		return true, nil
+	case snapshot.Status.Phase == examplev1.SnapshotFailed:
+		r.Recorder.Event(jsonServer, corev1.EventTypeWarning, "SnapshotFailed",
+			fmt.Sprintf("JsonServerSnapshot %s failed, deleting the JsonServer anyway: %s", snapshot.Name, snapshot.Status.Message))
+		return true, nil
+	case time.Since(snapshot.CreationTimestamp.Time) > expirySnapshotTimeout:
+		r.Recorder.Event(jsonServer, corev1.EventTypeWarning, "SnapshotFailed",
+			fmt.Sprintf("JsonServerSnapshot %s not completed after %s, deleting the JsonServer anyway", snapshot.Name, expirySnapshotTimeout))
+		return true, nil
+	}
+	return false, nil
+}
+
+// capturingOnExpiry reports whether the JsonServer expired and waits for its data to be
+// captured before it is deleted
+func capturingOnExpiry(jsonServer *examplev1.JsonServer) bool {
+	expiration := jsonServer.Status.ExpirationTime
+	return jsonServer.Spec.SnapshotOnExpiry && expiration != nil && !expiration.After(time.Now())
+}
+
+// expirySnapshotName is the name of the snapshot taken when the JsonServer expires. The UID
+// tells apart the JsonServers of the same name, e.g. created again for each pipeline run.
+func expirySnapshotName(jsonServer *examplev1.JsonServer) string {
+	uid := string(jsonServer.UID)
+	if len(uid) > 8 {
+		uid = uid[:8]
+	}
+	return fmt.Sprintf("%s-expired-%s", jsonServer.Name, uid)
+}
+
+// soonest returns the shortest of the positive delays, zero when none is positive
+func soonest(delays ...time.Duration) time.Duration {
+	var shortest time.Duration
+	for _, delay := range delays {
+		if delay > 0 && (shortest == 0 || delay < shortest) {
+			shortest = delay
+		}
+	}
+	return shortest
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Expiry", func() {
+	DescribeTable("finding the last request in the json-server logs",
+		func(logs string, expected string) {
+			last, found := lastRequestTime(strings.NewReader(logs))
+			if expected == "" {
+				Expect(found).To(BeFalse())
+				return
+			}
+			Expect(found).To(BeTrue())
+			Expect(last).To(BeTemporally("==", time.Date(2025, 1, 1, 10, 0, 2, 500000000, time.UTC)))
+		},
+		Entry("without request", "2025-01-01T10:00:00Z   \\{^_^}/ hi!\n2025-01-01T10:00:01Z   Loading /data/db.json\n", ""),
+		Entry("with requests",
+			"2025-01-01T10:00:00.1Z GET /people 200 3.456 ms - 45\n2025-01-01T10:00:02.5Z POST /people 201 1.2 ms - 30\n",
+			"2025-01-01T10:00:02.5Z"),
+		Entry("with colored requests",
+			"2025-01-01T10:00:02.5Z \x1b[0mDELETE /people/1 \x1b[32m200\x1b[0m 1.2 ms - 2\x1b[0m\n2025-01-01T10:00:03Z   Done\n",
+			"2025-01-01T10:00:02.5Z"),
+	)
+
+	DescribeTable("picking the soonest delay",
+		func(delays []time.Duration, expected time.Duration) {
+			Expect(soonest(delays...)).To(Equal(expected))
+		},
+		Entry("without delay", []time.Duration{0, 0}, time.Duration(0)),
+		Entry("with delays", []time.Duration{0, time.Minute, time.Second}, time.Second),
+	)
+})
@@ -0,0 +1,291 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+// stepExpose is the reconcile step of the Ingress or HTTPRoute exposing the JsonServer
+const stepExpose = "Expose"
+
+// cert-manager annotations requesting the certificate of an Ingress
+const (
+	certManagerIssuerAnnotation        = "cert-manager.io/issuer"
+	certManagerClusterIssuerAnnotation = "cert-manager.io/cluster-issuer"
+)
+
+// httpRouteGVK is the Gateway API HTTPRoute. It is handled as an unstructured object so
+// that the operator does not depend on the Gateway API being installed.
+var httpRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+
+// newHTTPRoute returns an empty unstructured HTTPRoute
+func newHTTPRoute() *unstructured.Unstructured {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(httpRouteGVK)
+	return route
+}
+
+// reconcileExpose ensures the Ingress or HTTPRoute selected in the expose section exists,
+// removes the other one and reports the URL the JsonServer is reachable at
+func (r *JsonServerReconciler) reconcileExpose(ctx context.Context, jsonServer *examplev1.JsonServer) error {
+	expose := jsonServer.Spec.Expose
+	exposeType := ""
+	if expose != nil {
+		exposeType = expose.Type
+		if exposeType == "" {
+			exposeType = examplev1.ExposeIngress
+		}
+	}
+
+	if exposeType != examplev1.ExposeIngress {
+		if err := r.deleteOwned(ctx, jsonServer, jsonServer.Name, &networkingv1.Ingress{}); err != nil {
+			return err
+		}
+	}
+	if exposeType != examplev1.ExposeHTTPRoute {
+		// Without the Gateway API there is no HTTPRoute to remove
+		if err := r.deleteOwned(ctx, jsonServer, jsonServer.Name, newHTTPRoute()); err != nil && !meta.IsNoMatchError(err) {
+			return err
+		}
+	}
+
+	switch exposeType {
+	case examplev1.ExposeIngress:
+		if err := r.reconcileIngress(ctx, jsonServer); err != nil {
+			return err
+		}
+	case examplev1.ExposeHTTPRoute:
+		if err := r.reconcileHTTPRoute(ctx, jsonServer); err != nil {
+			return err
+		}
+	default:
+		jsonServer.Status.URL = ""
+		return nil
+	}
+
+	scheme := "http"
+	if expose.TLS != nil {
+		scheme = "https"
+	}
+	jsonServer.Status.URL = fmt.Sprintf("%s://%s%s", scheme, expose.Host, exposePath(expose))
+	return nil
+}
+
+// exposePath returns the path prefix the JsonServer is exposed at
+func exposePath(expose *examplev1.ExposeSpec) string {
+	if expose.Path == "" {
+		return "/"
+	}
+	return expose.Path
+}
+
+// reconcileIngress ensures the Ingress routing the host and path to the Service exists
+func (r *JsonServerReconciler) reconcileIngress(ctx context.Context, jsonServer *examplev1.JsonServer) error {
+	log := logf.FromContext(ctx)
+	expose := jsonServer.Spec.Expose
+
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jsonServer.Name,
+			Namespace: jsonServer.Namespace,
+			Labels:    getResourceLabels(jsonServer),
+		},
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, ingress, func() error {
+		if err := controllerutil.SetControllerReference(jsonServer, ingress, r.Scheme); err != nil {
+			return err
+		}
+
+		// Only the cert-manager annotations are managed, other annotations are left to the user
+		delete(ingress.Annotations, certManagerIssuerAnnotation)
+		delete(ingress.Annotations, certManagerClusterIssuerAnnotation)
+		ingress.Spec.TLS = nil
+		if tls := expose.TLS; tls != nil {
+			secretName := tls.SecretName
+			if secretName == "" {
+				secretName = jsonServer.Name + "-tls"
+			}
+			ingress.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{expose.Host}, SecretName: secretName}}
+
+			switch {
+			case tls.Issuer != "":
+				metav1.SetMetaDataAnnotation(&ingress.ObjectMeta, certManagerIssuerAnnotation, tls.Issuer)
+			case tls.ClusterIssuer != "":
+				metav1.SetMetaDataAnnotation(&ingress.ObjectMeta, certManagerClusterIssuerAnnotation, tls.ClusterIssuer)
+			}
+		}
+
+		ingress.Spec.IngressClassName = expose.IngressClassName
+		ingress.Spec.Rules = []networkingv1.IngressRule{
+			{
+				Host: expose.Host,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{
+							{
+								Path:     exposePath(expose),
+								PathType: ptr.To(networkingv1.PathTypePrefix),
+								Backend: networkingv1.IngressBackend{
+									Service: &networkingv1.IngressServiceBackend{
+										Name: jsonServer.Name,
+										Port: networkingv1.ServiceBackendPort{Number: servicePort(jsonServer)},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Error(err, "Failed to create or update Ingress")
+		return err
+	}
+
+	log.Info("Ingress reconciled", "operation", op)
+	return nil
+}
+
+// reconcileHTTPRoute ensures the HTTPRoute attaching the Service to the parent Gateways exists.
+// Only the parent references, host names and rules are managed, and they are left as they are
+// while they hold the desired fields, completed with the defaults of the API server.
+func (r *JsonServerReconciler) reconcileHTTPRoute(ctx context.Context, jsonServer *examplev1.JsonServer) error {
+	log := logf.FromContext(ctx)
+	expose := jsonServer.Spec.Expose
+
+	route := newHTTPRoute()
+	route.SetName(jsonServer.Name)
+	route.SetNamespace(jsonServer.Namespace)
+	route.SetLabels(getResourceLabels(jsonServer))
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, route, func() error {
+		if err := controllerutil.SetControllerReference(jsonServer, route, r.Scheme); err != nil {
+			return err
+		}
+		// The labels of other controllers are kept
+		route.SetLabels(mergeMaps(route.GetLabels(), getResourceLabels(jsonServer)))
+
+		parentRefs := make([]interface{}, 0, len(expose.ParentRefs))
+		for _, ref := range expose.ParentRefs {
+			parentRef := map[string]interface{}{"name": ref.Name}
+			if ref.Namespace != nil {
+				parentRef["namespace"] = *ref.Namespace
+			}
+			if ref.SectionName != nil {
+				parentRef["sectionName"] = *ref.SectionName
+			}
+			parentRefs = append(parentRefs, parentRef)
+		}
+
+		desired := map[string]interface{}{
+			"parentRefs": parentRefs,
+			"hostnames":  []interface{}{expose.Host},
+			"rules": []interface{}{
+				map[string]interface{}{
+					"matches": []interface{}{
+						map[string]interface{}{
+							"path": map[string]interface{}{
+								"type":  "PathPrefix",
+								"value": exposePath(expose),
+							},
+						},
+					},
+					"backendRefs": []interface{}{
+						map[string]interface{}{
+							"name": jsonServer.Name,
+							"port": int64(servicePort(jsonServer)),
+						},
+					},
+				},
+			},
+		}
+		for field, value := range desired {
+			// Rewriting the defaulted fields away would update the HTTPRoute on every reconcile
+			if live, found, _ := unstructured.NestedFieldNoCopy(route.Object, "spec", field); found && containsFields(live, value) {
+				continue
+			}
+			if err := unstructured.SetNestedField(route.Object, value, "spec", field); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		log.Error(err, "Failed to create or update HTTPRoute")
+		return err
+	}
+
+	log.Info("HTTPRoute reconciled", "operation", op)
+	return nil
+}
+
+// containsFields reports whether the live value of an unstructured object holds the desired
+// value: the same scalars, lists of the same length and objects with at least the desired
+// fields
+func containsFields(live, desired interface{}) bool {
+	switch desired := desired.(type) {
+	case map[string]interface{}:
+		liveMap, ok := live.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for key, value := range desired {
+			if liveValue, found := liveMap[key]; !found || !containsFields(liveValue, value) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		liveSlice, ok := live.([]interface{})
+		if !ok || len(liveSlice) != len(desired) {
+			return false
+		}
+		for i := range desired {
+			if !containsFields(liveSlice[i], desired[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return live == desired
+	}
+}
+
+// gatewayAPIInstalled reports whether the cluster serves the HTTPRoute API
+func gatewayAPIInstalled(mapper meta.RESTMapper) bool {
+	_, err := mapper.RESTMapping(httpRouteGVK.GroupKind(), httpRouteGVK.Version)
+	return err == nil
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTPRoute fields", func() {
+	desired := map[string]interface{}{
+		"parentRefs": []interface{}{map[string]interface{}{"name": "gateway"}},
+		"rules": []interface{}{
+			map[string]interface{}{
+				"backendRefs": []interface{}{map[string]interface{}{"name": "app-my-server", "port": int64(80)}},
+			},
+		},
+	}
+
+	It("keeps the fields defaulted by the API server", func() {
+		live := map[string]interface{}{
+			"parentRefs": []interface{}{map[string]interface{}{
+				"group": "gateway.networking.k8s.io", "kind": "Gateway", "name": "gateway"}},
+			"rules": []interface{}{
+				map[string]interface{}{
+					"backendRefs": []interface{}{map[string]interface{}{
+						"group": "", "kind": "Service", "name": "app-my-server", "port": int64(80), "weight": int64(1)}},
+				},
+			},
+		}
+		Expect(containsFields(live, desired)).To(BeTrue())
+	})
+
+	It("detects the desired fields that changed", func() {
+		live := map[string]interface{}{
+			"parentRefs": []interface{}{map[string]interface{}{"name": "gateway"}, map[string]interface{}{"name": "other"}},
+			"rules": []interface{}{
+				map[string]interface{}{
+					"backendRefs": []interface{}{map[string]interface{}{"name": "app-my-server", "port": int64(80)}},
+				},
+			},
+		}
+		Expect(containsFields(live, desired)).To(BeFalse())
+
+		live["parentRefs"] = []interface{}{map[string]interface{}{"name": "gateway"}}
+		live["rules"] = []interface{}{
+			map[string]interface{}{
+				"backendRefs": []interface{}{map[string]interface{}{"name": "app-my-server", "port": int64(8080)}},
+			},
+		}
+		Expect(containsFields(live, desired)).To(BeFalse())
+	})
+})
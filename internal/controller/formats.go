@@ -0,0 +1,129 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/fixture"
+)
+
+// composedAnnotation holds, on the ConfigMap of the JsonServer, the hash of the inputs its
+// document was composed from: the format, the collections and the generators of the
+// collections, and the document of the source but for HTTP sources. The document of the
+// ConfigMap, or the last download of an HTTP source, is only reused while they are unchanged.
+const composedAnnotation = "example.example.com/composed-from"
+
+// composition returns the hash of the inputs the configuration is composed from. The
+// generators are hashed rather than the collections they render, which are only rendered
+// when the inputs change.
+func composition(format, document string, collections map[string]fixture.Input, generators map[string]fixture.Generator) string {
+	if document != "" {
+		document = checksum(document)
+	}
+	inputs, _ := json.Marshal(struct {
+		Format      string                       `json:"format"`
+		Document    string                       `json:"document,omitempty"`
+		Collections map[string]fixture.Input     `json:"collections"`
+		Generators  map[string]fixture.Generator `json:"generators,omitempty"`
+	}{format, document, collections, generators})
+	sum := sha256.Sum256(inputs)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// composeData converts the configuration from the format of the spec into JSON and adds the
+// collections of the spec, read from their sources or generated. Revisions and snapshots,
+// which hold the db.json served, are served as is.
+func (r *JsonServerReconciler) composeData(ctx context.Context, jsonServer *examplev1.JsonServer, data *resolvedData) *reconcileError {
+	if kind := data.Source.Kind; kind == examplev1.SourceKindRevision || kind == examplev1.SourceKindSnapshot {
+		return nil
+	}
+
+	spec := &jsonServer.Spec
+	var collections map[string]fixture.Input
+	var generators map[string]fixture.Generator
+	hash := ""
+	jsonServer.Status.Generated = nil
+	if fixture.Converts(spec) {
+		var rerr *reconcileError
+		if collections, rerr = r.resolveCollections(ctx, jsonServer); rerr != nil {
+			return rerr
+		}
+		generators = fixture.Generators(spec)
+		if data.Source.Kind == examplev1.SourceKindHTTP {
+			// The last download is kept composed, see resolveHTTP
+			hash = composition(spec.Format, "", collections, generators)
+		} else {
+			hash = composition(spec.Format, data.Data, collections, generators)
+			// Generating the collections takes up to seconds, the document served is reused
+			if composed, ok := r.composedDocument(ctx, jsonServer, hash); ok {
+				data.Data = composed
+				data.Composition = hash
+			}
+		}
+	}
+	if data.Composition == hash {
+		jsonServer.Status.Generated = generatedStatus(generators)
+		return nil
+	}
+	if data.Composition != "" {
+		// The last download was composed from other collections, download it again
+		fresh, rerr := r.downloadHTTP(ctx, jsonServer)
+		if rerr != nil {
+			return rerr
+		}
+		*data = *fresh
+		if hash == "" {
+			return nil
+		}
+	}
+
+	if rerr := generateCollections(generators, collections); rerr != nil {
+		return rerr
+	}
+	document := fixture.Input{Source: data.Description, Format: fixture.Format(spec.Format), Data: data.Data}
+	composed, err := fixture.Compose(document, collections)
+	if err != nil {
+		// Nothing to retry until the spec or the sources change, HTTP sources are polled
+		return &reconcileError{Step: stepDataSource, Reason: reasonConversionFailed, RetryAfter: data.RequeueAfter, Err: err}
+	}
+	data.Data = composed
+	data.Composition = hash
+	jsonServer.Status.Generated = generatedStatus(generators)
+	return nil
+}
+
+// composedDocument returns the document of the ConfigMap of the JsonServer when it was composed
+// from the inputs of the hash
+func (r *JsonServerReconciler) composedDocument(ctx context.Context, jsonServer *examplev1.JsonServer, hash string) (string, bool) {
+	configMap := &corev1.ConfigMap{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: jsonServer.Namespace, Name: jsonServer.Name}, configMap); err != nil {
+		return "", false
+	}
+	if !metav1.IsControlledBy(configMap, jsonServer) || configMap.Annotations[composedAnnotation] != hash {
+		return "", false
+	}
+	return r.readDocument(ctx, configMap)
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/fixture"
+)
+
+// generateCollections renders the collections of spec.generate into the inputs of the
+// configuration
+func generateCollections(generators map[string]fixture.Generator, collections map[string]fixture.Input) *reconcileError {
+	counts := make(map[string]int, len(generators))
+	for name, generator := range generators {
+		counts[name] = generator.Count
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(generators)) {
+		generator := generators[name]
+		if _, ok := collections[name]; ok {
+			return &reconcileError{Step: stepDataSource, Reason: reasonGenerationFailed,
+				Err: fmt.Errorf("%s: collection %q is already defined by spec.collections", generator.Source, name)}
+		}
+		records, err := generator.Generate(counts)
+		if err != nil {
+			return &reconcileError{Step: stepDataSource, Reason: reasonGenerationFailed, Err: err}
+		}
+		encoded, err := json.Marshal(records)
+		if err != nil {
+			return &reconcileError{Step: stepDataSource, Reason: reasonGenerationFailed, Err: err}
+		}
+		collections[name] = fixture.Input{Source: generator.Source, Format: fixture.JSON, Data: string(encoded)}
+	}
+	return nil
+}
+
+// generatedStatus returns the seeds the collections of spec.generate are rendered with, to be
+// reported in the status
+func generatedStatus(generators map[string]fixture.Generator) []examplev1.GeneratedCollectionStatus {
+	var generated []examplev1.GeneratedCollectionStatus
+	for _, name := range slices.Sorted(maps.Keys(generators)) {
+		generator := generators[name]
+		generated = append(generated, examplev1.GeneratedCollectionStatus{Name: name, Seed: generator.Seed, Count: int32(generator.Count)})
+	}
+	return generated
+}
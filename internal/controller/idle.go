@@ -0,0 +1,289 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+const (
+	// stepIdle is the reconcile step scaling idle JsonServers to zero and running their activator
+	stepIdle = "Idle"
+	// activatorLabel selects the activator pods of a JsonServer
+	activatorLabel = "example.example.com/activator-for"
+	// defaultScaleToZeroAfter is used when the idle section sets no delay
+	defaultScaleToZeroAfter = 15 * time.Minute
+	// defaultActivationTimeout is used when the idle section sets no activation timeout
+	defaultActivationTimeout = 2 * time.Minute
+)
+
+// scalesToZero reports whether the JsonServer is scaled to zero when idle. It never is when
+// the operator has no activator image to receive its requests.
+func (r *JsonServerReconciler) scalesToZero(jsonServer *examplev1.JsonServer) bool {
+	return jsonServer.Spec.Idle != nil && r.ActivatorImage != ""
+}
+
+// activatorName is the name of the activator Deployment and of its RBAC objects
+func activatorName(jsonServer *examplev1.JsonServer) string {
+	return jsonServer.Name + "-activator"
+}
+
+// activatorLabels returns the labels of the activator pods. They must not match the selector
+// of the json-server pods.
+func activatorLabels(jsonServer *examplev1.JsonServer) map[string]string {
+	return map[string]string{
+		activatorLabel: jsonServer.Name,
+		"managed-by":   "jsonserver-operator",
+	}
+}
+
+// reconcileIdle scales the JsonServer to zero once it served no request for the idle delay,
+// and back up once its activator received a request. It returns the delay until the
+// JsonServer may be idle, zero when it is idle or never scaled to zero.
+func (r *JsonServerReconciler) reconcileIdle(ctx context.Context, jsonServer *examplev1.JsonServer) (time.Duration, error) {
+	log := logf.FromContext(ctx)
+
+	if !r.scalesToZero(jsonServer) {
+		jsonServer.Status.IdleSince = nil
+		return 0, r.deleteActivator(ctx, jsonServer)
+	}
+	if err := r.reconcileActivator(ctx, jsonServer); err != nil {
+		return 0, err
+	}
+
+	// A request received by the activator counts as a request served by the JsonServer
+	activatedAt, err := time.Parse(time.RFC3339, jsonServer.Annotations[examplev1.ActivatedAtAnnotation])
+	if err == nil && (jsonServer.Status.LastRequestTime == nil || activatedAt.After(jsonServer.Status.LastRequestTime.Time)) {
+		jsonServer.Status.LastRequestTime = ptr.To(metav1.NewTime(activatedAt))
+	}
+
+	if idleSince := jsonServer.Status.IdleSince; idleSince != nil {
+		if err != nil || !activatedAt.After(idleSince.Time) {
+			return 0, nil
+		}
+		jsonServer.Status.IdleSince = nil
+		r.Recorder.Event(jsonServer, corev1.EventTypeNormal, "Activated",
+			fmt.Sprintf("Request received at %s, scaling back to %d replicas", activatedAt.UTC().Format(time.RFC3339), desiredReplicas(jsonServer, nil)))
+		log.Info("Idle JsonServer activated", "activatedAt", activatedAt)
+	}
+	// Kept running until the data of the expired JsonServer is captured
+	if capturingOnExpiry(jsonServer) {
+		return 0, nil
+	}
+
+	after := defaultScaleToZeroAfter
+	if jsonServer.Spec.Idle.ScaleToZeroAfter.Duration > 0 {
+		after = jsonServer.Spec.Idle.ScaleToZeroAfter.Duration
+	}
+	now := time.Now()
+	idleAt := lastActivity(jsonServer).Add(after)
+	if idleAt.After(now) {
+		return idleAt.Sub(now), nil
+	}
+	// The logs are only read when the JsonServer looks idle
+	if err := r.observeRequests(ctx, jsonServer); err != nil {
+		return 0, err
+	}
+	if idleAt = lastActivity(jsonServer).Add(after); idleAt.After(now) {
+		return idleAt.Sub(now), nil
+	}
+
+	jsonServer.Status.IdleSince = ptr.To(metav1.NewTime(now).Rfc3339Copy())
+	r.Recorder.Event(jsonServer, corev1.EventTypeNormal, "ScaledToZero",
+		fmt.Sprintf("No request since %s, scaling to zero", lastActivity(jsonServer).UTC().Format(time.RFC3339)))
+	log.Info("Idle JsonServer scaled to zero")
+	return 0, nil
+}
+
+// lastActivity returns when the JsonServer last served a request, its creation when it
+// never did as far as the operator knows
+func lastActivity(jsonServer *examplev1.JsonServer) time.Time {
+	last := jsonServer.CreationTimestamp.Time
+	if request := jsonServer.Status.LastRequestTime; request != nil && request.After(last) {
+		last = request.Time
+	}
+	return last
+}
+
+// routesToActivator reports whether the Services of the JsonServer send the requests to its
+// activator, i.e. while the JsonServer is idle or has no ready pod to serve them
+func (r *JsonServerReconciler) routesToActivator(jsonServer *examplev1.JsonServer, workload workloadState) bool {
+	return r.scalesToZero(jsonServer) && (jsonServer.Status.IdleSince != nil || workload.readyReplicas == 0)
+}
+
+// reconcileActivator ensures the activator of the JsonServer runs, with the permissions to
+// activate the JsonServer and find its pods
+func (r *JsonServerReconciler) reconcileActivator(ctx context.Context, jsonServer *examplev1.JsonServer) error {
+	log := logf.FromContext(ctx)
+	name := activatorName(jsonServer)
+	meta := metav1.ObjectMeta{Name: name, Namespace: jsonServer.Namespace, Labels: activatorLabels(jsonServer)}
+
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: *meta.DeepCopy()}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, serviceAccount, func() error {
+		return controllerutil.SetControllerReference(jsonServer, serviceAccount, r.Scheme)
+	}); err != nil {
+		log.Error(err, "Failed to create or update activator ServiceAccount")
+		return err
+	}
+
+	role := &rbacv1.Role{ObjectMeta: *meta.DeepCopy()}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, role, func() error {
+		role.Rules = []rbacv1.PolicyRule{
+			{
+				APIGroups:     []string{examplev1.GroupVersion.Group},
+				Resources:     []string{"jsonservers"},
+				ResourceNames: []string{jsonServer.Name},
+				Verbs:         []string{"get", "patch"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"pods"},
+				Verbs:     []string{"list"},
+			},
+		}
+		return controllerutil.SetControllerReference(jsonServer, role, r.Scheme)
+	}); err != nil {
+		log.Error(err, "Failed to create or update activator Role")
+		return err
+	}
+
+	roleBinding := &rbacv1.RoleBinding{ObjectMeta: *meta.DeepCopy()}
+	if _, err := controllerutil.CreateOrUpdate(ctx, r.Client, roleBinding, func() error {
+		// The role reference of a RoleBinding is immutable, it is only set on creation
+		if roleBinding.CreationTimestamp.IsZero() {
+			roleBinding.RoleRef = rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "Role", Name: name}
+		}
+		roleBinding.Subjects = []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: name, Namespace: jsonServer.Namespace}}
+		return controllerutil.SetControllerReference(jsonServer, roleBinding, r.Scheme)
+	}); err != nil {
+		log.Error(err, "Failed to create or update activator RoleBinding")
+		return err
+	}
+
+	deployment := &appsv1.Deployment{ObjectMeta: *meta.DeepCopy()}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, deployment, func() error {
+		if err := controllerutil.SetControllerReference(jsonServer, deployment, r.Scheme); err != nil {
+			return err
+		}
+		deployment.Spec.Replicas = ptr.To(int32(1))
+		deployment.Spec.Selector = &metav1.LabelSelector{MatchLabels: activatorLabels(jsonServer)}
+		deployment.Spec.Template = activatorPodTemplate(jsonServer, r.ActivatorImage)
+		return nil
+	})
+	if err != nil {
+		log.Error(err, "Failed to create or update activator Deployment")
+		return err
+	}
+
+	log.Info("Activator reconciled", "operation", op)
+	return nil
+}
+
+// activatorPodTemplate returns the template of the activator pods, which listen on the port
+// of the json-server pods so that the Services can target either
+func activatorPodTemplate(jsonServer *examplev1.JsonServer, image string) corev1.PodTemplateSpec {
+	timeout := defaultActivationTimeout
+	if jsonServer.Spec.Idle.ActivationTimeout.Duration > 0 {
+		timeout = jsonServer.Spec.Idle.ActivationTimeout.Duration
+	}
+
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{Labels: activatorLabels(jsonServer)},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: activatorName(jsonServer),
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsNonRoot:   ptr.To(true),
+				SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+			},
+			Containers: []corev1.Container{
+				{
+					Name:    "activator",
+					Image:   image,
+					Command: []string{"/activator"},
+					Args: []string{
+						"--jsonserver=" + jsonServer.Name,
+						"--namespace=" + jsonServer.Namespace,
+						"--selector=" + formatSelector(getResourceLabels(jsonServer)),
+						"--activation-timeout=" + timeout.String(),
+					},
+					Ports: []corev1.ContainerPort{
+						{
+							ContainerPort: containerPort,
+							Name:          "http",
+							Protocol:      corev1.ProtocolTCP,
+						},
+					},
+					ReadinessProbe: &corev1.Probe{
+						ProbeHandler: corev1.ProbeHandler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("http")}},
+					},
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("10m"),
+							corev1.ResourceMemory: resource.MustParse("16Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceMemory: resource.MustParse("64Mi"),
+						},
+					},
+					SecurityContext: &corev1.SecurityContext{
+						AllowPrivilegeEscalation: ptr.To(false),
+						Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// formatSelector formats labels as a label selector, e.g. "app=name,managed-by=operator"
+func formatSelector(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// deleteActivator removes the activator of a JsonServer that is no longer scaled to zero
+func (r *JsonServerReconciler) deleteActivator(ctx context.Context, jsonServer *examplev1.JsonServer) error {
+	name := activatorName(jsonServer)
+	for _, obj := range []client.Object{&appsv1.Deployment{}, &rbacv1.RoleBinding{}, &rbacv1.Role{}, &corev1.ServiceAccount{}} {
+		if err := r.deleteOwned(ctx, jsonServer, name, obj); err != nil {
+			logf.FromContext(ctx).Error(err, "Failed to delete activator", "kind", fmt.Sprintf("%T", obj))
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/image"
+)
+
+// image returns the json-server image of the JsonServer: its own, the operator default or the built-in default
+func (r *JsonServerReconciler) image(jsonServer *examplev1.JsonServer) string {
+	switch {
+	case jsonServer.Spec.Image != "":
+		return jsonServer.Spec.Image
+	case r.DefaultImage != "":
+		return r.DefaultImage
+	default:
+		return examplev1.DefaultImage
+	}
+}
+
+// recordImage reports the image of the JsonServer and the digest it resolved to on the
+// running pods. The digest is only reported, the pod template keeps the image of the spec so
+// that it never changes without a spec change. It is resolved once per image, the pods are
+// not listed again once it is known. The digest of the previous image is cleared until a
+// pod runs the new one.
+func (r *JsonServerReconciler) recordImage(ctx context.Context, jsonServer *examplev1.JsonServer) {
+	log := logf.FromContext(ctx)
+
+	jsonImage := r.image(jsonServer)
+	if jsonServer.Status.Image != jsonImage {
+		jsonServer.Status.Image = jsonImage
+		jsonServer.Status.ImageDigest = ""
+	}
+	if jsonServer.Status.ImageDigest != "" {
+		return
+	}
+
+	// Pods are not watched, the workload status changes once a pod is ready
+	pods := &corev1.PodList{}
+	if err := r.uncachedReader().List(ctx, pods, client.InNamespace(jsonServer.Namespace),
+		client.MatchingLabels(getResourceLabels(jsonServer))); err != nil {
+		// The digest is resolved again on the next reconcile
+		log.Error(err, "Failed to list pods to resolve the image digest")
+		return
+	}
+
+	for _, pod := range pods.Items {
+		if digest := podImageDigest(&pod, jsonImage); digest != "" {
+			jsonServer.Status.ImageDigest = digest
+			return
+		}
+	}
+}
+
+// podImageDigest returns the digest of the json-server container of the pod, if it runs the image
+func podImageDigest(pod *corev1.Pod, jsonImage string) string {
+	if len(pod.Spec.Containers) == 0 || pod.Spec.Containers[0].Image != jsonImage {
+		return ""
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == pod.Spec.Containers[0].Name {
+			return image.Digest(status.ImageID)
+		}
+	}
+	return ""
+}
@@ -18,37 +18,97 @@ package controller
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/intstr"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
 	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/payload"
+	"jsonserver-operator/internal/validation"
 )
 
+// configHashAnnotation is stamped on the pod template so that every change of
+// the served configuration rolls the json-server pods.
+const configHashAnnotation = "example.example.com/config-hash"
+
 // JsonServerReconciler reconciles a JsonServer object
 type JsonServerReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// APIReader reads the Secrets referenced in spec.dataFrom without caching them.
+	// The Client is used when it is not set.
+	APIReader client.Reader
+	// HTTPClient downloads the documents referenced in spec.dataFrom.
+	// A client with a 30 seconds timeout, restricted to the allowed addresses, is used when
+	// it is not set.
+	HTTPClient *http.Client
+	// AllowedURLHosts are the glob patterns of the hosts the documents of spec.dataFrom can be
+	// downloaded from, any public host when empty. See config.Config.
+	AllowedURLHosts []string
+	// DefaultImage is the json-server image of the JsonServers that set no image.
+	// examplev1.DefaultImage is used when it is not set.
+	DefaultImage string
+	// EphemeralTTL deletes the JsonServers of ephemeral namespaces that set no TTL this long
+	// after their creation. Disabled when zero.
+	EphemeralTTL time.Duration
+	// PodLogs reads the logs of the json-server pods to find their last request. The idle
+	// time of spec.ttlSecondsAfterLastRequest counts from the creation when it is not set.
+	PodLogs corev1client.PodsGetter
+	// ActivatorImage is the image of the activator receiving the requests of the JsonServers
+	// scaled to zero. JsonServers are never scaled to zero when it is not set.
+	ActivatorImage string
+	// OperatorNamespace is the namespace the operator runs in. The NetworkPolicies of the
+	// access sections let the operator pods of this namespace read the data of the pods.
+	OperatorNamespace string
 }
 
 // +kubebuilder:rbac:groups=example.example.com,resources=jsonservers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=example.example.com,resources=jsonservers/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=example.example.com,resources=jsonservers/finalizers,verbs=update
 // +kubebuilder:rbac:groups=example.example.com,resources=jsonservers/scale,verbs=get;update;patch
+// +kubebuilder:rbac:groups=example.example.com,resources=jsonserversnapshots,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=example.example.com,resources=jsonfixtures,verbs=get;list;watch
 
 // RBAC to manage the custom resources (including delete so that it can cleanup the resources when the CRD is deleted)
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+// +kubebuilder:rbac:groups=core,resources=pods/log,verbs=get
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=roles;rolebindings,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -76,39 +136,214 @@ func (r *JsonServerReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return ctrl.Result{}, err
 	}
 
-	if err := validateJSON(jsonServer.Spec.JsonConfig); err != nil {
+	// Keep the observed status around so that updateStatus only writes on change
+	originalStatus := jsonServer.Status.DeepCopy()
+
+	// Delete the JsonServer once it outlived its TTL
+	expireAfter, expired, err := r.reconcileExpiry(ctx, jsonServer)
+	if err != nil {
+		return r.reconcileFailed(ctx, jsonServer, originalStatus, classifyError(stepExpiry, err))
+	}
+	if expired {
+		return ctrl.Result{}, nil
+	}
+
+	// Resolve the JSON configuration from its source
+	data, rerr := r.resolveData(ctx, jsonServer)
+	if rerr != nil {
+		log.Error(rerr, "Failed to resolve the JSON configuration")
+		return r.configFailed(ctx, jsonServer, originalStatus, rerr)
+	}
+	jsonServer.Status.Source = &data.Source
+
+	// Converted from the format of the spec and completed with its collections
+	if rerr := r.composeData(ctx, jsonServer, data); rerr != nil {
+		log.Error(rerr, "Failed to convert the configuration")
+		return r.configFailed(ctx, jsonServer, originalStatus, rerr)
+	}
+
+	if err := validateJSON(data.Data, validationOptions(jsonServer)); err != nil {
 		log.Error(err, "Invalid JSON configuration")
-		return r.updateStatus(ctx, jsonServer, "Error", "Error: spec.jsonConfig is not a valid json object")
+		rerr := &reconcileError{Step: stepDataSource, Reason: reasonInvalidJSON, RetryAfter: data.RequeueAfter,
+			Err: fmt.Errorf("%s is not a valid json object: %w", data.Description, err)}
+		if documentErrs, ok := err.(validation.ErrorList); ok {
+			rerr.Reason = reasonInvalidStructure
+			rerr.Err = fmt.Errorf("%s cannot be served by json-server: %s", data.Description, truncateErrors(documentErrs))
+		}
+		// Nothing to retry until the spec or the source changes, HTTP sources are polled
+		return r.configFailed(ctx, jsonServer, originalStatus, rerr)
+	}
+	// Compressed and split across several ConfigMaps when it does not fit in one
+	chunks, err := payload.Encode(data.Data)
+	if err != nil {
+		log.Error(err, "JSON configuration too large")
+		rerr := &reconcileError{Step: stepConfigMap, Reason: reasonConfigTooLarge, RetryAfter: data.RequeueAfter,
+			Err: fmt.Errorf("%s cannot be stored in ConfigMaps: %w", data.Description, err)}
+		return r.configFailed(ctx, jsonServer, originalStatus, rerr)
+	}
+	if data.Sensitive && len(chunks) > 1 {
+		rerr := &reconcileError{Step: stepDataSecret, Reason: reasonConfigTooLarge, RetryAfter: data.RequeueAfter,
+			Err: fmt.Errorf("%s cannot be stored in a Secret: it is larger than %d bytes once compressed", data.Description, payload.ChunkSize)}
+		return r.configFailed(ctx, jsonServer, originalStatus, rerr)
+	}
+	setCondition(jsonServer, examplev1.ConditionConfigValid, metav1.ConditionTrue, reasonValid, fmt.Sprintf("%s is valid", data.Description))
+
+	// Immutable revision of the configuration, kept for rollbacks
+	if err := r.reconcileRevisions(ctx, jsonServer, data); err != nil {
+		return r.reconcileFailed(ctx, jsonServer, originalStatus, classifyError(stepRevisions, err))
+	}
+
+	// Resets of the data changed through the API, rendered into the pod template
+	resetAfter, rerr := r.reconcileReset(ctx, jsonServer)
+	if rerr != nil {
+		return r.reconcileFailed(ctx, jsonServer, originalStatus, rerr)
+	}
+
+	// Scale to zero when idle, back up when the activator received a request
+	idleAfter, err := r.reconcileIdle(ctx, jsonServer)
+	if err != nil {
+		return r.reconcileFailed(ctx, jsonServer, originalStatus, classifyError(stepIdle, err))
 	}
 
 	// Create resources
+	// Secret for the JSON data read from Secrets, never copied into the ConfigMap
+	var secret *corev1.Secret
+	if data.Sensitive {
+		if secret, err = r.reconcileDataSecret(ctx, jsonServer, data, chunks); err != nil {
+			return r.reconcileFailed(ctx, jsonServer, originalStatus, classifyError(stepDataSecret, err))
+		}
+	}
+
 	// ConfigMap for JSON data
-	configMap, err := r.reconcileConfigMap(ctx, jsonServer)
+	configMap, err := r.reconcileConfigMap(ctx, jsonServer, data, chunks)
 	if err != nil {
-		return r.updateStatus(ctx, jsonServer, "Error", "Error: unexpected failure")
+		return r.reconcileFailed(ctx, jsonServer, originalStatus, classifyError(stepConfigMap, err))
 	}
 
-	// Deployment
-	if err := r.reconcileDeployment(ctx, jsonServer, configMap); err != nil {
-		return r.updateStatus(ctx, jsonServer, "Error", "Error: unexpected failure")
+	// Deployment, or StatefulSet for persistent JsonServers
+	workload, rerr := r.reconcileWorkload(ctx, jsonServer, configMap, secret)
+	if rerr != nil {
+		return r.reconcileFailed(ctx, jsonServer, originalStatus, rerr)
 	}
 
-	// Service
-	if err := r.reconcileService(ctx, jsonServer); err != nil {
-		return r.updateStatus(ctx, jsonServer, "Error", "Error: unexpected failure")
+	// The data Secret is removed once the pods no longer read it
+	if !data.Sensitive {
+		if err := r.deleteDataSecret(ctx, jsonServer); err != nil {
+			return r.reconcileFailed(ctx, jsonServer, originalStatus, classifyError(stepDataSecret, err))
+		}
+	}
+
+	// HorizontalPodAutoscaler and PodDisruptionBudget of the pods
+	if err := r.reconcileAutoscaler(ctx, jsonServer); err != nil {
+		return r.reconcileFailed(ctx, jsonServer, originalStatus, classifyError(stepAutoscaling, err))
+	}
+	if err := r.reconcileDisruptionBudget(ctx, jsonServer); err != nil {
+		return r.reconcileFailed(ctx, jsonServer, originalStatus, classifyError(stepDisruptionBudget, err))
+	}
+
+	// NetworkPolicies restricting the clients of the pods
+	if err := r.reconcileAccess(ctx, jsonServer); err != nil {
+		return r.reconcileFailed(ctx, jsonServer, originalStatus, classifyError(stepAccess, err))
+	}
+
+	// Service, pointing at the activator while there is no pod to serve the requests
+	toActivator := r.routesToActivator(jsonServer, workload)
+	if err := r.reconcileService(ctx, jsonServer, toActivator); err != nil {
+		return r.reconcileFailed(ctx, jsonServer, originalStatus, classifyError(stepService, err))
+	}
+	if err := r.reconcileWriteService(ctx, jsonServer, toActivator); err != nil {
+		return r.reconcileFailed(ctx, jsonServer, originalStatus, classifyError(stepWriteService, err))
+	}
+
+	// Ingress or HTTPRoute
+	if err := r.reconcileExpose(ctx, jsonServer); err != nil {
+		return r.reconcileFailed(ctx, jsonServer, originalStatus, classifyError(stepExpose, err))
 	}
 
 	// Set Synced state
-	return r.updateStatus(ctx, jsonServer, "Synced", "Synced succesfully!")
+	setCondition(jsonServer, examplev1.ConditionResourcesReconciled, metav1.ConditionTrue, reasonReconciled, "All resources reconciled")
+	setWorkloadConditions(jsonServer, workload)
+	result, err := r.updateStatus(ctx, jsonServer, originalStatus, "Synced", "Synced succesfully!")
+	if err != nil {
+		return result, err
+	}
+	// Read periodically refreshed sources again, reset the data on schedule, scale to zero
+	// and expire on time
+	return ctrl.Result{RequeueAfter: soonest(data.RequeueAfter, resetAfter, idleAfter, expireAfter)}, nil
+}
+
+// configFailed records why the JSON configuration cannot be served and requeues
+// the JsonServer according to the retry policy of the error class
+func (r *JsonServerReconciler) configFailed(ctx context.Context, jsonServer *examplev1.JsonServer, originalStatus *examplev1.JsonServerStatus, rerr *reconcileError) (ctrl.Result, error) {
+	message := fmt.Sprintf("Error: %v", rerr.Err)
+	setCondition(jsonServer, examplev1.ConditionConfigValid, metav1.ConditionFalse, rerr.Reason, message)
+	setNotReady(jsonServer, rerr.Reason, message)
+	r.Recorder.Event(jsonServer, corev1.EventTypeWarning, rerr.Reason, message)
+
+	if result, err := r.updateStatus(ctx, jsonServer, originalStatus, "Error", message); err != nil {
+		return result, err
+	}
+	return requeueAtExpiry(jsonServer, rerr)
+}
+
+// reconcileFailed records why the owned resources could not be written and
+// requeues the JsonServer according to the retry policy of the error class
+func (r *JsonServerReconciler) reconcileFailed(ctx context.Context, jsonServer *examplev1.JsonServer, originalStatus *examplev1.JsonServerStatus, rerr *reconcileError) (ctrl.Result, error) {
+	message := fmt.Sprintf("Error: %s", rerr.Error())
+	setCondition(jsonServer, examplev1.ConditionResourcesReconciled, metav1.ConditionFalse, rerr.Reason, message)
+	setNotReady(jsonServer, rerr.Reason, message)
+	r.Recorder.Event(jsonServer, corev1.EventTypeWarning, rerr.Reason, message)
+
+	if result, err := r.updateStatus(ctx, jsonServer, originalStatus, "Error", message); err != nil {
+		return result, err
+	}
+	return requeueAtExpiry(jsonServer, rerr)
+}
+
+// requeueAtExpiry returns the result of a failed reconcile, requeued no later than the
+// expiration of the JsonServer so that JsonServers that cannot be reconciled expire too
+func requeueAtExpiry(jsonServer *examplev1.JsonServer, rerr *reconcileError) (ctrl.Result, error) {
+	result, err := rerr.result()
+	if expiration := jsonServer.Status.ExpirationTime; expiration != nil && err == nil && !result.Requeue {
+		result.RequeueAfter = soonest(result.RequeueAfter, max(time.Until(expiration.Time), expirySnapshotPollInterval))
+	}
+	return result, err
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *JsonServerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	// Index the JsonServers by the ConfigMaps, Secrets, snapshots, fixtures and JsonServers they
+	// read their data or their collections from
+	for _, index := range []string{configMapRefIndex, secretRefIndex, snapshotRefIndex, fixtureRefIndex, jsonServerRefIndex} {
+		if err := mgr.GetFieldIndexer().IndexField(context.Background(), &examplev1.JsonServer{}, index, indexDataSourceRefs(index)); err != nil {
+			return err
+		}
+	}
+
+	controllerBuilder := ctrl.NewControllerManagedBy(mgr).
 		For(&examplev1.JsonServer{}).
 		Owns(&appsv1.Deployment{}).
+		Owns(&appsv1.StatefulSet{}).
 		Owns(&corev1.Service{}).
 		Owns(&corev1.ConfigMap{}).
+		Owns(&corev1.Secret{}, builder.OnlyMetadata).
+		Owns(&networkingv1.Ingress{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Owns(&policyv1.PodDisruptionBudget{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		// The ConfigMap of a JsonServer holds the configuration other JsonServers read collections from
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.jsonServersReferencing(configMapRefIndex, jsonServerRefIndex))).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(r.jsonServersReferencing(secretRefIndex)),
+			builder.OnlyMetadata).
+		Watches(&examplev1.JsonServerSnapshot{}, handler.EnqueueRequestsFromMapFunc(r.jsonServersReferencing(snapshotRefIndex))).
+		Watches(&examplev1.JsonFixture{}, handler.EnqueueRequestsFromMapFunc(r.jsonServersReferencing(fixtureRefIndex)))
+
+	// HTTPRoutes can only be watched in clusters serving the Gateway API
+	if gatewayAPIInstalled(mgr.GetRESTMapper()) {
+		controllerBuilder = controllerBuilder.Owns(newHTTPRoute())
+	}
+
+	return controllerBuilder.
 		Named("jsonserver").
 		Complete(r)
 }
@@ -123,39 +358,72 @@ func getResourceLabels(jsonServer *examplev1.JsonServer) map[string]string {
 	}
 }
 
-// validateJSON checks if the input string is a valid JSON
-func validateJSON(input string) error {
-	var js json.RawMessage
-	return json.Unmarshal([]byte(input), &js)
+// validateJSON checks if the input string is a valid JSON document json-server can serve.
+// The admission webhook rejects invalid documents already, this is the safety net for
+// when it is disabled.
+func validateJSON(input string, opts validation.Options) error {
+	return validation.Validate(input, opts)
+}
+
+// maxReportedDocumentErrors caps the number of structural errors reported in the status
+const maxReportedDocumentErrors = 5
+
+// truncateErrors keeps the first structural errors of a document so that status messages stay short
+func truncateErrors(errs validation.ErrorList) string {
+	if len(errs) <= maxReportedDocumentErrors {
+		return errs.Error()
+	}
+	return fmt.Sprintf("%s (and %d more)", errs[:maxReportedDocumentErrors].Error(), len(errs)-maxReportedDocumentErrors)
+}
+
+// configHash returns a short content hash of the data served from the ConfigMap. The data
+// Secret is identified by its resource version, so that its content is not hashed into the
+// pod template.
+func configHash(configMap *corev1.ConfigMap, secret *corev1.Secret) string {
+	keys := make([]string, 0, len(configMap.Data))
+	for key := range configMap.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hasher := sha256.New()
+	for _, key := range keys {
+		hasher.Write([]byte(key))
+		hasher.Write([]byte{0})
+		hasher.Write([]byte(configMap.Data[key]))
+		hasher.Write([]byte{0})
+	}
+	if secret != nil {
+		hasher.Write([]byte(secret.Name))
+		hasher.Write([]byte{0})
+		hasher.Write([]byte(secret.ResourceVersion))
+	}
+	return hex.EncodeToString(hasher.Sum(nil))[:16]
 }
 
 // updateStatus updates the status of the JsonServer resource
-func (r *JsonServerReconciler) updateStatus(ctx context.Context, jsonServer *examplev1.JsonServer, state, message string) (ctrl.Result, error) {
+func (r *JsonServerReconciler) updateStatus(ctx context.Context, jsonServer *examplev1.JsonServer, originalStatus *examplev1.JsonServerStatus, state, message string) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 
-	// Check if any status field needs updating
-	needsUpdate := jsonServer.Status.State != state ||
-		jsonServer.Status.Message != message ||
-		jsonServer.Status.Replicas != jsonServer.Spec.Replicas ||
-		jsonServer.Status.Selector == ""
-
-	if needsUpdate {
-		jsonServer.Status.State = state
-		jsonServer.Status.Message = message
-		// Make sure replicas and selector are set (if not already set during reconcileDeployment)
-		if jsonServer.Status.Replicas != jsonServer.Spec.Replicas {
-			jsonServer.Status.Replicas = jsonServer.Spec.Replicas
-		}
-		if jsonServer.Status.Selector == "" {
-			labels := getResourceLabels(jsonServer)
-			selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
-				MatchLabels: labels,
-			})
-			if err == nil {
-				jsonServer.Status.Selector = selector.String()
-			}
+	jsonServer.Status.State = state
+	jsonServer.Status.Message = message
+	jsonServer.Status.ObservedGeneration = jsonServer.Generation
+	// Make sure replicas and selector are set (if not already set during reconcileDeployment)
+	if replicas := desiredReplicas(jsonServer, &jsonServer.Status.Replicas); jsonServer.Status.Replicas != replicas {
+		jsonServer.Status.Replicas = replicas
+	}
+	if jsonServer.Status.Selector == "" {
+		labels := getResourceLabels(jsonServer)
+		selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+			MatchLabels: labels,
+		})
+		if err == nil {
+			jsonServer.Status.Selector = selector.String()
 		}
+	}
 
+	// Check if any status field needs updating
+	if !equality.Semantic.DeepEqual(*originalStatus, jsonServer.Status) {
 		if err := r.Status().Update(ctx, jsonServer); err != nil {
 			log.Error(err, "Failed to update JsonServer status")
 			return ctrl.Result{}, err
@@ -165,10 +433,27 @@ func (r *JsonServerReconciler) updateStatus(ctx context.Context, jsonServer *exa
 	return ctrl.Result{}, nil
 }
 
-// reconcileConfigMap ensures the ConfigMap exists
-func (r *JsonServerReconciler) reconcileConfigMap(ctx context.Context, jsonServer *examplev1.JsonServer) (*corev1.ConfigMap, error) {
+// reconcileConfigMap ensures the ConfigMap exists. Documents compressed into several chunks
+// keep the first one in the ConfigMap and the others in chunk ConfigMaps, written first so
+// that they are in place once the ConfigMap points at them.
+func (r *JsonServerReconciler) reconcileConfigMap(ctx context.Context, jsonServer *examplev1.JsonServer, data *resolvedData, chunks [][]byte) (*corev1.ConfigMap, error) {
 	log := logf.FromContext(ctx)
 
+	// The data read from Secrets is kept in the data Secret
+	if data.Sensitive {
+		chunks = nil
+	}
+
+	routes, err := renderRoutes(jsonServer)
+	if err != nil {
+		log.Error(err, "Failed to render routes")
+		return nil, err
+	}
+
+	if err := r.reconcileChunks(ctx, jsonServer, chunks); err != nil {
+		return nil, err
+	}
+
 	configMap := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      jsonServer.Name,
@@ -187,7 +472,28 @@ func (r *JsonServerReconciler) reconcileConfigMap(ctx context.Context, jsonServe
 		if configMap.Data == nil {
 			configMap.Data = make(map[string]string)
 		}
-		configMap.Data["db.json"] = jsonServer.Spec.JsonConfig
+		if data.Sensitive {
+			clearDocument(configMap)
+			metav1.SetMetaDataAnnotation(&configMap.ObjectMeta, dataSecretAnnotation, dataSecretName(jsonServer))
+		} else {
+			setDocument(configMap, data.Data, chunks)
+			delete(configMap.Annotations, dataSecretAnnotation)
+		}
+		if data.Composition != "" {
+			metav1.SetMetaDataAnnotation(&configMap.ObjectMeta, composedAnnotation, data.Composition)
+		} else {
+			delete(configMap.Annotations, composedAnnotation)
+		}
+		if routes != "" {
+			configMap.Data[routesKey] = routes
+		} else {
+			delete(configMap.Data, routesKey)
+		}
+		if jsonServer.Spec.Consistency == examplev1.ConsistencySingleWriter {
+			configMap.Data[readReplicaKey] = readReplicaMiddleware
+		} else {
+			delete(configMap.Data, readReplicaKey)
+		}
 
 		return nil
 	})
@@ -201,9 +507,36 @@ func (r *JsonServerReconciler) reconcileConfigMap(ctx context.Context, jsonServe
 	return configMap, nil
 }
 
-func (r *JsonServerReconciler) reconcileDeployment(ctx context.Context, jsonServer *examplev1.JsonServer, configMap *corev1.ConfigMap) error {
+// reconcileWorkload runs the json-server pods as a Deployment, or as a StatefulSet when the
+// JsonServer is persistent or has a single writer, and returns the observed state of the workload
+func (r *JsonServerReconciler) reconcileWorkload(ctx context.Context, jsonServer *examplev1.JsonServer, configMap *corev1.ConfigMap, secret *corev1.Secret) (workloadState, *reconcileError) {
+	if runsAsStatefulSet(jsonServer) {
+		statefulSet, err := r.reconcileStatefulSet(ctx, jsonServer, configMap, secret)
+		if err != nil {
+			return workloadState{}, classifyError(stepStatefulSet, err)
+		}
+		r.recordImage(ctx, jsonServer)
+		return statefulSetState(statefulSet), nil
+	}
+
+	deployment, err := r.reconcileDeployment(ctx, jsonServer, configMap, secret)
+	if err != nil {
+		return workloadState{}, classifyError(stepDeployment, err)
+	}
+	r.recordImage(ctx, jsonServer)
+	return deploymentState(deployment), nil
+}
+
+// reconcileDeployment ensures the Deployment serving the ConfigMap exists and returns it with its observed status
+func (r *JsonServerReconciler) reconcileDeployment(ctx context.Context, jsonServer *examplev1.JsonServer, configMap *corev1.ConfigMap, secret *corev1.Secret) (*appsv1.Deployment, error) {
 	log := logf.FromContext(ctx)
 
+	// Remove the StatefulSet left over by a JsonServer that no longer needs one
+	if err := r.deleteOwned(ctx, jsonServer, jsonServer.Name, &appsv1.StatefulSet{}); err != nil {
+		log.Error(err, "Failed to delete StatefulSet")
+		return nil, err
+	}
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      jsonServer.Name,
@@ -212,6 +545,8 @@ func (r *JsonServerReconciler) reconcileDeployment(ctx context.Context, jsonServ
 		},
 	}
 
+	hash := configHash(configMap, secret)
+
 	// Create or update Deployment
 	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, deployment, func() error {
 
@@ -221,110 +556,106 @@ func (r *JsonServerReconciler) reconcileDeployment(ctx context.Context, jsonServ
 
 		labels := getResourceLabels(jsonServer)
 
-		deployment.Spec.Replicas = &jsonServer.Spec.Replicas
+		deployment.Spec.Replicas = ptr.To(desiredReplicas(jsonServer, deployment.Spec.Replicas))
 		deployment.Spec.Selector = &metav1.LabelSelector{
 			MatchLabels: labels,
 		}
-		deployment.Spec.Template = corev1.PodTemplateSpec{
-			ObjectMeta: metav1.ObjectMeta{
-				Labels: labels,
-			},
-			Spec: corev1.PodSpec{
-				Containers: []corev1.Container{
-					{
-						Name:  "json-server",
-						Image: "backplane/json-server",
-						Args:  []string{"/data/db.json"},
-						Ports: []corev1.ContainerPort{
-							{
-								ContainerPort: 3000,
-								Name:          "http",
-								Protocol:      corev1.ProtocolTCP,
-							},
-						},
-						VolumeMounts: []corev1.VolumeMount{
-							{
-								Name:      "json-config",
-								MountPath: "/data",
-							},
-						},
-					},
-				},
-				Volumes: []corev1.Volume{
-					{
-						Name: "json-config",
-						VolumeSource: corev1.VolumeSource{
-							ConfigMap: &corev1.ConfigMapVolumeSource{
-								LocalObjectReference: corev1.LocalObjectReference{
-									Name: configMap.Name,
-								},
-							},
-						},
-					},
-				},
-			},
+		deployment.Spec.Template = r.podTemplate(jsonServer, hash)
+		deployment.Spec.Template.Spec.Volumes = []corev1.Volume{documentVolume("json-config", configMap, secret)}
+		if chunkCount(configMap) > 0 || (secret != nil && chunkCount(secret) > 0) {
+			// Compressed documents are reassembled into an emptyDir before json-server starts
+			deployment.Spec.Template.Spec.InitContainers = []corev1.Container{unpackContainer(r.image(jsonServer))}
+			deployment.Spec.Template.Spec.Volumes = []corev1.Volume{
+				{Name: "json-config", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+				documentVolume(chunksVolumeName, configMap, secret),
+			}
 		}
+		applyServerOptions(jsonServer, &deployment.Spec.Template, configMap)
+		applyConsistency(jsonServer, &deployment.Spec.Template, configMap)
+		applyPodTemplateOverride(jsonServer, &deployment.Spec.Template)
 
 		return nil
 	})
 
 	if err != nil {
 		log.Error(err, "Failed to create or update Deployment")
-		return err
+		return nil, err
 	}
 
-	jsonServer.Status.Replicas = jsonServer.Spec.Replicas
-
-	labels := getResourceLabels(jsonServer)
-	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
-		MatchLabels: labels,
-	})
-	if err != nil {
+	if err := recordWorkloadStatus(jsonServer, *deployment.Spec.Replicas, hash); err != nil {
 		log.Error(err, "Failed to create selector from labels")
-		return err
+		return nil, err
 	}
-	jsonServer.Status.Selector = selector.String()
 
 	log.Info("Deployment reconciled", "operation", op)
 
-	return nil
+	return deployment, nil
 }
 
-func (r *JsonServerReconciler) reconcileService(ctx context.Context, jsonServer *examplev1.JsonServer) error {
-	log := logf.FromContext(ctx)
-
-	service := &corev1.Service{
+// podTemplate returns the template of the json-server pods serving /data/db.json
+// from the "json-config" volume
+func (r *JsonServerReconciler) podTemplate(jsonServer *examplev1.JsonServer, hash string) corev1.PodTemplateSpec {
+	template := corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      jsonServer.Name,
-			Namespace: jsonServer.Namespace,
-			Labels:    getResourceLabels(jsonServer),
+			Labels: getResourceLabels(jsonServer),
+			Annotations: map[string]string{
+				configHashAnnotation: hash,
+			},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{
+					Name:  "json-server",
+					Image: r.image(jsonServer),
+					Args:  []string{"/data/db.json"},
+					Ports: []corev1.ContainerPort{
+						{
+							ContainerPort: containerPort,
+							Name:          "http",
+							Protocol:      corev1.ProtocolTCP,
+						},
+					},
+					VolumeMounts: []corev1.VolumeMount{
+						{
+							Name:      "json-config",
+							MountPath: "/data",
+						},
+					},
+				},
+			},
 		},
 	}
+	// Rolling the pods restores the data they serve to the configuration
+	if reset := resetAt(jsonServer); reset != "" {
+		template.Annotations[resetAtAnnotation] = reset
+	}
+	return template
+}
 
-	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
-		if err := controllerutil.SetControllerReference(jsonServer, service, r.Scheme); err != nil {
-			return err
-		}
-
-		labels := getResourceLabels(jsonServer)
+// recordWorkloadStatus reports the replicas, selector and configuration served by the workload.
+// The replicas are those of the workload, set by the HorizontalPodAutoscaler of autoscaled JsonServers.
+func recordWorkloadStatus(jsonServer *examplev1.JsonServer, replicas int32, hash string) error {
+	jsonServer.Status.Replicas = replicas
+	jsonServer.Status.ConfigHash = hash
 
-		service.Spec.Selector = labels
-		service.Spec.Ports = []corev1.ServicePort{
-			{
-				Port:       3000,
-				TargetPort: intstr.FromInt(3000),
-				Protocol:   corev1.ProtocolTCP,
-			},
-		}
-
-		return nil
+	labels := getResourceLabels(jsonServer)
+	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels: labels,
 	})
-
 	if err != nil {
-		log.Error(err, "Failed to create or update Service")
 		return err
 	}
-
-	log.Info("Service reconciled", "operation", op)
+	jsonServer.Status.Selector = selector.String()
 	return nil
 }
+
+// deleteOwned deletes the named object of the given type, if the JsonServer controls it
+func (r *JsonServerReconciler) deleteOwned(ctx context.Context, jsonServer *examplev1.JsonServer, name string, obj client.Object) error {
+	if err := r.Get(ctx, client.ObjectKey{Namespace: jsonServer.Namespace, Name: name}, obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(obj, jsonServer) {
+		return nil
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, obj))
+}
@@ -18,16 +18,35 @@ package controller
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/fixture"
+	"jsonserver-operator/internal/payload"
 )
 
 var _ = Describe("JsonServer Controller", func() {
@@ -51,7 +70,10 @@ var _ = Describe("JsonServer Controller", func() {
 						Name:      resourceName,
 						Namespace: "default",
 					},
-					// TODO(user): Specify other spec details if needed.
+					Spec: examplev1.JsonServerSpec{
+						Replicas:   1,
+						JsonConfig: `{"people": [{"id": 1, "name": "Person A"}]}`,
+					},
 				}
 				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
 			}
@@ -69,8 +91,9 @@ var _ = Describe("JsonServer Controller", func() {
 		It("should successfully reconcile the resource", func() {
 			By("Reconciling the created resource")
 			controllerReconciler := &JsonServerReconciler{
-				Client: k8sClient,
-				Scheme: k8sClient.Scheme(),
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
 			}
 
 			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -80,5 +103,1362 @@ var _ = Describe("JsonServer Controller", func() {
 			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
+
+		It("should report conditions derived from the Deployment", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			By("Reconciling the created resource")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.ObservedGeneration).To(Equal(jsonserver.Generation))
+			Expect(meta.IsStatusConditionTrue(jsonserver.Status.Conditions, examplev1.ConditionConfigValid)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(jsonserver.Status.Conditions, examplev1.ConditionResourcesReconciled)).To(BeTrue())
+
+			By("Checking the JsonServer is not Ready while no pod is running")
+			// envtest runs no Deployment controller, so no replica ever becomes ready
+			Expect(jsonserver.Status.ReadyReplicas).To(BeZero())
+			Expect(meta.IsStatusConditionFalse(jsonserver.Status.Conditions, examplev1.ConditionReady)).To(BeTrue())
+			Expect(meta.IsStatusConditionFalse(jsonserver.Status.Conditions, examplev1.ConditionAvailable)).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(jsonserver.Status.Conditions, examplev1.ConditionProgressing)).To(BeTrue())
+
+			By("Breaking the jsonConfig")
+			jsonserver.Spec.JsonConfig = `{"people": [`
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.ObservedGeneration).To(Equal(jsonserver.Generation))
+			condition := meta.FindStatusCondition(jsonserver.Status.Conditions, examplev1.ConditionConfigValid)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(reasonInvalidJSON))
+			Expect(meta.IsStatusConditionFalse(jsonserver.Status.Conditions, examplev1.ConditionReady)).To(BeTrue())
+		})
+
+		It("should roll the pods when the jsonConfig changes", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			By("Reconciling the created resource")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			firstHash := deployment.Spec.Template.Annotations[configHashAnnotation]
+			Expect(firstHash).NotTo(BeEmpty())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.ConfigHash).To(Equal(firstHash))
+
+			By("Changing the jsonConfig")
+			jsonserver.Spec.JsonConfig = `{"people": [{"id": 1, "name": "Person B"}]}`
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			secondHash := deployment.Spec.Template.Annotations[configHashAnnotation]
+			Expect(secondHash).NotTo(Equal(firstHash))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.ConfigHash).To(Equal(secondHash))
+		})
+
+		It("should serve the data of the ConfigMap referenced in dataFrom", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			By("Referencing a ConfigMap that does not exist yet")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.JsonConfig = ""
+			jsonserver.Spec.DataFrom = &examplev1.DataSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "fixtures"},
+					Key:                  "db.json",
+				},
+			}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			condition := meta.FindStatusCondition(jsonserver.Status.Conditions, examplev1.ConditionConfigValid)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(reasonSourceNotFound))
+
+			By("Creating the ConfigMap")
+			fixtures := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "fixtures", Namespace: "default"},
+				Data:       map[string]string{"db.json": `{"people": [{"id": 2, "name": "Person C"}]}`},
+			}
+			Expect(k8sClient.Create(ctx, fixtures)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, fixtures)
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(jsonserver.Status.Conditions, examplev1.ConditionConfigValid)).To(BeTrue())
+			Expect(jsonserver.Status.Source).NotTo(BeNil())
+			Expect(jsonserver.Status.Source.Kind).To(Equal(examplev1.SourceKindConfigMap))
+			Expect(jsonserver.Status.Source.Revision).To(Equal(fixtures.ResourceVersion))
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data["db.json"]).To(Equal(fixtures.Data["db.json"]))
+		})
+
+		It("should keep the data read from a Secret out of the ConfigMap", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			fixtures := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "secret-fixtures", Namespace: "default"},
+				Data:       map[string][]byte{"db.json": []byte(`{"tokens": [{"id": 1, "value": "s3cr3t"}]}`)},
+			}
+			Expect(k8sClient.Create(ctx, fixtures)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, fixtures)
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.JsonConfig = ""
+			jsonserver.Spec.DataFrom = &examplev1.DataSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: fixtures.Name},
+					Key:                  "db.json",
+				},
+			}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Checking the document is kept in the data Secret mounted by the pods")
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).NotTo(HaveKey("db.json"))
+			Expect(configMap.Annotations).To(HaveKeyWithValue(dataSecretAnnotation, resourceName+"-data"))
+
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-data", Namespace: "default"}, secret)).To(Succeed())
+			Expect(secret.Data["db.json"]).To(Equal(fixtures.Data["db.json"]))
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.Volumes[0].Secret).NotTo(BeNil())
+			Expect(deployment.Spec.Template.Spec.Volumes[0].Secret.SecretName).To(Equal(secret.Name))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.CurrentRevision).To(BeEmpty())
+
+			By("Serving inline data again")
+			jsonserver.Spec.JsonConfig = `{"people": []}`
+			jsonserver.Spec.DataFrom = nil
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKey("db.json"))
+			Expect(configMap.Annotations).NotTo(HaveKey(dataSecretAnnotation))
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-data", Namespace: "default"}, secret)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should download the data of the URL referenced in dataFrom", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("ETag", `"v1"`)
+				_, _ = w.Write([]byte(`{"people": [{"id": 3, "name": "Person D"}]}`))
+			}))
+			DeferCleanup(server.Close)
+
+			controllerReconciler := &JsonServerReconciler{
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				Recorder:   record.NewFakeRecorder(10),
+				HTTPClient: server.Client(),
+			}
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.JsonConfig = ""
+			jsonserver.Spec.DataFrom = &examplev1.DataSource{
+				HTTP: &examplev1.HTTPSource{
+					URL:             server.URL + "/db.json",
+					RefreshInterval: &metav1.Duration{Duration: time.Minute},
+				},
+			}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Minute))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.Source).NotTo(BeNil())
+			Expect(jsonserver.Status.Source.Kind).To(Equal(examplev1.SourceKindHTTP))
+			Expect(jsonserver.Status.Source.Revision).To(Equal(`"v1"`))
+			Expect(jsonserver.Status.Source.LastSyncTime).NotTo(BeNil())
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data["db.json"]).To(ContainSubstring("Person D"))
+		})
+
+		It("should run persistent JsonServers as a StatefulSet seeding its volumes", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			By("Reconciling the created resource")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})).To(Succeed())
+
+			By("Enabling persistence")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Persistence = &examplev1.PersistenceSpec{ReseedPolicy: examplev1.ReseedOnConfigChange}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			statefulSet := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, statefulSet)).To(Succeed())
+			Expect(statefulSet.Spec.VolumeClaimTemplates).To(HaveLen(1))
+			Expect(statefulSet.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests.Storage().String()).To(Equal("1Gi"))
+			Expect(statefulSet.Spec.Template.Spec.InitContainers).To(HaveLen(1))
+			Expect(statefulSet.Spec.Template.Spec.InitContainers[0].Env).To(ContainElement(
+				corev1.EnvVar{Name: "RESEED_POLICY", Value: examplev1.ReseedOnConfigChange}))
+			Expect(statefulSet.Spec.Template.Annotations).To(HaveKey(configHashAnnotation))
+
+			By("Checking the Deployment was removed")
+			err = k8sClient.Get(ctx, typeNamespacedName, &appsv1.Deployment{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("should recreate the StatefulSet when its volume claim templates change", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			// The garbage collector, which does not run in envtest, removes the orphan finalizer
+			// once it released the pods
+			recreate := func() {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+				statefulSet := &appsv1.StatefulSet{}
+				Expect(k8sClient.Get(ctx, typeNamespacedName, statefulSet)).To(Succeed())
+				Expect(statefulSet.DeletionTimestamp).NotTo(BeNil())
+				Expect(statefulSet.Finalizers).To(ContainElement(metav1.FinalizerOrphanDependents))
+				statefulSet.Finalizers = nil
+				Expect(k8sClient.Update(ctx, statefulSet)).To(Succeed())
+
+				_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			dataVolume := func(statefulSet *appsv1.StatefulSet) *corev1.Volume {
+				for i, volume := range statefulSet.Spec.Template.Spec.Volumes {
+					if volume.Name == dataVolumeName {
+						return &statefulSet.Spec.Template.Spec.Volumes[i]
+					}
+				}
+				return nil
+			}
+
+			By("Running a single writer without persistence")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Replicas = 2
+			jsonserver.Spec.Consistency = examplev1.ConsistencySingleWriter
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			statefulSet := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, statefulSet)).To(Succeed())
+			Expect(statefulSet.Spec.VolumeClaimTemplates).To(BeEmpty())
+			Expect(dataVolume(statefulSet)).NotTo(BeNil())
+
+			By("Enabling persistence")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Persistence = &examplev1.PersistenceSpec{Size: resource.MustParse("2Gi")}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+			recreate()
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, statefulSet)).To(Succeed())
+			Expect(statefulSet.DeletionTimestamp).To(BeNil())
+			Expect(statefulSet.Spec.VolumeClaimTemplates).To(HaveLen(1))
+			Expect(statefulSet.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests.Storage().String()).To(Equal("2Gi"))
+			Expect(dataVolume(statefulSet)).To(BeNil())
+
+			By("Keeping the StatefulSet while the claim templates do not change")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, statefulSet)).To(Succeed())
+			Expect(statefulSet.DeletionTimestamp).To(BeNil())
+
+			By("Disabling persistence")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Persistence = nil
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+			recreate()
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, statefulSet)).To(Succeed())
+			Expect(statefulSet.Spec.VolumeClaimTemplates).To(BeEmpty())
+			Expect(dataVolume(statefulSet)).NotTo(BeNil())
+		})
+
+		It("should route the writes of single-writer JsonServers to the first replica", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Replicas = 3
+			jsonserver.Spec.Consistency = examplev1.ConsistencySingleWriter
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Checking the replicas run as a StatefulSet syncing from the writer")
+			statefulSet := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, statefulSet)).To(Succeed())
+			Expect(statefulSet.Spec.Template.Spec.Containers).To(HaveLen(2))
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].Args).To(ContainElement("--watch"))
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].Env).To(ContainElement(
+				corev1.EnvVar{Name: "WRITER_POD", Value: resourceName + "-0"}))
+			Expect(statefulSet.Spec.Template.Spec.Containers[1].Name).To(Equal("sync"))
+
+			By("Checking the read replicas load the middleware rejecting writes with the entrypoint of the image")
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].Command).To(BeEmpty())
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].Args).To(HaveExactElements(
+				"--watch", "--middlewares", "/etc/json-server/read-replica.js", "/data/db.json"))
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(
+				corev1.VolumeMount{Name: serverConfigVolumeName, MountPath: serverConfigDir, ReadOnly: true}))
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue(readReplicaKey, readReplicaMiddleware))
+
+			By("Checking the write Service selects the first replica")
+			writeService := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-write", Namespace: "default"}, writeService)).To(Succeed())
+			Expect(writeService.Spec.Selector).To(HaveKeyWithValue(appsv1.StatefulSetPodNameLabel, resourceName+"-0"))
+
+			By("Switching to sticky sessions")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Consistency = examplev1.ConsistencySticky
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			service := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, service)).To(Succeed())
+			Expect(service.Spec.SessionAffinity).To(Equal(corev1.ServiceAffinityClientIP))
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-write", Namespace: "default"}, writeService)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).NotTo(HaveKey(readReplicaKey))
+		})
+
+		It("should expose the JsonServer through an Ingress and report its URL", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Expose = &examplev1.ExposeSpec{
+				Type: examplev1.ExposeIngress,
+				Host: "people.example.com",
+				Path: "/api",
+				TLS:  &examplev1.ExposeTLS{ClusterIssuer: "letsencrypt"},
+			}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			ingress := &networkingv1.Ingress{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, ingress)).To(Succeed())
+			Expect(ingress.Annotations).To(HaveKeyWithValue(certManagerClusterIssuerAnnotation, "letsencrypt"))
+			Expect(ingress.Spec.TLS).To(ConsistOf(networkingv1.IngressTLS{Hosts: []string{"people.example.com"}, SecretName: resourceName + "-tls"}))
+			Expect(ingress.Spec.Rules).To(HaveLen(1))
+			Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Path).To(Equal("/api"))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.URL).To(Equal("https://people.example.com/api"))
+
+			By("Removing the expose section")
+			jsonserver.Spec.Expose = nil
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			err = k8sClient.Get(ctx, typeNamespacedName, ingress)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.URL).To(BeEmpty())
+		})
+
+		It("should merge the podTemplate override onto the managed pod template", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.PodTemplate = &examplev1.PodTemplateOverride{
+				Labels: map[string]string{"team": "frontend", "app": "overridden"},
+				Resources: &corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m")},
+				},
+				SecurityContext: &corev1.SecurityContext{
+					RunAsNonRoot:             ptr.To(true),
+					AllowPrivilegeEscalation: ptr.To(false),
+				},
+				NodeSelector:       map[string]string{"kubernetes.io/os": "linux"},
+				ServiceAccountName: "json-server",
+			}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			template := deployment.Spec.Template
+			Expect(template.Labels).To(HaveKeyWithValue("team", "frontend"))
+			Expect(template.Labels).To(HaveKeyWithValue("app", resourceName))
+			Expect(template.Annotations).To(HaveKey(configHashAnnotation))
+			Expect(template.Spec.Containers[0].Resources.Requests.Cpu().String()).To(Equal("50m"))
+			Expect(template.Spec.Containers[0].SecurityContext.RunAsNonRoot).To(Equal(ptr.To(true)))
+			Expect(template.Spec.Containers[0].Args).To(Equal([]string{"/data/db.json"}))
+			Expect(template.Spec.NodeSelector).To(HaveKeyWithValue("kubernetes.io/os", "linux"))
+			Expect(template.Spec.ServiceAccountName).To(Equal("json-server"))
+		})
+
+		It("should shape the Service from the spec and recreate it when it turns headless", func() {
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: recorder,
+			}
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Service = &examplev1.ServiceSpec{
+				Type:                  examplev1.ServiceTypeNodePort,
+				Port:                  8080,
+				PortName:              "api",
+				AppProtocol:           ptr.To("http"),
+				Annotations:           map[string]string{"example.com/lb": "internal"},
+				ExternalTrafficPolicy: corev1.ServiceExternalTrafficPolicyLocal,
+			}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			service := &corev1.Service{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, service)).To(Succeed())
+			Expect(service.Spec.Type).To(Equal(corev1.ServiceTypeNodePort))
+			Expect(service.Spec.ExternalTrafficPolicy).To(Equal(corev1.ServiceExternalTrafficPolicyLocal))
+			Expect(service.Annotations).To(HaveKeyWithValue("example.com/lb", "internal"))
+			Expect(service.Spec.Ports).To(HaveLen(1))
+			Expect(service.Spec.Ports[0].Name).To(Equal("api"))
+			Expect(service.Spec.Ports[0].Port).To(Equal(int32(8080)))
+			Expect(service.Spec.Ports[0].AppProtocol).To(Equal(ptr.To("http")))
+			nodePort := service.Spec.Ports[0].NodePort
+			Expect(nodePort).NotTo(BeZero())
+			uid := service.UID
+
+			By("Reconciling again keeps the allocated node port")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, service)).To(Succeed())
+			Expect(service.Spec.Ports[0].NodePort).To(Equal(nodePort))
+
+			By("Switching to a headless Service")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Service = &examplev1.ServiceSpec{Type: examplev1.ServiceTypeHeadless}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, service)).To(Succeed())
+			Expect(service.UID).NotTo(Equal(uid))
+			Expect(service.Spec.ClusterIP).To(Equal(corev1.ClusterIPNone))
+			Expect(service.Annotations).NotTo(HaveKey("example.com/lb"))
+			Expect(recorder.Events).To(Receive(ContainSubstring("ServiceRecreated")))
+		})
+
+		It("should render the server section into routes and json-server arguments", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Server = &examplev1.ServerSpec{
+				Routes: []examplev1.Route{
+					{Pattern: "/:resource/:id/show", Target: "/:resource/:id"},
+					{Pattern: "/blog/*", Target: "/posts/$1"},
+				},
+				BasePath:         "/api",
+				IDField:          "uuid",
+				ForeignKeySuffix: "_id",
+				Delay:            &metav1.Duration{Duration: 500 * time.Millisecond},
+				ReadOnly:         true,
+				Static:           &corev1.LocalObjectReference{Name: "assets"},
+			}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Checking the routes are rendered in the order of the spec, the base path first")
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKeyWithValue(routesKey,
+				"{\n  \"/api/*\": \"/$1\",\n  \"/:resource/:id/show\": \"/:resource/:id\",\n  \"/blog/*\": \"/posts/$1\"\n}\n"))
+
+			By("Checking the json-server arguments")
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Args).To(Equal([]string{
+				"--routes", "/etc/json-server/routes.json",
+				"--id", "uuid",
+				"--foreign-key-suffix", "_id",
+				"--delay", "500",
+				"--read-only",
+				"--static", "/static",
+				"/data/db.json",
+			}))
+			Expect(deployment.Spec.Template.Annotations).To(HaveKey(routesHashAnnotation))
+			Expect(deployment.Spec.Template.Spec.Volumes).To(ContainElement(HaveField("Name", staticVolumeName)))
+
+			By("Removing the routes")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Server = &examplev1.ServerSpec{ReadOnly: true}
+			jsonserver.Spec.Consistency = examplev1.ConsistencyReadOnly
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).NotTo(HaveKey(routesKey))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Args).To(Equal([]string{"--read-only", "/data/db.json"}))
+		})
+
+		It("should keep configuration revisions and roll back to one of them", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			By("Reconciling the created resource")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			first := jsonserver.Status.CurrentRevision
+			Expect(first).NotTo(BeEmpty())
+
+			By("Overwriting the jsonConfig")
+			jsonserver.Spec.JsonConfig = `{"people": []}`
+			jsonserver.Spec.RevisionHistoryLimit = ptr.To(int32(2))
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.CurrentRevision).NotTo(Equal(first))
+			Expect(jsonserver.Status.Revisions).To(HaveLen(2))
+			Expect(jsonserver.Status.Revisions[0].Hash).To(Equal(jsonserver.Status.CurrentRevision))
+			Expect(jsonserver.Status.Revisions[1].Hash).To(Equal(first))
+			Expect(jsonserver.Status.Revisions[0].Author).NotTo(BeEmpty())
+
+			revision := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-" + first, Namespace: "default"}, revision)).To(Succeed())
+			Expect(revision.Immutable).To(Equal(ptr.To(true)))
+
+			By("Rolling back to the first revision")
+			jsonserver.Spec.RollbackTo = first
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data["db.json"]).To(Equal(revision.Data["db.json"]))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.CurrentRevision).To(Equal(first))
+			Expect(jsonserver.Status.Source.Kind).To(Equal(examplev1.SourceKindRevision))
+			Expect(jsonserver.Status.Revisions[0].Hash).To(Equal(first))
+
+			By("Rolling back to a revision that is not kept")
+			jsonserver.Spec.RollbackTo = "0123456789abcdef"
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			condition := meta.FindStatusCondition(jsonserver.Status.Conditions, examplev1.ConditionConfigValid)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(reasonSourceNotFound))
+		})
+
+		It("should run the image of the spec, the operator default otherwise", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				Recorder:     record.NewFakeRecorder(10),
+				DefaultImage: "registry.example.com/json-server:0.17.4",
+			}
+
+			By("Reconciling the created resource")
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("registry.example.com/json-server:0.17.4"))
+
+			By("Keeping the pod template once the digest is resolved")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Status.ImageDigest = "sha256:4567456745674567456745674567456745674567456745674567456745674567"
+			Expect(k8sClient.Status().Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal("registry.example.com/json-server:0.17.4"))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.ImageDigest).To(Equal("sha256:4567456745674567456745674567456745674567456745674567456745674567"))
+
+			By("Pinning the image of the JsonServer")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Image = "registry.example.com/json-server@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.Containers[0].Image).To(Equal(jsonserver.Spec.Image))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.Image).To(Equal(jsonserver.Spec.Image))
+			// envtest runs no pod, the digest is only known once a pod pulled the image
+			Expect(jsonserver.Status.ImageDigest).To(BeEmpty())
+		})
+
+		It("should reset the data on request and on schedule by rolling the pods", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			By("Scheduling daily resets")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.ResetSchedule = "@daily"
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", time.Until(nextMidnight()), time.Minute))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.NextResetTime).NotTo(BeNil())
+			Expect(jsonserver.Status.NextResetTime.Time).To(BeTemporally("==", nextMidnight()))
+			Expect(jsonserver.Status.LastResetTime).To(BeNil())
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Annotations).NotTo(HaveKey(resetAtAnnotation))
+
+			By("Requesting a reset")
+			jsonserver.Annotations = map[string]string{examplev1.ResetRequestedAtAnnotation: "2025-01-01T10:00:00Z"}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.LastResetRequest).To(Equal("2025-01-01T10:00:00Z"))
+			Expect(jsonserver.Status.LastResetTime).NotTo(BeNil())
+			requested := *jsonserver.Status.LastResetTime
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(resetAtAnnotation, requested.UTC().Format(time.RFC3339)))
+
+			By("Handling the request once")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.LastResetTime.Equal(&requested)).To(BeTrue())
+
+			By("Reaching the scheduled reset")
+			due := metav1.NewTime(time.Now().Add(-time.Second))
+			jsonserver.Status.NextResetTime = &due
+			jsonserver.Status.LastResetTime = &metav1.Time{Time: requested.Add(-time.Hour)}
+			Expect(k8sClient.Status().Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.LastResetTime.Time).To(BeTemporally(">=", requested.Time))
+			Expect(jsonserver.Status.NextResetTime.Time).To(BeTemporally("==", nextMidnight()))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Annotations).To(HaveKeyWithValue(resetAtAnnotation,
+				jsonserver.Status.LastResetTime.UTC().Format(time.RFC3339)))
+		})
+
+		It("should delete expired JsonServers after capturing their data", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			By("Reporting the expiration time")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.TTLSecondsAfterCreation = ptr.To(int32(3600))
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.ExpirationTime).NotTo(BeNil())
+			Expect(jsonserver.Status.ExpirationTime.Time).To(BeTemporally("==", jsonserver.CreationTimestamp.Add(time.Hour)))
+
+			By("Expiring a JsonServer that snapshots its data")
+			expiring := &examplev1.JsonServer{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-expiring", Namespace: "default"},
+				Spec: examplev1.JsonServerSpec{
+					Replicas:                1,
+					JsonConfig:              `{"people": []}`,
+					TTLSecondsAfterCreation: ptr.To(int32(0)),
+					SnapshotOnExpiry:        true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, expiring)).To(Succeed())
+			expiringName := types.NamespacedName{Name: expiring.Name, Namespace: "default"}
+
+			result, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: expiringName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(expirySnapshotPollInterval))
+
+			snapshot := &examplev1.JsonServerSnapshot{}
+			snapshotName := types.NamespacedName{Name: expirySnapshotName(expiring), Namespace: "default"}
+			Expect(k8sClient.Get(ctx, snapshotName, snapshot)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, snapshot)
+			Expect(snapshot.Spec.JsonServerName).To(Equal(expiring.Name))
+			Expect(k8sClient.Get(ctx, expiringName, expiring)).To(Succeed())
+
+			By("Deleting the JsonServer once the snapshot completed")
+			snapshot.Status.Phase = examplev1.SnapshotCompleted
+			Expect(k8sClient.Status().Update(ctx, snapshot)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: expiringName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, expiringName, expiring))).To(BeTrue())
+		})
+
+		It("should scale idle JsonServers back up to capture their data before they expire", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				Recorder:       record.NewFakeRecorder(10),
+				ActivatorImage: "chickenbeef/jsonserver-operator:latest",
+			}
+
+			By("Scaling an idle JsonServer to zero")
+			idle := &examplev1.JsonServer{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-idle-expiring", Namespace: "default"},
+				Spec: examplev1.JsonServerSpec{
+					Replicas:   1,
+					JsonConfig: `{"people": []}`,
+					Idle:       &examplev1.IdleSpec{ScaleToZeroAfter: metav1.Duration{Duration: time.Second}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, idle)).To(Succeed())
+			DeferCleanup(func() {
+				if err := k8sClient.Delete(ctx, idle); !errors.IsNotFound(err) {
+					Expect(err).NotTo(HaveOccurred())
+				}
+			})
+			idleName := types.NamespacedName{Name: idle.Name, Namespace: "default"}
+			Eventually(func(g Gomega) {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: idleName})
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(k8sClient.Get(ctx, idleName, idle)).To(Succeed())
+				g.Expect(idle.Status.IdleSince).NotTo(BeNil())
+			}).WithTimeout(10 * time.Second).Should(Succeed())
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, idleName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(BeZero())
+
+			By("Scaling it back up when it expires, until its data is captured")
+			idle.Spec.TTLSecondsAfterCreation = ptr.To(int32(0))
+			idle.Spec.SnapshotOnExpiry = true
+			Expect(k8sClient.Update(ctx, idle)).To(Succeed())
+
+			for range 2 {
+				result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: idleName})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(Equal(expirySnapshotPollInterval))
+				Expect(k8sClient.Get(ctx, idleName, idle)).To(Succeed())
+				Expect(idle.Status.IdleSince).To(BeNil())
+				Expect(k8sClient.Get(ctx, idleName, deployment)).To(Succeed())
+				Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
+			}
+
+			snapshot := &examplev1.JsonServerSnapshot{}
+			snapshotName := types.NamespacedName{Name: expirySnapshotName(idle), Namespace: "default"}
+			Expect(k8sClient.Get(ctx, snapshotName, snapshot)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, snapshot)
+
+			By("Deleting the JsonServer once the snapshot completed")
+			snapshot.Status.Phase = examplev1.SnapshotCompleted
+			Expect(k8sClient.Status().Update(ctx, snapshot)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: idleName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, idleName, idle))).To(BeTrue())
+		})
+
+		It("should leave the replicas to the HorizontalPodAutoscaler and protect the pods with a PodDisruptionBudget", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			By("Creating the HorizontalPodAutoscaler and the PodDisruptionBudget")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Autoscaling = &examplev1.AutoscalingSpec{MinReplicas: ptr.To(int32(2)), MaxReplicas: 5}
+			jsonserver.Spec.DisruptionBudget = &examplev1.DisruptionBudgetSpec{MinAvailable: ptr.To(intstr.FromInt32(1))}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, hpa)).To(Succeed())
+			Expect(hpa.Spec.ScaleTargetRef).To(Equal(autoscalingv2.CrossVersionObjectReference{
+				APIVersion: "apps/v1", Kind: "Deployment", Name: resourceName}))
+			Expect(*hpa.Spec.MinReplicas).To(Equal(int32(2)))
+			Expect(hpa.Spec.MaxReplicas).To(Equal(int32(5)))
+			Expect(hpa.Spec.Metrics).To(ConsistOf(utilizationMetric(corev1.ResourceCPU, defaultTargetCPUUtilization)))
+
+			pdb := &policyv1.PodDisruptionBudget{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, pdb)).To(Succeed())
+			Expect(pdb.Spec.MinAvailable).To(Equal(ptr.To(intstr.FromInt32(1))))
+			Expect(pdb.Spec.Selector.MatchLabels).To(Equal(getResourceLabels(jsonserver)))
+
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
+
+			By("Keeping and reporting the replicas set by the HorizontalPodAutoscaler")
+			deployment.Spec.Replicas = ptr.To(int32(4))
+			Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(4)))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.Replicas).To(Equal(int32(4)))
+
+			By("Going back to spec.replicas without autoscaling")
+			jsonserver.Spec.Autoscaling = nil
+			jsonserver.Spec.DisruptionBudget = nil
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(1)))
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, hpa))).To(BeTrue())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, pdb))).To(BeTrue())
+		})
+
+		It("should restrict the clients of the pods with a NetworkPolicy", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:            k8sClient,
+				Scheme:            k8sClient.Scheme(),
+				Recorder:          record.NewFakeRecorder(10),
+				OperatorNamespace: "jsonserver-operator-system",
+			}
+
+			By("Letting in the listed peers, the operator and the pods of the JsonServer only")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			frontend := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "frontend"}}
+			jsonserver.Spec.Access = &examplev1.AccessSpec{
+				From:        []examplev1.AccessPeer{{PodSelector: frontend}},
+				DefaultDeny: true,
+			}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			policy := &networkingv1.NetworkPolicy{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, policy)).To(Succeed())
+			Expect(policy.Spec.PodSelector.MatchLabels).To(Equal(getResourceLabels(jsonserver)))
+			Expect(policy.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress))
+			Expect(policy.Spec.Ingress).To(HaveLen(1))
+			Expect(policy.Spec.Ingress[0].Ports).To(ConsistOf(networkingv1.NetworkPolicyPort{
+				Protocol: ptr.To(corev1.ProtocolTCP), Port: ptr.To(intstr.FromInt32(3000))}))
+			Expect(policy.Spec.Ingress[0].From).To(ContainElements(
+				networkingv1.NetworkPolicyPeer{PodSelector: frontend},
+				networkingv1.NetworkPolicyPeer{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{corev1.LabelMetadataName: "jsonserver-operator-system"}},
+					PodSelector:       &metav1.LabelSelector{MatchLabels: operatorPodLabels},
+				},
+			))
+			Expect(policy.Spec.Ingress[0].From).NotTo(ContainElement(networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{}}))
+
+			By("Letting in the namespace of the JsonServer without defaultDeny")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Access.DefaultDeny = false
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, policy)).To(Succeed())
+			Expect(policy.Spec.Ingress[0].From).To(ContainElement(networkingv1.NetworkPolicyPeer{PodSelector: &metav1.LabelSelector{}}))
+
+			By("Removing the NetworkPolicy with the access section")
+			jsonserver.Spec.Access = nil
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, typeNamespacedName, policy))).To(BeTrue())
+		})
+
+		It("should scale idle JsonServers to zero behind their activator and back up on request", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:         k8sClient,
+				Scheme:         k8sClient.Scheme(),
+				Recorder:       record.NewFakeRecorder(10),
+				ActivatorImage: "chickenbeef/jsonserver-operator:latest",
+			}
+
+			By("Running the activator next to the json-server pods")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Replicas = 2
+			jsonserver.Spec.Idle = &examplev1.IdleSpec{ScaleToZeroAfter: metav1.Duration{Duration: time.Second}}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			activator := &appsv1.Deployment{}
+			activatorKey := types.NamespacedName{Name: resourceName + "-activator", Namespace: "default"}
+			service := &corev1.Service{}
+			Eventually(func(g Gomega) {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				g.Expect(err).NotTo(HaveOccurred())
+				g.Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+				g.Expect(jsonserver.Status.IdleSince).NotTo(BeNil())
+			}).WithTimeout(10 * time.Second).Should(Succeed())
+
+			Expect(k8sClient.Get(ctx, activatorKey, activator)).To(Succeed())
+			Expect(activator.Spec.Template.Spec.ServiceAccountName).To(Equal(activatorKey.Name))
+			Expect(activator.Spec.Template.Spec.Containers[0].Image).To(Equal("chickenbeef/jsonserver-operator:latest"))
+			Expect(activator.Spec.Template.Spec.Containers[0].Args).To(ContainElement("--jsonserver=" + resourceName))
+			Expect(k8sClient.Get(ctx, activatorKey, &rbacv1.RoleBinding{})).To(Succeed())
+
+			By("Scaling the idle JsonServer to zero, keeping the replicas of the spec")
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(BeZero())
+			Expect(jsonserver.Spec.Replicas).To(Equal(int32(2)))
+			Expect(jsonserver.Status.Replicas).To(BeZero())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, service)).To(Succeed())
+			Expect(service.Spec.Selector).To(Equal(activatorLabels(jsonserver)))
+
+			By("Scaling back up once the activator received a request")
+			time.Sleep(time.Second)
+			jsonserver.Annotations = map[string]string{examplev1.ActivatedAtAnnotation: time.Now().UTC().Format(time.RFC3339)}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.IdleSince).To(BeNil())
+			Expect(jsonserver.Status.Replicas).To(Equal(int32(2)))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(*deployment.Spec.Replicas).To(Equal(int32(2)))
+
+			By("Routing to the json-server pods once they are ready")
+			deployment.Status.Replicas = 2
+			deployment.Status.ReadyReplicas = 2
+			Expect(k8sClient.Status().Update(ctx, deployment)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, service)).To(Succeed())
+			Expect(service.Spec.Selector).To(Equal(getResourceLabels(jsonserver)))
+		})
+
+		It("should compress large documents into chunk ConfigMaps reassembled by an init container", func() {
+			random := make([]byte, 1152*1024)
+			_, _ = rand.NewChaCha8([32]byte{}).Read(random)
+			document := `{"blobs": [{"id": 1, "data": "` + base64.StdEncoding.EncodeToString(random) + `"}]}`
+			downloads := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				downloads++
+				_, _ = w.Write([]byte(document))
+			}))
+			DeferCleanup(server.Close)
+
+			recorder := record.NewFakeRecorder(10)
+			controllerReconciler := &JsonServerReconciler{
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				Recorder:   recorder,
+				HTTPClient: server.Client(),
+			}
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.JsonConfig = ""
+			jsonserver.Spec.DataFrom = &examplev1.DataSource{
+				HTTP: &examplev1.HTTPSource{URL: server.URL + "/db.json", RefreshInterval: &metav1.Duration{Duration: time.Hour}},
+			}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Keeping the first chunk and the checksum in the ConfigMap of the JsonServer")
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).NotTo(HaveKey("db.json"))
+			Expect(configMap.Data).To(HaveKeyWithValue(checksumKey, checksum(document)))
+			Expect(configMap.Annotations).To(HaveKeyWithValue(chunksAnnotation, "2"))
+			compressed := configMap.BinaryData[chunkKey(0)]
+
+			chunk := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-chunk-1", Namespace: "default"}, chunk)).To(Succeed())
+			Expect(chunk.BinaryData).To(HaveKey(chunkKey(1)))
+			Expect(payload.Decompress(append(compressed, chunk.BinaryData[chunkKey(1)]...))).To(Equal(document))
+
+			By("Reassembling the chunks into an emptyDir before json-server starts")
+			deployment := &appsv1.Deployment{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			podSpec := deployment.Spec.Template.Spec
+			Expect(podSpec.InitContainers).To(HaveLen(1))
+			Expect(podSpec.InitContainers[0].Name).To(Equal("unpack"))
+			Expect(podSpec.Volumes[0].EmptyDir).NotTo(BeNil())
+			Expect(podSpec.Volumes[1].Projected.Sources).To(HaveLen(2))
+			Expect(podSpec.Volumes[1].Projected.Sources[1].ConfigMap.Name).To(Equal(resourceName + "-chunk-1"))
+
+			By("Reporting once that the document is too large to be kept as a revision")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.CurrentRevision).To(BeEmpty())
+			unrecorded := jsonserver.Status.UnrecordedRevision
+			Expect(unrecorded).NotTo(BeEmpty())
+			Expect(recorder.Events).To(Receive(ContainSubstring("RevisionNotRecorded")))
+
+			By("Reusing the chunks while the download is fresh")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(downloads).To(Equal(1))
+			Expect(recorder.Events).NotTo(Receive(ContainSubstring("RevisionNotRecorded")))
+
+			By("Reporting another document too large to be kept as a revision")
+			_, _ = rand.NewChaCha8([32]byte{1}).Read(random)
+			document = `{"blobs": [{"id": 1, "data": "` + base64.StdEncoding.EncodeToString(random) + `"}]}`
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Status.Source.LastSyncTime = &metav1.Time{Time: time.Now().Add(-2 * time.Hour)}
+			Expect(k8sClient.Status().Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(downloads).To(Equal(2))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.UnrecordedRevision).NotTo(BeEmpty())
+			Expect(jsonserver.Status.UnrecordedRevision).NotTo(Equal(unrecorded))
+			Expect(recorder.Events).To(Receive(ContainSubstring("RevisionNotRecorded")))
+
+			By("Removing the chunks once the document fits in the ConfigMap again")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.DataFrom = nil
+			jsonserver.Spec.JsonConfig = `{"people": [{"id": 1, "name": "Person A"}]}`
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(HaveKey("db.json"))
+			Expect(configMap.BinaryData).To(BeEmpty())
+			err = k8sClient.Get(ctx, types.NamespacedName{Name: resourceName + "-chunk-1", Namespace: "default"}, chunk)
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, deployment)).To(Succeed())
+			Expect(deployment.Spec.Template.Spec.InitContainers).To(BeEmpty())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.CurrentRevision).NotTo(BeEmpty())
+			Expect(jsonserver.Status.UnrecordedRevision).To(BeEmpty())
+		})
+
+		It("should convert fixtures written in other formats and add the collections of the spec", func() {
+			downloads := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				downloads++
+				_, _ = w.Write([]byte("{\n  // Written by hand\n  posts: [{id: 1, title: 'Hello'},],\n}\n"))
+			}))
+			DeferCleanup(server.Close)
+
+			controllerReconciler := &JsonServerReconciler{
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				Recorder:   record.NewFakeRecorder(10),
+				HTTPClient: server.Client(),
+			}
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.JsonConfig = ""
+			jsonserver.Spec.Format = examplev1.FormatJSON5
+			jsonserver.Spec.DataFrom = &examplev1.DataSource{
+				HTTP: &examplev1.HTTPSource{URL: server.URL + "/db.json5", RefreshInterval: &metav1.Duration{Duration: time.Hour}},
+			}
+			jsonserver.Spec.Collections = map[string]examplev1.CollectionSource{
+				"users": {Format: examplev1.FormatCSV, Data: "id,name,admin\n1,Person A,true\n2,Person B,\n",
+					Columns: []examplev1.CSVColumn{{Name: "id", Type: examplev1.ColumnInteger}, {Name: "admin", Type: examplev1.ColumnBoolean}}},
+				"tags": {Format: examplev1.FormatNDJSON, Data: `{"id": 1, "label": "news"}` + "\n"},
+			}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Serving the canonical db.json")
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data["db.json"]).To(MatchJSON(`{
+				"posts": [{"id": 1, "title": "Hello"}],
+				"tags": [{"id": 1, "label": "news"}],
+				"users": [{"id": 1, "name": "Person A", "admin": true}, {"id": 2, "name": "Person B", "admin": null}]
+			}`))
+
+			By("Reusing the converted download while it is fresh")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(downloads).To(Equal(1))
+
+			By("Pointing at the line and column of conversion errors")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Collections["users"] = examplev1.CollectionSource{Format: examplev1.FormatCSV, Data: "id,name\n1,Person A\nB,Person B\n",
+				Columns: []examplev1.CSVColumn{{Name: "id", Type: examplev1.ColumnInteger}}}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(downloads).To(Equal(2))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			condition := meta.FindStatusCondition(jsonserver.Status.Conditions, examplev1.ConditionConfigValid)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(reasonConversionFailed))
+			Expect(condition.Message).To(ContainSubstring(`spec.collections[users].data: line 3, column 1: column "id": "B" is not an integer`))
+		})
+
+		It("should compose the configuration from collections read from fixtures, ConfigMaps and other JsonServers", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			jsonFixture := &examplev1.JsonFixture{
+				ObjectMeta: metav1.ObjectMeta{Name: "shared-users", Namespace: "default"},
+				Spec: examplev1.JsonFixtureSpec{Collections: map[string]examplev1.FixtureCollection{
+					"users": {Format: examplev1.FormatNDJSON, Data: `{"id": 1, "name": "Person A"}` + "\n"},
+				}},
+			}
+			Expect(k8sClient.Create(ctx, jsonFixture)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, jsonFixture)
+			orders := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: "team-orders", Namespace: "default"},
+				Data:       map[string]string{"orders.yaml": "- id: 1\n  userId: 1\n"},
+			}
+			Expect(k8sClient.Create(ctx, orders)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, orders)
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.JsonConfig = `{"posts": [{"id": 1, "title": "Hello"}]}`
+			jsonserver.Spec.Collections = map[string]examplev1.CollectionSource{
+				"users":  {FixtureRef: &examplev1.CollectionReference{Name: "shared-users"}},
+				"orders": {Format: examplev1.FormatYAML, ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "team-orders"}, Key: "orders.yaml"}},
+			}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data["db.json"]).To(MatchJSON(`{
+				"orders": [{"id": 1, "userId": 1}],
+				"posts": [{"id": 1, "title": "Hello"}],
+				"users": [{"id": 1, "name": "Person A"}]
+			}`))
+
+			By("Indexing the JsonServer by the objects its collections are read from")
+			Expect(indexDataSourceRefs(fixtureRefIndex)(jsonserver)).To(Equal([]string{"shared-users"}))
+			Expect(indexDataSourceRefs(configMapRefIndex)(jsonserver)).To(Equal([]string{"team-orders"}))
+
+			By("Reading a collection served by another JsonServer")
+			other := &examplev1.JsonServer{
+				ObjectMeta: metav1.ObjectMeta{Name: "app-mock-orders", Namespace: "default"},
+				Spec: examplev1.JsonServerSpec{Replicas: 1, Collections: map[string]examplev1.CollectionSource{
+					"customers": {JsonServerRef: &examplev1.CollectionReference{Name: resourceName, Collection: "users"}},
+				}},
+			}
+			Expect(k8sClient.Create(ctx, other)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, other)
+			otherName := types.NamespacedName{Name: other.Name, Namespace: "default"}
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: otherName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, otherName, configMap)).To(Succeed())
+			Expect(configMap.Data["db.json"]).To(MatchJSON(`{"customers": [{"id": 1, "name": "Person A"}]}`))
+			Expect(indexDataSourceRefs(jsonServerRefIndex)(other)).To(Equal([]string{resourceName}))
+
+			By("Reporting collisions with the configuration")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Collections["posts"] = examplev1.CollectionSource{FixtureRef: &examplev1.CollectionReference{Name: "shared-users", Collection: "users"}}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			condition := meta.FindStatusCondition(jsonserver.Status.Conditions, examplev1.ConditionConfigValid)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(reasonConversionFailed))
+			Expect(condition.Message).To(ContainSubstring(`collection "users" of JsonFixture "shared-users": collection "posts" is already defined by spec.jsonConfig`))
+		})
+
+		It("should render generated collections reproducibly and report their seeds", func() {
+			controllerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			seed := int64(42)
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Generate = map[string]examplev1.GeneratedCollection{
+				"teams": {Count: 2, Template: `{"id": {{ .ID }}, "name": {{ word | json }}}`},
+				"users": {Count: 5, Seed: &seed, Template: "id: {{ .ID }}\nname: {{ name }}\nteamId: {{ ref \"teams\" }}\n"},
+			}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			var db map[string][]map[string]any
+			Expect(json.Unmarshal([]byte(configMap.Data["db.json"]), &db)).To(Succeed())
+			Expect(db).To(HaveKey("people"))
+			Expect(db["teams"]).To(HaveLen(2))
+			Expect(db["users"]).To(HaveLen(5))
+			for _, user := range db["users"] {
+				Expect(user["teamId"]).To(BeElementOf(1.0, 2.0))
+			}
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.Generated).To(Equal([]examplev1.GeneratedCollectionStatus{
+				{Name: "teams", Seed: fixture.DefaultSeed("teams"), Count: 2},
+				{Name: "users", Seed: 42, Count: 5},
+			}))
+
+			By("Rendering the same records again")
+			rendered := configMap.Data["db.json"]
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data["db.json"]).To(Equal(rendered))
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			Expect(jsonserver.Status.Generated).To(HaveLen(2))
+
+			By("Reusing the rendered records while the generators are unchanged")
+			hash := composition(jsonserver.Spec.Format, jsonserver.Spec.JsonConfig, map[string]fixture.Input{}, fixture.Generators(&jsonserver.Spec))
+			Expect(configMap.Annotations).To(HaveKeyWithValue(composedAnnotation, hash))
+			composed, ok := controllerReconciler.composedDocument(ctx, jsonserver, hash)
+			Expect(ok).To(BeTrue())
+			Expect(composed).To(Equal(rendered))
+			jsonserver.Spec.Generate["teams"] = examplev1.GeneratedCollection{Count: 3, Template: jsonserver.Spec.Generate["teams"].Template}
+			Expect(composition(jsonserver.Spec.Format, jsonserver.Spec.JsonConfig, map[string]fixture.Input{}, fixture.Generators(&jsonserver.Spec))).NotTo(Equal(hash))
+
+			By("Reporting the errors of the templates")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Generate["users"] = examplev1.GeneratedCollection{Count: 1, Template: "id: {{ .ID }}\nteamId: {{ ref \"groups\" }}\n"}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			condition := meta.FindStatusCondition(jsonserver.Status.Conditions, examplev1.ConditionConfigValid)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(reasonGenerationFailed))
+			Expect(condition.Message).To(ContainSubstring(`spec.generate[users].template: line 2, column 11: record 1`))
+
+			By("Stopping the templates that loop too long")
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			jsonserver.Spec.Generate["users"] = examplev1.GeneratedCollection{Count: 10000,
+				Template: `{{ range (int 1000 1000) }}{{ range (int 1000 1000) }}{{ end }}{{ end }}{"id": {{ .ID }}}`}
+			Expect(k8sClient.Update(ctx, jsonserver)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, jsonserver)).To(Succeed())
+			condition = meta.FindStatusCondition(jsonserver.Status.Conditions, examplev1.ConditionConfigValid)
+			Expect(condition).NotTo(BeNil())
+			Expect(condition.Reason).To(Equal(reasonGenerationFailed))
+			Expect(condition.Message).To(ContainSubstring("the range actions of the records cannot loop more than 1000000 times"))
+		})
 	})
 })
+
+// nextMidnight returns the time @daily schedules run next
+func nextMidnight() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
@@ -0,0 +1,420 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/payload"
+	"jsonserver-operator/internal/validation"
+)
+
+const (
+	// snapshotJsonServerIndex indexes the JsonServerSnapshots by the JsonServer they capture
+	snapshotJsonServerIndex = ".spec.jsonServerName"
+	// snapshotPendingRetryDelay is how often a pending snapshot looks for a ready pod again
+	snapshotPendingRetryDelay = 15 * time.Second
+	// maxSnapshotSize is the largest document read from a pod. The documents are stored like
+	// the data of the JsonServers, compressed and split across ConfigMaps when they do not fit
+	// in one, larger documents would have to compress more than 16 times to fit.
+	maxSnapshotSize = 16 * payload.MaxCompressedSize
+)
+
+// Reasons of the events of the snapshots that cannot be captured
+const (
+	reasonSnapshotTooLarge        = "SnapshotTooLarge"
+	reasonSnapshotInvalidDocument = "InvalidDocument"
+	reasonSnapshotStorageConflict = "StorageConflict"
+)
+
+// defaultPodClient reads the data of the json-server pods when the reconciler has no client configured
+var defaultPodClient = &http.Client{Timeout: 30 * time.Second}
+
+// JsonServerSnapshotReconciler reconciles a JsonServerSnapshot object
+type JsonServerSnapshotReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// APIReader lists the pods of the captured JsonServer and reads the objects the snapshots
+	// are stored in without caching them. The Client is used when it is not set.
+	APIReader client.Reader
+	// HTTPClient reads the data from the json-server pods.
+	// A client with a 30 seconds timeout is used when it is not set.
+	HTTPClient *http.Client
+}
+
+// +kubebuilder:rbac:groups=example.example.com,resources=jsonserversnapshots,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=example.example.com,resources=jsonserversnapshots/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=example.example.com,resources=jsonserversnapshots/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch
+
+// Reconcile captures the data of a running pod of the JsonServer selected by the snapshot,
+// including the changes made through the json-server API, and stores it in a ConfigMap or a
+// Secret named after the snapshot. A snapshot is captured once, it stays Pending until a pod
+// of the JsonServer is ready.
+func (r *JsonServerSnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+	log.Info("Reconciling JsonServerSnapshot", "name", req.NamespacedName)
+
+	snapshot := &examplev1.JsonServerSnapshot{}
+	if err := r.Get(ctx, req.NamespacedName, snapshot); err != nil {
+		if apierrors.IsNotFound(err) {
+			log.Info("JsonServerSnapshot resource not found. Ignoring since object must be deleted")
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get JsonServerSnapshot")
+		return ctrl.Result{}, err
+	}
+
+	// Snapshots are captured once
+	if snapshot.Status.Phase == examplev1.SnapshotCompleted || snapshot.Status.Phase == examplev1.SnapshotFailed {
+		return ctrl.Result{}, nil
+	}
+	originalStatus := snapshot.Status.DeepCopy()
+
+	jsonServer := &examplev1.JsonServer{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: snapshot.Namespace, Name: snapshot.Spec.JsonServerName}, jsonServer)
+	if apierrors.IsNotFound(err) {
+		// The JsonServers are watched, creating it triggers a new capture
+		return r.pending(ctx, snapshot, originalStatus, fmt.Sprintf("JsonServer %q not found", snapshot.Spec.JsonServerName))
+	}
+	if err != nil {
+		log.Error(err, "Failed to get JsonServer")
+		return ctrl.Result{}, err
+	}
+
+	pod, err := r.capturePod(ctx, jsonServer)
+	if err != nil {
+		log.Error(err, "Failed to list json-server pods")
+		return ctrl.Result{}, err
+	}
+	if pod == nil {
+		return r.pending(ctx, snapshot, originalStatus, fmt.Sprintf("no pod of JsonServer %q is ready", jsonServer.Name))
+	}
+
+	data, err := r.download(ctx, pod)
+	if err != nil {
+		log.Error(err, "Failed to read the data of the json-server pod", "pod", pod.Name)
+		return r.pending(ctx, snapshot, originalStatus, fmt.Sprintf("failed to read the data of pod %s: %v", pod.Name, err))
+	}
+	// Checked first, larger documents are truncated
+	if len(data) > maxSnapshotSize {
+		return r.failed(ctx, snapshot, originalStatus, reasonSnapshotTooLarge,
+			fmt.Sprintf("the data of pod %s is larger than %d bytes", pod.Name, maxSnapshotSize))
+	}
+	if err := validation.ValidateJSON(string(data)); err != nil {
+		return r.failed(ctx, snapshot, originalStatus, reasonSnapshotInvalidDocument,
+			fmt.Sprintf("pod %s served an invalid document: %v", pod.Name, err))
+	}
+	chunks, err := payload.Encode(string(data))
+	if errors.Is(err, payload.ErrTooLarge) {
+		return r.failed(ctx, snapshot, originalStatus, reasonSnapshotTooLarge,
+			fmt.Sprintf("the data of pod %s cannot be stored in ConfigMaps: %v", pod.Name, err))
+	}
+	if err != nil {
+		log.Error(err, "Failed to compress the snapshot")
+		return ctrl.Result{}, err
+	}
+	// Like the data Secret of the JsonServers, which is not split
+	if snapshotStorage(snapshot) == examplev1.SnapshotStorageSecret && len(chunks) > 1 {
+		return r.failed(ctx, snapshot, originalStatus, reasonSnapshotTooLarge,
+			fmt.Sprintf("the data of pod %s is larger than %d bytes once compressed, more than a Secret can hold", pod.Name, payload.ChunkSize))
+	}
+
+	dataRef, err := r.store(ctx, snapshot, string(data), chunks)
+	var conflict *storageConflictError
+	if errors.As(err, &conflict) {
+		return r.failed(ctx, snapshot, originalStatus, reasonSnapshotStorageConflict, conflict.Error())
+	}
+	if err != nil {
+		log.Error(err, "Failed to store the snapshot")
+		return ctrl.Result{}, err
+	}
+
+	sum := sha256.Sum256(data)
+	now := metav1.Now()
+	snapshot.Status.Phase = examplev1.SnapshotCompleted
+	snapshot.Status.Message = fmt.Sprintf("Captured %d bytes from pod %s", len(data), pod.Name)
+	snapshot.Status.Pod = pod.Name
+	snapshot.Status.CaptureTime = &now
+	snapshot.Status.Size = int64(len(data))
+	snapshot.Status.Checksum = "sha256:" + hex.EncodeToString(sum[:])
+	snapshot.Status.DataRef = dataRef
+	r.Recorder.Event(snapshot, corev1.EventTypeNormal, "Captured", snapshot.Status.Message)
+	log.Info("Snapshot captured", "pod", pod.Name, "size", len(data))
+
+	return ctrl.Result{}, r.updateStatus(ctx, snapshot, originalStatus)
+}
+
+// pending reports that the snapshot cannot be captured yet and retries later
+func (r *JsonServerSnapshotReconciler) pending(ctx context.Context, snapshot *examplev1.JsonServerSnapshot,
+	originalStatus *examplev1.JsonServerSnapshotStatus, message string) (ctrl.Result, error) {
+	snapshot.Status.Phase = examplev1.SnapshotPending
+	snapshot.Status.Message = message
+	if err := r.updateStatus(ctx, snapshot, originalStatus); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: snapshotPendingRetryDelay}, nil
+}
+
+// failed reports why the snapshot cannot be captured. It is not retried, a new snapshot
+// has to be created.
+func (r *JsonServerSnapshotReconciler) failed(ctx context.Context, snapshot *examplev1.JsonServerSnapshot,
+	originalStatus *examplev1.JsonServerSnapshotStatus, reason, message string) (ctrl.Result, error) {
+	snapshot.Status.Phase = examplev1.SnapshotFailed
+	snapshot.Status.Message = message
+	r.Recorder.Event(snapshot, corev1.EventTypeWarning, reason, message)
+	return ctrl.Result{}, r.updateStatus(ctx, snapshot, originalStatus)
+}
+
+// updateStatus writes the status of the snapshot when it changed
+func (r *JsonServerSnapshotReconciler) updateStatus(ctx context.Context, snapshot *examplev1.JsonServerSnapshot,
+	originalStatus *examplev1.JsonServerSnapshotStatus) error {
+	if equality.Semantic.DeepEqual(originalStatus, &snapshot.Status) {
+		return nil
+	}
+	if err := r.Status().Update(ctx, snapshot); err != nil {
+		logf.FromContext(ctx).Error(err, "Failed to update JsonServerSnapshot status")
+		return err
+	}
+	return nil
+}
+
+// capturePod returns a ready pod of the JsonServer to capture the data from, the writer
+// of single-writer JsonServers, or nil when none is ready
+func (r *JsonServerSnapshotReconciler) capturePod(ctx context.Context, jsonServer *examplev1.JsonServer) (*corev1.Pod, error) {
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+	pods := &corev1.PodList{}
+	if err := reader.List(ctx, pods, client.InNamespace(jsonServer.Namespace),
+		client.MatchingLabels(getResourceLabels(jsonServer))); err != nil {
+		return nil, err
+	}
+
+	// Oldest first, so that the same pod is captured while the JsonServer does not change
+	slices.SortFunc(pods.Items, func(a, b corev1.Pod) int {
+		return a.CreationTimestamp.Compare(b.CreationTimestamp.Time)
+	})
+	var ready *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil || pod.Status.PodIP == "" || !podReady(pod) {
+			continue
+		}
+		if jsonServer.Spec.Consistency == examplev1.ConsistencySingleWriter {
+			// The other replicas lag behind the writer
+			if pod.Name == writerPodName(jsonServer) {
+				return pod, nil
+			}
+			continue
+		}
+		if ready == nil {
+			ready = pod
+		}
+	}
+	return ready, nil
+}
+
+// podReady reports whether the pod passes its readiness checks
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// download reads the whole database of a json-server pod from its /db endpoint
+func (r *JsonServerSnapshotReconciler) download(ctx context.Context, pod *corev1.Pod) ([]byte, error) {
+	url := fmt.Sprintf("http://%s/db", net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(containerPort)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	httpClient := r.HTTPClient
+	if httpClient == nil {
+		httpClient = defaultPodClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	// Reading one byte more than a snapshot can hold is enough to reject larger documents
+	return io.ReadAll(io.LimitReader(resp.Body, maxSnapshotSize+1))
+}
+
+// snapshotStorage returns the kind of object the snapshot is stored in
+func snapshotStorage(snapshot *examplev1.JsonServerSnapshot) string {
+	if snapshot.Spec.Storage == "" {
+		return examplev1.SnapshotStorageConfigMap
+	}
+	return snapshot.Spec.Storage
+}
+
+// storageConflictError reports an object named after the snapshot that the snapshot does
+// not own, which is never overwritten
+type storageConflictError struct {
+	kind, name string
+}
+
+func (e *storageConflictError) Error() string {
+	return fmt.Sprintf("%s %q already exists and is not owned by the snapshot, delete it or name the snapshot differently", e.kind, e.name)
+}
+
+// store writes the captured document to the ConfigMap or Secret named after the snapshot, laid
+// out like the data of the JsonServers so that they are seeded from it the same way: as is, or
+// the first chunk of the compressed document, whose other chunks are kept in ConfigMaps of
+// their own
+func (r *JsonServerSnapshotReconciler) store(ctx context.Context, snapshot *examplev1.JsonServerSnapshot,
+	data string, chunks [][]byte) (*examplev1.SnapshotDataRef, error) {
+	log := logf.FromContext(ctx)
+	kind := snapshotStorage(snapshot)
+
+	var desired client.Object
+	switch kind {
+	case examplev1.SnapshotStorageSecret:
+		secret := &corev1.Secret{Data: map[string][]byte{"db.json": []byte(data)}}
+		if len(chunks) > 0 {
+			secret.Data = map[string][]byte{chunkKey(0): chunks[0], checksumKey: []byte(checksum(data))}
+			metav1.SetMetaDataAnnotation(&secret.ObjectMeta, chunksAnnotation, strconv.Itoa(len(chunks)))
+		}
+		desired = secret
+	default:
+		configMap := &corev1.ConfigMap{Data: map[string]string{}}
+		setDocument(configMap, data, chunks)
+		desired = configMap
+	}
+
+	// The chunks first, the object named after the snapshot is read once they are all stored
+	for index := 1; index < len(chunks); index++ {
+		chunk := &corev1.ConfigMap{BinaryData: map[string][]byte{chunkKey(index): chunks[index]}}
+		chunk.SetName(chunkName(snapshot.Name, index))
+		chunk.SetLabels(map[string]string{"managed-by": "jsonserver-operator", chunkLabel: strconv.Itoa(index)})
+		if err := r.storeObject(ctx, snapshot, examplev1.SnapshotStorageConfigMap, chunk); err != nil {
+			return nil, err
+		}
+	}
+	desired.SetName(snapshot.Name)
+	desired.SetLabels(map[string]string{"managed-by": "jsonserver-operator"})
+	if err := r.storeObject(ctx, snapshot, kind, desired); err != nil {
+		return nil, err
+	}
+	log.Info("Snapshot stored", "kind", kind, "chunks", len(chunks))
+
+	return &examplev1.SnapshotDataRef{Kind: kind, Name: snapshot.Name}, nil
+}
+
+// storeObject creates, or updates, an object holding the captured document or one of its
+// chunks, owned by the snapshot
+func (r *JsonServerSnapshotReconciler) storeObject(ctx context.Context, snapshot *examplev1.JsonServerSnapshot,
+	kind string, desired client.Object) error {
+	key := client.ObjectKey{Namespace: snapshot.Namespace, Name: desired.GetName()}
+
+	// Read without the cache, which would otherwise hold every Secret of the cluster
+	reader := r.APIReader
+	if reader == nil {
+		reader = r.Client
+	}
+	obj := desired.DeepCopyObject().(client.Object)
+	err := reader.Get(ctx, key, obj)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	exists := err == nil
+	// Never overwrite nor adopt an object the snapshot did not create, such as the ConfigMap
+	// of a JsonServer named like the snapshot
+	if exists && !metav1.IsControlledBy(obj, snapshot) {
+		return &storageConflictError{kind: kind, name: key.Name}
+	}
+
+	desired.SetNamespace(key.Namespace)
+	if err := controllerutil.SetControllerReference(snapshot, desired, r.Scheme); err != nil {
+		return err
+	}
+	if exists {
+		// Stored by an earlier capture whose status was not written
+		desired.SetResourceVersion(obj.GetResourceVersion())
+		return r.Update(ctx, desired)
+	}
+	return r.Create(ctx, desired)
+}
+
+// snapshotsOf maps a JsonServer to the snapshots capturing it
+func (r *JsonServerSnapshotReconciler) snapshotsOf(ctx context.Context, obj client.Object) []reconcile.Request {
+	snapshots := &examplev1.JsonServerSnapshotList{}
+	if err := r.List(ctx, snapshots, client.InNamespace(obj.GetNamespace()),
+		client.MatchingFields{snapshotJsonServerIndex: obj.GetName()}); err != nil {
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, snapshot := range snapshots.Items {
+		if snapshot.Status.Phase != examplev1.SnapshotCompleted && snapshot.Status.Phase != examplev1.SnapshotFailed {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&snapshot)})
+		}
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *JsonServerSnapshotReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &examplev1.JsonServerSnapshot{}, snapshotJsonServerIndex,
+		func(obj client.Object) []string {
+			return []string{obj.(*examplev1.JsonServerSnapshot).Spec.JsonServerName}
+		}); err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&examplev1.JsonServerSnapshot{}).
+		Watches(&examplev1.JsonServer{}, handler.EnqueueRequestsFromMapFunc(r.snapshotsOf)).
+		Named("jsonserversnapshot").
+		Complete(r)
+}
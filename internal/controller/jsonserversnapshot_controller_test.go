@@ -0,0 +1,372 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"encoding/base64"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/payload"
+)
+
+// incompressibleDocument returns a document of about n bytes that gzip barely shrinks
+func incompressibleDocument(n int) string {
+	random := make([]byte, n*3/4)
+	rng := rand.NewChaCha8([32]byte{})
+	_, _ = rng.Read(random)
+	return `{"blobs":["` + base64.StdEncoding.EncodeToString(random) + `"]}`
+}
+
+var _ = Describe("JsonServerSnapshot Controller", func() {
+	Context("When reconciling a resource", func() {
+		const resourceName = "test-snapshot"
+		const jsonServerName = "test-snapshot-source"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+		snapshot := &examplev1.JsonServerSnapshot{}
+
+		BeforeEach(func() {
+			By("creating the JsonServer the data is captured from")
+			jsonServer := &examplev1.JsonServer{
+				ObjectMeta: metav1.ObjectMeta{Name: jsonServerName, Namespace: "default"},
+				Spec: examplev1.JsonServerSpec{
+					Replicas:   1,
+					JsonConfig: `{"people": [{"id": 1, "name": "Person A"}]}`,
+				},
+			}
+			Expect(k8sClient.Create(ctx, jsonServer)).To(Succeed())
+
+			By("creating the custom resource for the Kind JsonServerSnapshot")
+			err := k8sClient.Get(ctx, typeNamespacedName, snapshot)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &examplev1.JsonServerSnapshot{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: "default",
+					},
+					Spec: examplev1.JsonServerSnapshotSpec{
+						JsonServerName: jsonServerName,
+					},
+				}
+				Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+			}
+		})
+
+		AfterEach(func() {
+			resource := &examplev1.JsonServerSnapshot{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			Expect(err).NotTo(HaveOccurred())
+
+			By("Cleanup the specific resource instance JsonServerSnapshot")
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+
+			jsonServer := &examplev1.JsonServer{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: jsonServerName, Namespace: "default"}, jsonServer)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, jsonServer)).To(Succeed())
+		})
+
+		It("should stay pending while no pod of the JsonServer is ready", func() {
+			controllerReconciler := &JsonServerSnapshotReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically(">", 0))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, snapshot)).To(Succeed())
+			Expect(snapshot.Status.Phase).To(Equal(examplev1.SnapshotPending))
+		})
+
+		It("should fail snapshots that do not fit in the ConfigMaps once compressed", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(incompressibleDocument(payload.MaxCompressedSize + payload.ChunkSize)))
+			}))
+			DeferCleanup(server.Close)
+			httpClient := &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+				},
+			}}
+
+			jsonServer := &examplev1.JsonServer{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: jsonServerName, Namespace: "default"}, jsonServer)).To(Succeed())
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      jsonServerName + "-0",
+					Namespace: "default",
+					Labels:    getResourceLabels(jsonServer),
+				},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "json-server", Image: "json-server"}}},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, pod)
+			pod.Status.PodIP = "10.0.0.1"
+			pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+			controllerReconciler := &JsonServerSnapshotReconciler{
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				Recorder:   record.NewFakeRecorder(10),
+				HTTPClient: httpClient,
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, snapshot)).To(Succeed())
+			Expect(snapshot.Status.Phase).To(Equal(examplev1.SnapshotFailed))
+			Expect(snapshot.Status.Message).To(ContainSubstring("cannot be stored in ConfigMaps"))
+		})
+
+		It("should fail instead of overwriting a ConfigMap it does not own", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(`{"people": []}`))
+			}))
+			DeferCleanup(server.Close)
+			httpClient := &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+				},
+			}}
+
+			jsonServer := &examplev1.JsonServer{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: jsonServerName, Namespace: "default"}, jsonServer)).To(Succeed())
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      jsonServerName + "-0",
+					Namespace: "default",
+					Labels:    getResourceLabels(jsonServer),
+				},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "json-server", Image: "json-server"}}},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, pod)
+			pod.Status.PodIP = "10.0.0.1"
+			pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+			By("Creating a ConfigMap named like the snapshot")
+			configMap := &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+				Data:       map[string]string{"settings": "kept"},
+			}
+			Expect(k8sClient.Create(ctx, configMap)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, configMap)
+
+			controllerReconciler := &JsonServerSnapshotReconciler{
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				Recorder:   record.NewFakeRecorder(10),
+				HTTPClient: httpClient,
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, snapshot)).To(Succeed())
+			Expect(snapshot.Status.Phase).To(Equal(examplev1.SnapshotFailed))
+			Expect(snapshot.Status.Message).To(ContainSubstring(`ConfigMap "test-snapshot" already exists and is not owned by the snapshot`))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, configMap)).To(Succeed())
+			Expect(configMap.Data).To(Equal(map[string]string{"settings": "kept"}))
+			Expect(configMap.OwnerReferences).To(BeEmpty())
+		})
+
+		It("should capture the data of a running pod and seed a JsonServer from it", func() {
+			const captured = `{"people": [{"id": 1, "name": "Person A"}, {"id": 2, "name": "Posted"}]}`
+			// envtest runs no pods, the pod IP is routed to a local server standing in for json-server
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Path).To(Equal("/db"))
+				_, _ = w.Write([]byte(captured))
+			}))
+			DeferCleanup(server.Close)
+			httpClient := &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+				},
+			}}
+
+			By("Creating a ready pod of the JsonServer")
+			jsonServer := &examplev1.JsonServer{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: jsonServerName, Namespace: "default"}, jsonServer)).To(Succeed())
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      jsonServerName + "-0",
+					Namespace: "default",
+					Labels:    getResourceLabels(jsonServer),
+				},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "json-server", Image: "json-server"}}},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, pod)
+			pod.Status.PodIP = "10.0.0.1"
+			pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+			controllerReconciler := &JsonServerSnapshotReconciler{
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				Recorder:   record.NewFakeRecorder(10),
+				HTTPClient: httpClient,
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, snapshot)).To(Succeed())
+			Expect(snapshot.Status.Phase).To(Equal(examplev1.SnapshotCompleted))
+			Expect(snapshot.Status.Pod).To(Equal(pod.Name))
+			Expect(snapshot.Status.Size).To(Equal(int64(len(captured))))
+			Expect(snapshot.Status.Checksum).To(HavePrefix("sha256:"))
+			Expect(snapshot.Status.DataRef).To(Equal(&examplev1.SnapshotDataRef{Kind: examplev1.SnapshotStorageConfigMap, Name: resourceName}))
+
+			stored := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, stored)).To(Succeed())
+			Expect(stored.Data["db.json"]).To(Equal(captured))
+
+			By("Seeding another JsonServer from the snapshot")
+			seeded := &examplev1.JsonServer{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-snapshot-seeded", Namespace: "default"},
+				Spec: examplev1.JsonServerSpec{
+					Replicas: 1,
+					DataFrom: &examplev1.DataSource{SnapshotRef: &corev1.LocalObjectReference{Name: resourceName}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, seeded)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, seeded)
+
+			jsonServerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			seededName := types.NamespacedName{Name: seeded.Name, Namespace: "default"}
+			_, err = jsonServerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: seededName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, seededName, seeded)).To(Succeed())
+			Expect(seeded.Status.Source).NotTo(BeNil())
+			Expect(seeded.Status.Source.Kind).To(Equal(examplev1.SourceKindSnapshot))
+			Expect(seeded.Status.Source.Revision).To(Equal(snapshot.Status.Checksum))
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, seededName, configMap)).To(Succeed())
+			Expect(configMap.Data["db.json"]).To(Equal(captured))
+		})
+
+		It("should split large snapshots across ConfigMaps and seed a JsonServer from them", func() {
+			captured := incompressibleDocument(payload.ChunkSize + payload.ChunkSize/2)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(captured))
+			}))
+			DeferCleanup(server.Close)
+			httpClient := &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+				},
+			}}
+
+			jsonServer := &examplev1.JsonServer{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: jsonServerName, Namespace: "default"}, jsonServer)).To(Succeed())
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      jsonServerName + "-0",
+					Namespace: "default",
+					Labels:    getResourceLabels(jsonServer),
+				},
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "json-server", Image: "json-server"}}},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, pod)
+			pod.Status.PodIP = "10.0.0.1"
+			pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+			controllerReconciler := &JsonServerSnapshotReconciler{
+				Client:     k8sClient,
+				Scheme:     k8sClient.Scheme(),
+				Recorder:   record.NewFakeRecorder(10),
+				HTTPClient: httpClient,
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, snapshot)).To(Succeed())
+			Expect(snapshot.Status.Phase).To(Equal(examplev1.SnapshotCompleted))
+			Expect(snapshot.Status.Size).To(Equal(int64(len(captured))))
+
+			stored := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, stored)).To(Succeed())
+			Expect(stored.Data).NotTo(HaveKey("db.json"))
+			Expect(stored.Annotations).To(HaveKeyWithValue(chunksAnnotation, "2"))
+			chunk := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: chunkName(resourceName, 1), Namespace: "default"}, chunk)).To(Succeed())
+			Expect(metav1.IsControlledBy(chunk, snapshot)).To(BeTrue())
+
+			By("Seeding another JsonServer from the snapshot")
+			seeded := &examplev1.JsonServer{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-snapshot-seeded-chunks", Namespace: "default"},
+				Spec: examplev1.JsonServerSpec{
+					Replicas: 1,
+					DataFrom: &examplev1.DataSource{SnapshotRef: &corev1.LocalObjectReference{Name: resourceName}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, seeded)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, ctx, seeded)
+
+			jsonServerReconciler := &JsonServerReconciler{
+				Client:   k8sClient,
+				Scheme:   k8sClient.Scheme(),
+				Recorder: record.NewFakeRecorder(10),
+			}
+			seededName := types.NamespacedName{Name: seeded.Name, Namespace: "default"}
+			_, err = jsonServerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: seededName})
+			Expect(err).NotTo(HaveOccurred())
+
+			configMap := &corev1.ConfigMap{}
+			Expect(k8sClient.Get(ctx, seededName, configMap)).To(Succeed())
+			data, ok := jsonServerReconciler.readDocument(ctx, configMap)
+			Expect(ok).To(BeTrue())
+			Expect(data).To(Equal(captured))
+		})
+	})
+})
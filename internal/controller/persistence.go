@@ -0,0 +1,250 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"slices"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+const (
+	// stepStatefulSet is the reconcile step of persistent JsonServers
+	stepStatefulSet = "StatefulSet"
+	// dataVolumeName is the volume claim template holding the data of each replica
+	dataVolumeName = "data"
+	// seedVolumeName is the volume of the ConfigMaps the data volumes are seeded from
+	seedVolumeName = "seed"
+	// defaultVolumeSize is used when the persistence section sets no size
+	defaultVolumeSize = "1Gi"
+)
+
+// seedScript copies the configuration to the data volume when the volume is new, when the
+// data was reset since the volume was seeded or, with the OnConfigChange policy, when the
+// configuration changed since the volume was seeded. The hash of the seeded configuration
+// and the time of the last reset are kept next to the data in .config-hash and .reset-at.
+// Compressed documents are reassembled from their chunks, like in unpackScript.
+const seedScript = `set -e
+if [ ! -f /data/db.json ]; then
+  echo "Seeding /data/db.json"
+elif [ -n "$RESET_AT" ] && [ "$(cat /data/.reset-at 2>/dev/null)" != "$RESET_AT" ]; then
+  echo "Data reset at $RESET_AT, reseeding /data/db.json"
+elif [ "$RESEED_POLICY" = "OnConfigChange" ] && [ "$(cat /data/.config-hash 2>/dev/null)" != "$CONFIG_HASH" ]; then
+  echo "Configuration changed, reseeding /data/db.json"
+else
+  echo "Keeping the existing /data/db.json"
+  exit 0
+fi
+if [ -f /seed/db.json ]; then
+  cp /seed/db.json /data/db.json.tmp
+else
+  cat /seed/db.json.gz.* | gunzip > /data/db.json.tmp
+  echo "$(cat /seed/db.json.sha256)  /data/db.json.tmp" | sha256sum -c -
+fi
+mv /data/db.json.tmp /data/db.json
+echo "$CONFIG_HASH" > /data/.config-hash
+echo "$RESET_AT" > /data/.reset-at
+`
+
+// reconcileStatefulSet ensures the StatefulSet of a persistent or single-writer JsonServer exists
+// and returns it with its observed status. The pods serve and write /data/db.json on their own
+// volume, which an init container seeds from the ConfigMap.
+func (r *JsonServerReconciler) reconcileStatefulSet(ctx context.Context, jsonServer *examplev1.JsonServer, configMap *corev1.ConfigMap, secret *corev1.Secret) (*appsv1.StatefulSet, error) {
+	log := logf.FromContext(ctx)
+	persistence := jsonServer.Spec.Persistence
+
+	// Remove the Deployment left over by a JsonServer that did not need a StatefulSet
+	if err := r.deleteOwned(ctx, jsonServer, jsonServer.Name, &appsv1.Deployment{}); err != nil {
+		log.Error(err, "Failed to delete Deployment")
+		return nil, err
+	}
+
+	statefulSet := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jsonServer.Name,
+			Namespace: jsonServer.Namespace,
+			Labels:    getResourceLabels(jsonServer),
+		},
+	}
+
+	// The volume claim templates of a StatefulSet are immutable, it is recreated when they change
+	var claimTemplates []corev1.PersistentVolumeClaim
+	if persistence != nil {
+		claimTemplates = []corev1.PersistentVolumeClaim{volumeClaimTemplate(jsonServer)}
+	}
+	if existing, err := r.recreateStatefulSet(ctx, jsonServer, claimTemplates); err != nil || existing != nil {
+		return existing, err
+	}
+
+	hash := configHash(configMap, secret)
+	reseedPolicy := examplev1.ReseedNever
+	if persistence != nil && persistence.ReseedPolicy != "" {
+		reseedPolicy = persistence.ReseedPolicy
+	}
+
+	// Create or update StatefulSet
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, statefulSet, func() error {
+
+		if err := controllerutil.SetControllerReference(jsonServer, statefulSet, r.Scheme); err != nil {
+			return err
+		}
+
+		labels := getResourceLabels(jsonServer)
+
+		statefulSet.Spec.Replicas = ptr.To(desiredReplicas(jsonServer, statefulSet.Spec.Replicas))
+		statefulSet.Spec.ServiceName = jsonServer.Name
+		statefulSet.Spec.Selector = &metav1.LabelSelector{
+			MatchLabels: labels,
+		}
+
+		template := r.podTemplate(jsonServer, hash)
+		if persistence != nil && reseedPolicy == examplev1.ReseedNever {
+			// The configuration only seeds new volumes, changing it must not roll the pods
+			delete(template.Annotations, configHashAnnotation)
+		}
+		template.Spec.Containers[0].VolumeMounts[0].Name = dataVolumeName
+		template.Spec.InitContainers = []corev1.Container{
+			{
+				Name:    "seed",
+				Image:   template.Spec.Containers[0].Image,
+				Command: []string{"sh", "-c", seedScript},
+				Env: []corev1.EnvVar{
+					{Name: "RESEED_POLICY", Value: reseedPolicy},
+					{Name: "CONFIG_HASH", Value: template.Annotations[configHashAnnotation]},
+					{Name: "RESET_AT", Value: resetAt(jsonServer)},
+				},
+				VolumeMounts: []corev1.VolumeMount{
+					{Name: dataVolumeName, MountPath: "/data"},
+					{Name: seedVolumeName, MountPath: "/seed", ReadOnly: true},
+				},
+			},
+		}
+		template.Spec.Volumes = []corev1.Volume{documentVolume(seedVolumeName, configMap, secret)}
+		if persistence == nil {
+			// Single writers without persistence seed an empty volume on every start
+			template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+				Name:         dataVolumeName,
+				VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+			})
+		}
+		applyServerOptions(jsonServer, &template, configMap)
+		applyConsistency(jsonServer, &template, configMap)
+		applyPodTemplateOverride(jsonServer, &template)
+		statefulSet.Spec.Template = template
+
+		if statefulSet.CreationTimestamp.IsZero() {
+			statefulSet.Spec.VolumeClaimTemplates = claimTemplates
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Error(err, "Failed to create or update StatefulSet")
+		return nil, err
+	}
+
+	if err := recordWorkloadStatus(jsonServer, *statefulSet.Spec.Replicas, hash); err != nil {
+		log.Error(err, "Failed to create selector from labels")
+		return nil, err
+	}
+
+	log.Info("StatefulSet reconciled", "operation", op)
+
+	return statefulSet, nil
+}
+
+// recreateStatefulSet deletes the StatefulSet of the JsonServer when its volume claim templates
+// differ from the desired ones, leaving its pods and their volumes behind for the new
+// StatefulSet to adopt and roll. It returns the StatefulSet while it is being deleted, nil
+// when it can be created or updated. The deletion of the StatefulSet triggers the next
+// reconcile.
+func (r *JsonServerReconciler) recreateStatefulSet(ctx context.Context, jsonServer *examplev1.JsonServer, claimTemplates []corev1.PersistentVolumeClaim) (*appsv1.StatefulSet, error) {
+	log := logf.FromContext(ctx)
+
+	statefulSet := &appsv1.StatefulSet{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: jsonServer.Namespace, Name: jsonServer.Name}, statefulSet); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	if !metav1.IsControlledBy(statefulSet, jsonServer) {
+		// Reported by CreateOrUpdate
+		return nil, nil
+	}
+	if !statefulSet.DeletionTimestamp.IsZero() {
+		log.Info("Waiting for the StatefulSet to be deleted before recreating it")
+		return statefulSet, nil
+	}
+	if claimTemplatesEqual(statefulSet.Spec.VolumeClaimTemplates, claimTemplates) {
+		return nil, nil
+	}
+
+	if err := r.Delete(ctx, statefulSet, client.PropagationPolicy(metav1.DeletePropagationOrphan),
+		client.Preconditions{UID: &statefulSet.UID}); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Failed to delete StatefulSet to change its volume claim templates")
+		return nil, err
+	}
+	log.Info("StatefulSet deleted to change its volume claim templates, its pods are kept")
+	return statefulSet, nil
+}
+
+// claimTemplatesEqual compares the fields of the volume claim templates the JsonServer sets,
+// ignoring those defaulted by the API server
+func claimTemplatesEqual(live, desired []corev1.PersistentVolumeClaim) bool {
+	return slices.EqualFunc(live, desired, func(live, desired corev1.PersistentVolumeClaim) bool {
+		liveSize := live.Spec.Resources.Requests[corev1.ResourceStorage]
+		desiredSize := desired.Spec.Resources.Requests[corev1.ResourceStorage]
+		return live.Name == desired.Name &&
+			slices.Equal(live.Spec.AccessModes, desired.Spec.AccessModes) &&
+			ptr.Equal(live.Spec.StorageClassName, desired.Spec.StorageClassName) &&
+			liveSize.Cmp(desiredSize) == 0
+	})
+}
+
+// volumeClaimTemplate returns the claim of the volume each replica keeps its data on
+func volumeClaimTemplate(jsonServer *examplev1.JsonServer) corev1.PersistentVolumeClaim {
+	persistence := jsonServer.Spec.Persistence
+	size := persistence.Size
+	if size.IsZero() {
+		size = resource.MustParse(defaultVolumeSize)
+	}
+
+	return corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   dataVolumeName,
+			Labels: getResourceLabels(jsonServer),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: persistence.StorageClassName,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}
+}
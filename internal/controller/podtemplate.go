@@ -0,0 +1,99 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+// applyPodTemplateOverride merges the podTemplate section of the spec onto the pod template
+// built by the operator. Labels, annotations and environment variables managed by the
+// operator take precedence over the ones of the override.
+func applyPodTemplateOverride(jsonServer *examplev1.JsonServer, template *corev1.PodTemplateSpec) {
+	override := jsonServer.Spec.PodTemplate
+	if override == nil {
+		return
+	}
+
+	template.Labels = mergeMaps(override.Labels, template.Labels)
+	template.Annotations = mergeMaps(override.Annotations, template.Annotations)
+
+	spec := &template.Spec
+	server := &spec.Containers[0]
+	if override.Resources != nil {
+		server.Resources = *override.Resources.DeepCopy()
+	}
+	server.LivenessProbe = override.LivenessProbe.DeepCopy()
+	server.ReadinessProbe = override.ReadinessProbe.DeepCopy()
+	server.StartupProbe = override.StartupProbe.DeepCopy()
+	server.Env = mergeEnv(override.Env, server.Env)
+
+	// The restricted Pod Security Standard applies to the containers added by the operator too
+	if override.SecurityContext != nil {
+		for i := range spec.InitContainers {
+			spec.InitContainers[i].SecurityContext = override.SecurityContext.DeepCopy()
+		}
+		for i := range spec.Containers {
+			spec.Containers[i].SecurityContext = override.SecurityContext.DeepCopy()
+		}
+	}
+	spec.SecurityContext = override.PodSecurityContext.DeepCopy()
+
+	spec.NodeSelector = override.NodeSelector
+	spec.Tolerations = override.Tolerations
+	spec.Affinity = override.Affinity.DeepCopy()
+	spec.TopologySpreadConstraints = override.TopologySpreadConstraints
+	spec.PriorityClassName = override.PriorityClassName
+	spec.ImagePullSecrets = override.ImagePullSecrets
+	spec.ServiceAccountName = override.ServiceAccountName
+}
+
+// mergeMaps returns the entries of both maps, the managed entries taking precedence
+func mergeMaps(override, managed map[string]string) map[string]string {
+	if len(override) == 0 {
+		return managed
+	}
+	merged := make(map[string]string, len(override)+len(managed))
+	for key, value := range override {
+		merged[key] = value
+	}
+	for key, value := range managed {
+		merged[key] = value
+	}
+	return merged
+}
+
+// mergeEnv returns the managed variables followed by the variables of the override the
+// operator does not manage
+func mergeEnv(override, managed []corev1.EnvVar) []corev1.EnvVar {
+	if len(override) == 0 {
+		return managed
+	}
+	names := make(map[string]bool, len(managed))
+	for _, env := range managed {
+		names[env.Name] = true
+	}
+	merged := append([]corev1.EnvVar(nil), managed...)
+	for _, env := range override {
+		if !names[env.Name] {
+			merged = append(merged, env)
+		}
+	}
+	return merged
+}
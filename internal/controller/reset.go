@@ -0,0 +1,111 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+const (
+	// stepReset is the reconcile step restoring the data to the configuration
+	stepReset = "Reset"
+	// reasonInvalidResetSchedule reports a resetSchedule that is not a cron expression
+	reasonInvalidResetSchedule = "InvalidResetSchedule"
+	// resetAtAnnotation holds the time of the last reset on the pod template, changing it
+	// rolls the pods and seeds their volumes again
+	resetAtAnnotation = "example.example.com/reset-at"
+)
+
+// reconcileReset restores the data changed through the API to the configuration when the
+// reset schedule is due or a new reset was requested with the reset-requested-at annotation.
+// The reset is recorded in the status, from which the pod template is rendered. It returns
+// the delay until the next scheduled reset, zero without schedule.
+func (r *JsonServerReconciler) reconcileReset(ctx context.Context, jsonServer *examplev1.JsonServer) (time.Duration, *reconcileError) {
+	log := logf.FromContext(ctx)
+	now := time.Now()
+
+	var schedule cron.Schedule
+	if jsonServer.Spec.ResetSchedule != "" {
+		var err error
+		if schedule, err = parseResetSchedule(jsonServer.Spec.ResetSchedule); err != nil {
+			return 0, &reconcileError{Step: stepReset, Reason: reasonInvalidResetSchedule,
+				Err: fmt.Errorf("resetSchedule %q is not a valid cron expression: %w", jsonServer.Spec.ResetSchedule, err)}
+		}
+	}
+
+	var reasons []string
+	request := jsonServer.Annotations[examplev1.ResetRequestedAtAnnotation]
+	if request != "" && request != jsonServer.Status.LastResetRequest {
+		reasons = append(reasons, fmt.Sprintf("requested at %s", request))
+		jsonServer.Status.LastResetRequest = request
+	}
+
+	next := jsonServer.Status.NextResetTime
+	due := schedule != nil && next != nil && !next.After(now)
+	if due {
+		reasons = append(reasons, fmt.Sprintf("scheduled at %s", next.UTC().Format(time.RFC3339)))
+	}
+
+	if len(reasons) > 0 {
+		// Second precision, so that the pod template rendered now matches the one rendered
+		// from the stored status
+		resetTime := metav1.NewTime(now).Rfc3339Copy()
+		jsonServer.Status.LastResetTime = &resetTime
+		message := fmt.Sprintf("Data reset to the configuration (%s)", strings.Join(reasons, ", "))
+		r.Recorder.Event(jsonServer, corev1.EventTypeNormal, "DataReset", message)
+		log.Info("Data reset to the configuration", "reasons", reasons)
+	}
+
+	if schedule == nil {
+		jsonServer.Status.NextResetTime = nil
+		return 0, nil
+	}
+	// A schedule changed to an earlier time applies right away, one changed to a later
+	// time after the reset already planned
+	upcoming := metav1.NewTime(schedule.Next(now)).Rfc3339Copy()
+	if due || next == nil || upcoming.Before(next) {
+		jsonServer.Status.NextResetTime = &upcoming
+	}
+	return jsonServer.Status.NextResetTime.Sub(now), nil
+}
+
+// parseResetSchedule parses a cron expression, in UTC unless it sets its time zone
+func parseResetSchedule(schedule string) (cron.Schedule, error) {
+	if !strings.HasPrefix(schedule, "CRON_TZ=") && !strings.HasPrefix(schedule, "TZ=") {
+		schedule = "CRON_TZ=UTC " + schedule
+	}
+	return cron.ParseStandard(schedule)
+}
+
+// resetAt returns the time of the last reset as rendered in the pod template, empty when the
+// data was never reset
+func resetAt(jsonServer *examplev1.JsonServer) string {
+	if jsonServer.Status.LastResetTime == nil {
+		return ""
+	}
+	return jsonServer.Status.LastResetTime.UTC().Format(time.RFC3339)
+}
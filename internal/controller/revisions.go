@@ -0,0 +1,278 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/payload"
+)
+
+const (
+	// stepRevisions is the reconcile step recording the configuration revisions
+	stepRevisions = "Revisions"
+	// revisionLabel holds the hash of the configuration kept in a revision ConfigMap
+	revisionLabel = "example.example.com/revision"
+	// revisionNumberAnnotation orders the revisions by the last time they were served
+	revisionNumberAnnotation = "example.example.com/revision-number"
+	// revisionGzipKey holds the compressed configuration of the revisions too large to be kept as is
+	revisionGzipKey = "db.json.gz"
+	// revisionAuthorAnnotation holds the field manager that wrote the configuration of a revision
+	revisionAuthorAnnotation = "example.example.com/revision-author"
+	// defaultRevisionHistoryLimit is used when the spec sets no history limit
+	defaultRevisionHistoryLimit = 10
+)
+
+// revisionHash returns the content hash identifying a configuration revision
+func revisionHash(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// revisionName is the name of the ConfigMap holding a configuration revision
+func revisionName(jsonServer *examplev1.JsonServer, hash string) string {
+	return fmt.Sprintf("%s-%s", jsonServer.Name, hash)
+}
+
+// resolveRevision reads the configuration of the revision selected by spec.rollbackTo
+func (r *JsonServerReconciler) resolveRevision(ctx context.Context, jsonServer *examplev1.JsonServer) (*resolvedData, *reconcileError) {
+	hash := jsonServer.Spec.RollbackTo
+	description := fmt.Sprintf("revision %s", hash)
+
+	revision := &corev1.ConfigMap{}
+	if err := r.Get(ctx, client.ObjectKey{Namespace: jsonServer.Namespace, Name: revisionName(jsonServer, hash)}, revision); err != nil {
+		return nil, sourceError(description, err)
+	}
+	if !metav1.IsControlledBy(revision, jsonServer) || revision.Labels[revisionLabel] != hash {
+		return nil, sourceError(description, apierrors.NewNotFound(corev1.Resource("configmaps"), revision.Name))
+	}
+	data, ok := revision.Data["db.json"]
+	if compressed, isCompressed := revision.BinaryData[revisionGzipKey]; !ok && isCompressed {
+		decompressed, err := payload.Decompress(compressed)
+		if err != nil {
+			return nil, &reconcileError{Step: stepDataSource, Reason: reasonInvalidJSON,
+				Err: fmt.Errorf("%s cannot be decompressed: %w", description, err)}
+		}
+		data, ok = decompressed, true
+	}
+	if !ok {
+		return nil, missingKeyError(description, "db.json")
+	}
+
+	return &resolvedData{
+		Data:        data,
+		Description: description,
+		Source: examplev1.SourceStatus{
+			Kind:         examplev1.SourceKindRevision,
+			Name:         revision.Name,
+			Revision:     hash,
+			LastSyncTime: syncTime(jsonServer, examplev1.SourceKindRevision, revision.Name, hash),
+		},
+		Author: revision.Annotations[revisionAuthorAnnotation],
+	}, nil
+}
+
+// reconcileRevisions keeps the configuration being served in an immutable revision ConfigMap,
+// removes the revisions beyond the history limit and reports the history in the status.
+// The data read from Secrets has no revision.
+// Like the revisions of a Deployment, revisions are numbered in the order they were last
+// served, so that serving a configuration again makes its revision the newest one.
+func (r *JsonServerReconciler) reconcileRevisions(ctx context.Context, jsonServer *examplev1.JsonServer, data *resolvedData) error {
+	log := logf.FromContext(ctx)
+	hash := revisionHash(data.Data)
+	unrecorded := ""
+
+	revisions := &corev1.ConfigMapList{}
+	if err := r.List(ctx, revisions, client.InNamespace(jsonServer.Namespace),
+		client.MatchingLabels(getResourceLabels(jsonServer)), client.HasLabels{revisionLabel}); err != nil {
+		log.Error(err, "Failed to list revision ConfigMaps")
+		return err
+	}
+
+	var owned []*corev1.ConfigMap
+	var current *corev1.ConfigMap
+	latest := int64(0)
+	for i := range revisions.Items {
+		revision := &revisions.Items[i]
+		if !metav1.IsControlledBy(revision, jsonServer) {
+			continue
+		}
+		owned = append(owned, revision)
+		latest = max(latest, revisionNumber(revision))
+		if revision.Labels[revisionLabel] == hash {
+			current = revision
+		}
+	}
+
+	switch {
+	case data.Sensitive:
+		// Revisions are ConfigMaps, the data read from Secrets is not recorded
+		hash = ""
+
+	case current == nil:
+		current = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      revisionName(jsonServer, hash),
+				Namespace: jsonServer.Namespace,
+				Labels:    getResourceLabels(jsonServer),
+			},
+			Data:      map[string]string{"db.json": data.Data},
+			Immutable: ptr.To(true),
+		}
+		current.Labels[revisionLabel] = hash
+		if len(data.Data) > payload.ChunkSize {
+			compressed, err := payload.Compress(data.Data)
+			if err != nil {
+				return err
+			}
+			if len(compressed) > payload.ChunkSize {
+				// Revisions are kept in a single ConfigMap, unlike the configuration being served
+				log.Info("Configuration revision too large to be recorded", "revision", hash, "size", len(compressed))
+				// Reported once per configuration, not on every reconcile
+				if jsonServer.Status.UnrecordedRevision != hash {
+					r.Recorder.Eventf(jsonServer, corev1.EventTypeWarning, "RevisionNotRecorded",
+						"Configuration revision %s compresses to %d bytes, more than fits in a ConfigMap: it cannot be rolled back to", hash, len(compressed))
+				}
+				// No revision is served
+				unrecorded = hash
+				hash = ""
+				break
+			}
+			current.Data = nil
+			current.BinaryData = map[string][]byte{revisionGzipKey: compressed}
+		}
+		metav1.SetMetaDataAnnotation(&current.ObjectMeta, revisionNumberAnnotation, strconv.FormatInt(latest+1, 10))
+		if data.Author != "" {
+			metav1.SetMetaDataAnnotation(&current.ObjectMeta, revisionAuthorAnnotation, data.Author)
+		}
+		if err := controllerutil.SetControllerReference(jsonServer, current, r.Scheme); err != nil {
+			return err
+		}
+		// AlreadyExists when the cache has not observed the revision yet, retried as an API conflict
+		if err := r.Create(ctx, current); err != nil {
+			log.Error(err, "Failed to create revision ConfigMap")
+			return err
+		}
+		owned = append(owned, current)
+		log.Info("Configuration revision recorded", "revision", hash)
+
+	case revisionNumber(current) < latest:
+		// Only the data of a revision is immutable, its annotations can be updated
+		metav1.SetMetaDataAnnotation(&current.ObjectMeta, revisionNumberAnnotation, strconv.FormatInt(latest+1, 10))
+		if err := r.Update(ctx, current); err != nil {
+			log.Error(err, "Failed to update revision ConfigMap")
+			return err
+		}
+		log.Info("Configuration revision served again", "revision", hash)
+	}
+
+	// Newest first
+	slices.SortFunc(owned, func(a, b *corev1.ConfigMap) int {
+		return cmp.Compare(revisionNumber(b), revisionNumber(a))
+	})
+
+	limit := defaultRevisionHistoryLimit
+	if jsonServer.Spec.RevisionHistoryLimit != nil {
+		limit = int(*jsonServer.Spec.RevisionHistoryLimit)
+	}
+
+	history := []examplev1.ConfigRevision{}
+	for _, revision := range owned {
+		// The revision being served is the newest one, it is never pruned
+		if len(history) >= limit {
+			if err := r.Delete(ctx, revision, client.Preconditions{UID: &revision.UID}); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "Failed to delete revision ConfigMap", "revision", revision.Labels[revisionLabel])
+				return err
+			}
+			log.Info("Configuration revision pruned", "revision", revision.Labels[revisionLabel])
+			continue
+		}
+		history = append(history, examplev1.ConfigRevision{
+			Hash:         revision.Labels[revisionLabel],
+			CreationTime: revision.CreationTimestamp,
+			Author:       revision.Annotations[revisionAuthorAnnotation],
+		})
+	}
+
+	jsonServer.Status.CurrentRevision = hash
+	jsonServer.Status.UnrecordedRevision = unrecorded
+	jsonServer.Status.Revisions = history
+	return nil
+}
+
+// revisionNumber returns the number of a revision, zero when it has none
+func revisionNumber(revision *corev1.ConfigMap) int64 {
+	number, _ := strconv.ParseInt(revision.Annotations[revisionNumberAnnotation], 10, 64)
+	return number
+}
+
+// lastManager returns the field manager that last wrote the field at path, e.g.
+// "f:spec", "f:jsonConfig", according to the managed fields of an object
+func lastManager(entries []metav1.ManagedFieldsEntry, path ...string) string {
+	manager := ""
+	var latest time.Time
+	for _, entry := range entries {
+		if entry.FieldsV1 == nil || entry.Subresource != "" {
+			continue
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		if !hasManagedField(fields, path) {
+			continue
+		}
+		var written time.Time
+		if entry.Time != nil {
+			written = entry.Time.Time
+		}
+		if manager == "" || !written.Before(latest) {
+			manager = entry.Manager
+			latest = written
+		}
+	}
+	return manager
+}
+
+// hasManagedField reports whether the fields of a managed fields entry include path
+func hasManagedField(fields map[string]interface{}, path []string) bool {
+	for _, key := range path {
+		child, ok := fields[key].(map[string]interface{})
+		if !ok {
+			return false
+		}
+		fields = child
+	}
+	return true
+}
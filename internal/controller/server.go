@@ -0,0 +1,167 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/validation"
+)
+
+const (
+	// routesKey is the ConfigMap key of the json-server rewrite rules
+	routesKey = "routes.json"
+	// serverConfigVolumeName is the volume of the json-server configuration files
+	serverConfigVolumeName = "server-config"
+	// serverConfigDir is where the json-server configuration files are mounted
+	serverConfigDir = "/etc/json-server"
+	// staticVolumeName is the volume of the static files
+	staticVolumeName = "static"
+	// staticDir is where the static files are mounted
+	staticDir = "/static"
+	// routesHashAnnotation rolls the pods when the routes change, json-server only reads them
+	// at startup. The config hash does not cover persistent JsonServers that are never reseeded.
+	routesHashAnnotation = "example.example.com/routes-hash"
+)
+
+// renderRoutes returns the routes.json file of the JsonServer, or an empty string when it has
+// no routes. json-server applies the rules in file order: the base path is stripped first, the
+// routes follow in the order of the spec.
+func renderRoutes(jsonServer *examplev1.JsonServer) (string, error) {
+	server := jsonServer.Spec.Server
+	if server == nil || (len(server.Routes) == 0 && server.BasePath == "") {
+		return "", nil
+	}
+
+	var routes []examplev1.Route
+	if server.BasePath != "" {
+		routes = append(routes, examplev1.Route{Pattern: server.BasePath + "/*", Target: "/$1"})
+	}
+	routes = append(routes, server.Routes...)
+
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	for i, route := range routes {
+		pattern, err := json.Marshal(route.Pattern)
+		if err != nil {
+			return "", err
+		}
+		target, err := json.Marshal(route.Target)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&buf, "  %s: %s", pattern, target)
+		if i < len(routes)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("}\n")
+	return buf.String(), nil
+}
+
+// validationOptions returns the conventions the data of the JsonServer is checked against
+func validationOptions(jsonServer *examplev1.JsonServer) validation.Options {
+	server := jsonServer.Spec.Server
+	if server == nil {
+		return validation.DefaultOptions
+	}
+	// Validate falls back to the json-server defaults for empty options
+	return validation.Options{IDField: server.IDField, ForeignKeySuffix: server.ForeignKeySuffix}
+}
+
+// applyServerOptions renders the server section of the spec into the arguments of json-server
+// and mounts the routes and static files it references
+func applyServerOptions(jsonServer *examplev1.JsonServer, template *corev1.PodTemplateSpec, configMap *corev1.ConfigMap) {
+	server := jsonServer.Spec.Server
+	if server == nil {
+		return
+	}
+	container := &template.Spec.Containers[0]
+
+	var args []string
+	if routes, ok := configMap.Data[routesKey]; ok {
+		args = append(args, "--routes", serverConfigDir+"/"+routesKey)
+		sum := sha256.Sum256([]byte(routes))
+		metav1.SetMetaDataAnnotation(&template.ObjectMeta, routesHashAnnotation, hex.EncodeToString(sum[:])[:16])
+		mountServerConfig(template, configMap, routesKey)
+	}
+	if server.IDField != "" {
+		args = append(args, "--id", server.IDField)
+	}
+	if server.ForeignKeySuffix != "" {
+		args = append(args, "--foreign-key-suffix", server.ForeignKeySuffix)
+	}
+	if server.Delay != nil && server.Delay.Duration > 0 {
+		args = append(args, "--delay", fmt.Sprint(server.Delay.Milliseconds()))
+	}
+	if server.ReadOnly {
+		args = append(args, "--read-only")
+	}
+	if server.Static != nil {
+		args = append(args, "--static", staticDir)
+		template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+			Name: staticVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: *server.Static},
+			},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      staticVolumeName,
+			MountPath: staticDir,
+			ReadOnly:  true,
+		})
+	}
+
+	container.Args = append(args, container.Args...)
+}
+
+// mountServerConfig mounts a key of the ConfigMap of the JsonServer in the json-server
+// configuration directory, next to the keys mounted already
+func mountServerConfig(template *corev1.PodTemplateSpec, configMap *corev1.ConfigMap, key string) {
+	item := corev1.KeyToPath{Key: key, Path: key}
+	for i := range template.Spec.Volumes {
+		if volume := &template.Spec.Volumes[i]; volume.Name == serverConfigVolumeName {
+			volume.ConfigMap.Items = append(volume.ConfigMap.Items, item)
+			return
+		}
+	}
+
+	template.Spec.Volumes = append(template.Spec.Volumes, corev1.Volume{
+		Name: serverConfigVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name},
+				Items:                []corev1.KeyToPath{item},
+			},
+		},
+	})
+	container := &template.Spec.Containers[0]
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      serverConfigVolumeName,
+		MountPath: serverConfigDir,
+		ReadOnly:  true,
+	})
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+const (
+	// containerPort is the port json-server listens on in the pods
+	containerPort = 3000
+	// defaultServicePort is the port of the Service when the service section sets none
+	defaultServicePort = 3000
+	// defaultServicePortName is the name of the Service port when the service section sets none
+	defaultServicePortName = "http"
+	// managedAnnotationsAnnotation lists the Service annotations set from the spec, so that the
+	// ones removed from the spec are removed from the Service without touching the others
+	managedAnnotationsAnnotation = "example.example.com/managed-annotations"
+)
+
+// servicePort returns the port the Service exposes the json-server pods on
+func servicePort(jsonServer *examplev1.JsonServer) int32 {
+	if service := jsonServer.Spec.Service; service != nil && service.Port != 0 {
+		return service.Port
+	}
+	return defaultServicePort
+}
+
+// serviceType returns the type of Service selected in the service section
+func serviceType(jsonServer *examplev1.JsonServer) string {
+	if service := jsonServer.Spec.Service; service != nil && service.Type != "" {
+		return service.Type
+	}
+	return examplev1.ServiceTypeClusterIP
+}
+
+// reconcileService ensures the Service exposing the json-server pods, or their activator while
+// they are scaled to zero, exists. Changes of immutable fields, such as switching from or to a
+// headless Service, recreate the Service.
+func (r *JsonServerReconciler) reconcileService(ctx context.Context, jsonServer *examplev1.JsonServer, toActivator bool) error {
+	log := logf.FromContext(ctx)
+
+	existing := &corev1.Service{}
+	err := r.Get(ctx, client.ObjectKey{Namespace: jsonServer.Namespace, Name: jsonServer.Name}, existing)
+	if client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Failed to get Service")
+		return err
+	}
+	if err == nil && metav1.IsControlledBy(existing, jsonServer) && serviceNeedsRecreate(existing, jsonServer) {
+		return r.recreateService(ctx, jsonServer, existing, toActivator)
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jsonServer.Name,
+			Namespace: jsonServer.Namespace,
+			Labels:    getResourceLabels(jsonServer),
+		},
+	}
+
+	op, err := controllerutil.CreateOrUpdate(ctx, r.Client, service, func() error {
+		return r.mutateService(jsonServer, service, toActivator)
+	})
+
+	if err != nil {
+		log.Error(err, "Failed to create or update Service")
+		return err
+	}
+
+	log.Info("Service reconciled", "operation", op)
+	return nil
+}
+
+// recreateService deletes the Service whose immutable fields no longer match the spec and
+// creates it again. The deletion is conditioned on the UID of the observed Service so that
+// a Service recreated concurrently is never deleted.
+func (r *JsonServerReconciler) recreateService(ctx context.Context, jsonServer *examplev1.JsonServer, existing *corev1.Service, toActivator bool) error {
+	log := logf.FromContext(ctx)
+
+	if err := r.Delete(ctx, existing, client.Preconditions{UID: &existing.UID}); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Failed to delete Service")
+		return err
+	}
+	r.Recorder.Event(jsonServer, corev1.EventTypeNormal, "ServiceRecreated",
+		fmt.Sprintf("Service %s recreated to change immutable fields", existing.Name))
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jsonServer.Name,
+			Namespace: jsonServer.Namespace,
+			Labels:    getResourceLabels(jsonServer),
+		},
+	}
+	if err := r.mutateService(jsonServer, service, toActivator); err != nil {
+		return err
+	}
+	// The old Service may still be terminating, e.g. while its load balancer is cleaned up.
+	// Creating it fails with AlreadyExists then, which is retried as an API conflict.
+	if err := r.Create(ctx, service); err != nil {
+		log.Error(err, "Failed to create Service")
+		return err
+	}
+
+	log.Info("Service recreated")
+	return nil
+}
+
+// mutateService sets the fields of the Service managed by the operator. The fields the
+// cluster allocates, such as the cluster IP or a node port left empty in the spec, are kept.
+func (r *JsonServerReconciler) mutateService(jsonServer *examplev1.JsonServer, service *corev1.Service, toActivator bool) error {
+	if err := controllerutil.SetControllerReference(jsonServer, service, r.Scheme); err != nil {
+		return err
+	}
+
+	spec := jsonServer.Spec.Service
+	if spec == nil {
+		spec = &examplev1.ServiceSpec{}
+	}
+	svcType := serviceType(jsonServer)
+	external := svcType == examplev1.ServiceTypeNodePort || svcType == examplev1.ServiceTypeLoadBalancer
+
+	setServiceAnnotations(service, spec.Annotations)
+
+	service.Spec.Selector = getResourceLabels(jsonServer)
+	if toActivator {
+		service.Spec.Selector = activatorLabels(jsonServer)
+	}
+
+	service.Spec.Type = corev1.ServiceType(svcType)
+	if svcType == examplev1.ServiceTypeHeadless {
+		service.Spec.Type = corev1.ServiceTypeClusterIP
+		service.Spec.ClusterIP = corev1.ClusterIPNone
+		service.Spec.ClusterIPs = []string{corev1.ClusterIPNone}
+	}
+
+	portName := spec.PortName
+	if portName == "" {
+		portName = defaultServicePortName
+	}
+	port := corev1.ServicePort{
+		Name:        portName,
+		Port:        servicePort(jsonServer),
+		TargetPort:  intstr.FromInt32(containerPort),
+		Protocol:    corev1.ProtocolTCP,
+		AppProtocol: spec.AppProtocol,
+	}
+	if external {
+		port.NodePort = spec.NodePort
+		// Keep the node port allocated by the cluster instead of requesting a new one
+		if port.NodePort == 0 && len(service.Spec.Ports) == 1 {
+			port.NodePort = service.Spec.Ports[0].NodePort
+		}
+	}
+	service.Spec.Ports = []corev1.ServicePort{port}
+
+	service.Spec.ExternalTrafficPolicy = ""
+	if external {
+		service.Spec.ExternalTrafficPolicy = spec.ExternalTrafficPolicy
+		if service.Spec.ExternalTrafficPolicy == "" {
+			service.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyCluster
+		}
+	}
+
+	// The cluster picks the IP families when the spec leaves them empty
+	if len(spec.IPFamilies) > 0 {
+		service.Spec.IPFamilies = spec.IPFamilies
+	}
+	if spec.IPFamilyPolicy != nil {
+		service.Spec.IPFamilyPolicy = spec.IPFamilyPolicy
+	}
+
+	// Keep the clients of sticky JsonServers on the replica holding their writes
+	service.Spec.SessionAffinity = spec.SessionAffinity
+	if jsonServer.Spec.Consistency == examplev1.ConsistencySticky {
+		service.Spec.SessionAffinity = corev1.ServiceAffinityClientIP
+	}
+	if service.Spec.SessionAffinity == "" {
+		service.Spec.SessionAffinity = corev1.ServiceAffinityNone
+	}
+	if service.Spec.SessionAffinity == corev1.ServiceAffinityNone {
+		// The session affinity config defaulted for ClientIP is rejected without affinity
+		service.Spec.SessionAffinityConfig = nil
+	}
+
+	return nil
+}
+
+// setServiceAnnotations sets the annotations of the spec on the Service and removes the ones
+// a previous spec set, leaving the annotations added by other controllers alone
+func setServiceAnnotations(service *corev1.Service, annotations map[string]string) {
+	if previous, ok := service.Annotations[managedAnnotationsAnnotation]; ok {
+		for _, key := range strings.Split(previous, ",") {
+			delete(service.Annotations, key)
+		}
+		delete(service.Annotations, managedAnnotationsAnnotation)
+	}
+	if len(annotations) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(annotations))
+	for key, value := range annotations {
+		metav1.SetMetaDataAnnotation(&service.ObjectMeta, key, value)
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	metav1.SetMetaDataAnnotation(&service.ObjectMeta, managedAnnotationsAnnotation, strings.Join(keys, ","))
+}
+
+// serviceNeedsRecreate reports whether the Service has immutable fields the spec changes:
+// its cluster IP when switching from or to a headless Service, or its primary IP family
+func serviceNeedsRecreate(service *corev1.Service, jsonServer *examplev1.JsonServer) bool {
+	headless := service.Spec.ClusterIP == corev1.ClusterIPNone
+	if headless != (serviceType(jsonServer) == examplev1.ServiceTypeHeadless) {
+		return true
+	}
+
+	if spec := jsonServer.Spec.Service; spec != nil && len(spec.IPFamilies) > 0 && len(service.Spec.IPFamilies) > 0 {
+		return spec.IPFamilies[0] != service.Spec.IPFamilies[0]
+	}
+	return false
+}
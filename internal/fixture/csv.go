@@ -0,0 +1,131 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fixture
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// decodeCSV decodes the records of a collection written one per row, named by the header
+// row. The values are strings unless their column is typed. Empty values of typed columns
+// are null.
+func decodeCSV(source, data string, columns map[string]ColumnType) (any, error) {
+	reader := csv.NewReader(strings.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &Error{Source: source, Msg: "missing header row"}
+	}
+	if err != nil {
+		return nil, csvError(source, err)
+	}
+	for i, name := range header {
+		line, column := reader.FieldPos(i)
+		switch {
+		case name == "":
+			return nil, &Error{Source: source, Line: line, Column: column, Msg: fmt.Sprintf("column %d has no name", i+1)}
+		case slices.Contains(header[:i], name):
+			return nil, &Error{Source: source, Line: line, Column: column, Msg: fmt.Sprintf("duplicate column %q", name)}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(columns)) {
+		if !slices.Contains(header, name) {
+			return nil, &Error{Source: source, Line: 1, Msg: fmt.Sprintf("typed column %q is not in the header", name)}
+		}
+	}
+
+	records := []any{}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, csvError(source, err)
+		}
+
+		record := make(map[string]any, len(header))
+		for i, name := range header {
+			value, err := csvValue(row[i], columns[name])
+			if err != nil {
+				line, column := reader.FieldPos(i)
+				return nil, &Error{Source: source, Line: line, Column: column, Msg: fmt.Sprintf("column %q: %v", name, err)}
+			}
+			record[name] = value
+		}
+		records = append(records, record)
+	}
+}
+
+// csvValue converts a CSV value to the type of its column
+func csvValue(value string, columnType ColumnType) (any, error) {
+	if columnType == String || columnType == "" {
+		return value, nil
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+
+	switch columnType {
+	case Integer:
+		integer, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not an integer", value)
+		}
+		return json.Number(strconv.FormatInt(integer, 10)), nil
+	case Number:
+		if !jsonNumber.MatchString(strings.TrimPrefix(strings.ToLower(value), "-")) {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return json.Number(value), nil
+	case Boolean:
+		switch strings.ToLower(value) {
+		case "true", "yes":
+			return true, nil
+		case "false", "no":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean, use true or false", value)
+	case JSONValue:
+		decoded, err := decodeJSON("", value, 0)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON %q: %s", value, errorMsg(err))
+		}
+		return decoded, nil
+	default:
+		return nil, fmt.Errorf("unsupported column type %q", columnType)
+	}
+}
+
+// csvError locates the errors of the CSV reader, such as rows with too many fields
+func csvError(source string, err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &Error{Source: source, Line: parseErr.Line, Column: parseErr.Column, Msg: parseErr.Err.Error()}
+	}
+	return &Error{Source: source, Msg: err.Error()}
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fixture
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CSV", func() {
+	columns := map[string]ColumnType{"id": Integer, "price": Number, "active": Boolean, "tags": JSONValue}
+
+	It("decodes the rows into records typed by the column hints", func() {
+		value, err := decodeCSV("products", "id,name,price,active,tags\n1,\"Desk, oak\",149.90,yes,\"[\"\"office\"\"]\"\n2,Lamp,,false,\n", columns)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal([]any{
+			map[string]any{"id": json.Number("1"), "name": "Desk, oak", "price": json.Number("149.90"), "active": true, "tags": []any{"office"}},
+			map[string]any{"id": json.Number("2"), "name": "Lamp", "price": nil, "active": false, "tags": nil},
+		}))
+	})
+
+	It("reads the columns without hint as strings", func() {
+		Expect(decodeCSV("products", "id,zip\n1,01234\n", nil)).To(Equal([]any{map[string]any{"id": "1", "zip": "01234"}}))
+	})
+
+	DescribeTable("locating errors",
+		func(input, message string) {
+			_, err := decodeCSV("products", input, columns)
+			Expect(err).To(MatchError(message))
+		},
+		Entry("untyped value", "id,name,price,active,tags\n1,Desk,149.90,true,\n2,Lamp,cheap,true,\n",
+			`products: line 3, column 8: column "price": "cheap" is not a number`),
+		Entry("missing field", "id,name,price,active,tags\n1,Desk,149.90\n",
+			"products: line 2, column 1: wrong number of fields"),
+		Entry("unknown typed column", "id,name\n1,Desk\n", `products: line 1: typed column "active" is not in the header`),
+		Entry("duplicate column", "id,id\n", `products: line 1, column 4: duplicate column "id"`),
+		Entry("empty input", "", "products: missing header row"),
+	)
+})
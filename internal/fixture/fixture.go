@@ -0,0 +1,247 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package fixture converts the fixtures written as YAML, JSON5, NDJSON or CSV into the
+// db.json served by json-server. It is shared by the admission webhook and the controller.
+package fixture
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"jsonserver-operator/internal/validation"
+)
+
+// Format is the format of a document or of a collection
+type Format string
+
+// Formats of the fixtures. NDJSON and CSV only hold collections.
+const (
+	JSON   Format = "JSON"
+	YAML   Format = "YAML"
+	JSON5  Format = "JSON5"
+	NDJSON Format = "NDJSON"
+	CSV    Format = "CSV"
+)
+
+// ColumnType is the type of the values of a CSV column
+type ColumnType string
+
+// Types of the CSV columns
+const (
+	String    ColumnType = "string"
+	Integer   ColumnType = "integer"
+	Number    ColumnType = "number"
+	Boolean   ColumnType = "boolean"
+	JSONValue ColumnType = "json"
+)
+
+// Input is a document, or a collection, in one of the formats
+type Input struct {
+	// Source names the input in errors, e.g. "spec.collections[users].data"
+	Source string
+	// Format of Data, JSON when empty
+	Format Format
+	// Data is the document or the collection
+	Data string
+	// Columns types the values of the CSV columns, read as strings when not listed
+	Columns map[string]ColumnType
+}
+
+// Error is a conversion error, located in its input when the format allows it
+type Error struct {
+	// Source names the input
+	Source string
+	// Line and Column are 1-based, zero when unknown
+	Line   int
+	Column int
+	// Msg describes the error
+	Msg string
+}
+
+func (e *Error) Error() string {
+	if location := e.Location(); location != "" {
+		return fmt.Sprintf("%s: %s: %s", e.Source, location, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.Source, e.Msg)
+}
+
+// Location returns the line and column of the error, empty when unknown
+func (e *Error) Location() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("line %d, column %d", e.Line, e.Column)
+	case e.Line > 0:
+		return fmt.Sprintf("line %d", e.Line)
+	default:
+		return ""
+	}
+}
+
+// errorMsg returns the message of the error without its source and location, for the callers
+// reporting it within a larger input
+func errorMsg(err error) string {
+	var fixtureErr *Error
+	if errors.As(err, &fixtureErr) {
+		return fixtureErr.Msg
+	}
+	return err.Error()
+}
+
+// yamlLine extracts the line reported in the errors of the YAML parser
+var yamlLine = regexp.MustCompile(`^(?:.*: )?yaml: line (\d+): `)
+
+// Decode converts the input into its JSON value. Numbers are decoded as json.Number so that
+// they are written back unchanged.
+func Decode(input Input) (any, error) {
+	switch input.Format {
+	case JSON, "":
+		return decodeJSON(input.Source, input.Data, 0)
+	case YAML:
+		converted, err := yaml.YAMLToJSON([]byte(input.Data))
+		if err != nil {
+			yamlErr := &Error{Source: input.Source, Msg: err.Error()}
+			if match := yamlLine.FindStringSubmatchIndex(yamlErr.Msg); match != nil {
+				yamlErr.Line, _ = strconv.Atoi(yamlErr.Msg[match[2]:match[3]])
+				yamlErr.Msg = yamlErr.Msg[match[1]:]
+			}
+			return nil, yamlErr
+		}
+		return decodeJSON(input.Source, string(converted), 0)
+	case JSON5:
+		return decodeJSON5(input.Source, input.Data)
+	case NDJSON:
+		return decodeNDJSON(input.Source, input.Data)
+	case CSV:
+		return decodeCSV(input.Source, input.Data, input.Columns)
+	default:
+		return nil, &Error{Source: input.Source, Msg: fmt.Sprintf("unsupported format %q", input.Format)}
+	}
+}
+
+// decodeJSON decodes a JSON value. Syntax errors are located on the given line of a larger
+// input, the first one when zero.
+func decodeJSON(source, data string, line int) (any, error) {
+	if err := validation.ValidateJSON(data); err != nil {
+		var syntaxErr *validation.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			return nil, &Error{Source: source, Msg: err.Error()}
+		}
+		return nil, &Error{Source: source, Line: max(line, 1) + syntaxErr.Line - 1, Column: syntaxErr.Column, Msg: syntaxErr.Msg}
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, &Error{Source: source, Line: line, Msg: err.Error()}
+	}
+	return value, nil
+}
+
+// decodeNDJSON decodes the records of a collection written one per line. Blank lines are skipped.
+func decodeNDJSON(source, data string) (any, error) {
+	records := []any{}
+	for i, line := range strings.Split(data, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		record, err := decodeJSON(source, line, i+1)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := record.(map[string]any); !ok {
+			return nil, &Error{Source: source, Line: i + 1, Msg: fmt.Sprintf("records must be objects, got %s", kind(record))}
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// Compose returns the db.json holding the collections of the document and the named
+// collections. The document may be empty, each collection must have a name the document
+// does not use. The keys of the objects are sorted, composing the same inputs always gives
+// the same db.json.
+func Compose(document Input, collections map[string]Input) (string, error) {
+	db := map[string]any{}
+	if strings.TrimSpace(document.Data) != "" {
+		if document.Format == NDJSON || document.Format == CSV {
+			return "", &Error{Source: document.Source, Msg: fmt.Sprintf("%s only holds collections, use JSON, YAML or JSON5", document.Format)}
+		}
+		value, err := Decode(document)
+		if err != nil {
+			return "", err
+		}
+		object, ok := value.(map[string]any)
+		if !ok {
+			return "", &Error{Source: document.Source, Msg: fmt.Sprintf("must be an object of collections, got %s", kind(value))}
+		}
+		db = object
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(collections)) {
+		collection := collections[name]
+		if _, ok := db[name]; ok {
+			return "", &Error{Source: collection.Source, Msg: fmt.Sprintf("collection %q is already defined by %s", name, document.Source)}
+		}
+		value, err := Decode(collection)
+		if err != nil {
+			return "", err
+		}
+		switch value.(type) {
+		case []any, map[string]any:
+		default:
+			return "", &Error{Source: collection.Source, Msg: fmt.Sprintf("must be an array of records or an object, got %s", kind(value))}
+		}
+		db[name] = value
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(db); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// kind names the JSON type of a decoded value in errors
+func kind(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case json.Number:
+		return "a number"
+	case string:
+		return "a string"
+	case []any:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fixture
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fixtures", func() {
+	It("decodes YAML documents", func() {
+		value, err := Decode(Input{Source: "spec.jsonConfig", Format: YAML, Data: "people:\n  - id: 1\n    name: Alice\n    admin: true\n"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(map[string]any{
+			"people": []any{map[string]any{"id": json.Number("1"), "name": "Alice", "admin": true}},
+		}))
+	})
+
+	It("locates the errors of YAML documents", func() {
+		_, err := Decode(Input{Source: "spec.jsonConfig", Format: YAML, Data: "people:\n  - id: 1\n   name: Alice\n"})
+		Expect(err).To(MatchError("spec.jsonConfig: line 2: did not find expected '-' indicator"))
+	})
+
+	It("locates the syntax errors of JSON documents", func() {
+		_, err := Decode(Input{Source: "spec.jsonConfig", Data: "{\n  \"people\": [\n    {\"id\": 1,}\n  ]\n}"})
+		Expect(err).To(MatchError("spec.jsonConfig: line 3, column 14: invalid character '}' looking for beginning of object key string"))
+	})
+
+	It("decodes NDJSON collections and locates their errors", func() {
+		value, err := Decode(Input{Source: "orders", Format: NDJSON, Data: "{\"id\": 1}\n\n{\"id\": 2, \"total\": 9.5}\n"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(HaveLen(2))
+
+		_, err = Decode(Input{Source: "orders", Format: NDJSON, Data: "{\"id\": 1}\n{\"id\": 2,, }\n"})
+		Expect(err).To(MatchError(HavePrefix("orders: line 2, column 10: invalid character ','")))
+		_, err = Decode(Input{Source: "orders", Format: NDJSON, Data: "{\"id\": 1}\n[2]\n"})
+		Expect(err).To(MatchError("orders: line 2: records must be objects, got an array"))
+	})
+
+	It("composes the document and the collections into db.json", func() {
+		db, err := Compose(Input{Source: "spec.jsonConfig", Format: YAML, Data: "profile:\n  name: typicode\n"}, map[string]Input{
+			"users":  {Source: "spec.collections[users].data", Format: CSV, Data: "id,name\n1,Alice\n", Columns: map[string]ColumnType{"id": Integer}},
+			"orders": {Source: "spec.collections[orders].data", Format: NDJSON, Data: `{"id": 1, "userId": 1, "note": "<gift>"}`},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(db).To(Equal(`{
+  "orders": [
+    {
+      "id": 1,
+      "note": "<gift>",
+      "userId": 1
+    }
+  ],
+  "profile": {
+    "name": "typicode"
+  },
+  "users": [
+    {
+      "id": 1,
+      "name": "Alice"
+    }
+  ]
+}
+`))
+	})
+
+	It("composes collections without document", func() {
+		Expect(Compose(Input{Source: "spec.jsonConfig"}, map[string]Input{
+			"users": {Source: "spec.collections[users].data", Data: `[{"id": 1}]`},
+		})).To(MatchJSON(`{"users": [{"id": 1}]}`))
+	})
+
+	It("rejects collections already defined by the document", func() {
+		_, err := Compose(Input{Source: "spec.jsonConfig", Data: `{"users": []}`}, map[string]Input{
+			"users": {Source: "spec.collections[users].data", Data: `[]`},
+		})
+		Expect(err).To(MatchError(`spec.collections[users].data: collection "users" is already defined by spec.jsonConfig`))
+	})
+
+	DescribeTable("rejecting values that are not collections",
+		func(document Input, collections map[string]Input, message string) {
+			_, err := Compose(document, collections)
+			Expect(err).To(MatchError(message))
+		},
+		Entry("array document", Input{Source: "spec.jsonConfig", Data: `[]`}, nil,
+			"spec.jsonConfig: must be an object of collections, got an array"),
+		Entry("CSV document", Input{Source: "spec.jsonConfig", Format: CSV, Data: "id\n1\n"}, nil,
+			"spec.jsonConfig: CSV only holds collections, use JSON, YAML or JSON5"),
+		Entry("string collection", Input{Source: "spec.jsonConfig"}, map[string]Input{"name": {Source: "spec.collections[name].data", Data: `"Alice"`}},
+			"spec.collections[name].data: must be an array of records or an object, got a string"),
+	)
+})
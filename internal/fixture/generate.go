@@ -0,0 +1,462 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fixture
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+const (
+	// MaxGeneratedRecords bounds the records of a generated collection
+	MaxGeneratedRecords = 10000
+	// maxRecordSize bounds the output of the template of one record
+	maxRecordSize = 64 * 1024
+	// maxSeed keeps the seeds exact in JSON clients, which read numbers as doubles
+	maxSeed = 1<<53 - 1
+	// maxRangeDepth bounds the nesting of the range actions of a template
+	maxRangeDepth = 2
+	// maxLoopIterations bounds the iterations of the range actions of all the records of a
+	// collection together. Loops writing nothing are not bounded by maxRecordSize.
+	maxLoopIterations = 1000000
+	// maxGenerationTime bounds the time spent rendering a collection
+	maxGenerationTime = 10 * time.Second
+	// loopFunc is the function added to the pipeline of every range action, counting its
+	// iterations
+	loopFunc = "_loop"
+)
+
+// templateLocation extracts the line and column reported in the errors of text/template, and
+// templateExecuting the name of the template they repeat
+var (
+	templateLocation  = regexp.MustCompile(`^template: [^:]*:(\d+):(?:(\d+):)? `)
+	templateExecuting = regexp.MustCompile(`executing "[^"]*" `)
+)
+
+// Generator renders the records of a collection from a template. The records are the same
+// for the same template, count and seed.
+type Generator struct {
+	// Source names the template in errors, e.g. "spec.generate[users].template"
+	Source string
+	// Count is the number of records
+	Count int
+	// Seed of the fake data
+	Seed int64
+	// Template renders one record as a JSON or YAML object. It is executed with the Index (from
+	// 0) and the ID (from 1) of the record.
+	Template string
+}
+
+// record is the data the template of a record is executed with
+type record struct {
+	Index int
+	ID    int
+}
+
+// DefaultSeed returns the seed of a generated collection without one, derived from its name
+// so that the same collection is generated the same way by every JsonServer
+func DefaultSeed(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64() & maxSeed)
+}
+
+// Parse checks the syntax of the template and the functions it calls
+func (g Generator) Parse() error {
+	_, err := g.parse(&faker{})
+	return err
+}
+
+// Generate renders the records. ref picks the ID of a record of the other generated
+// collections, whose counts are given by name. The loops of the template and the time spent
+// rendering are bounded for the whole collection.
+func (g Generator) Generate(counts map[string]int) ([]any, error) {
+	if g.Count > MaxGeneratedRecords {
+		return nil, &Error{Source: g.Source, Msg: fmt.Sprintf("cannot generate more than %d records", MaxGeneratedRecords)}
+	}
+	faker := &faker{
+		rand:     rand.New(rand.NewPCG(uint64(g.Seed), 0)),
+		counts:   counts,
+		deadline: time.Now().Add(maxGenerationTime),
+	}
+	tmpl, err := g.parse(faker)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]any, 0, g.Count)
+	for i := range g.Count {
+		if err := faker.checkDeadline(); err != nil {
+			return nil, &Error{Source: g.Source, Msg: fmt.Sprintf("record %d: %s", i+1, err)}
+		}
+		out := &limitedBuffer{limit: maxRecordSize}
+		if err := tmpl.Execute(out, record{Index: i, ID: i + 1}); err != nil {
+			templateErr := g.templateError(err, i)
+			var budgetErr *budgetError
+			if errors.As(err, &budgetErr) {
+				templateErr.Msg = fmt.Sprintf("record %d: %s", i+1, budgetErr.msg)
+			}
+			return nil, templateErr
+		}
+		value, err := Decode(Input{Source: g.Source, Format: YAML, Data: out.String()})
+		if err != nil {
+			return nil, &Error{Source: g.Source, Msg: fmt.Sprintf("record %d: %s", i+1, errorMsg(err))}
+		}
+		if _, ok := value.(map[string]any); !ok {
+			return nil, &Error{Source: g.Source, Msg: fmt.Sprintf("record %d: records must be objects, got %s", i+1, kind(value))}
+		}
+		records = append(records, value)
+	}
+	return records, nil
+}
+
+// parse parses the template with the functions of the faker, rejects the loops it cannot
+// bound, and makes the faker count the iterations of the others
+func (g Generator) parse(f *faker) (*template.Template, error) {
+	tmpl, err := template.New(g.Source).Option("missingkey=error").Funcs(f.funcs()).Parse(g.Template)
+	if err != nil {
+		return nil, g.templateError(err, -1)
+	}
+	// Templates calling each other can loop without range actions
+	if len(tmpl.Templates()) > 1 {
+		return nil, &Error{Source: g.Source, Msg: "templates cannot be defined"}
+	}
+	if err := g.boundLoops(tmpl.Tree, tmpl.Root, 0); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// boundLoops walks the nodes of the template. It rejects calls to other templates, range
+// actions nested too deeply or over too many integers, and appends loopFunc to the pipeline
+// of the other range actions.
+func (g Generator) boundLoops(tree *parse.Tree, node parse.Node, depth int) error {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return nil
+		}
+		for _, child := range node.Nodes {
+			if err := g.boundLoops(tree, child, depth); err != nil {
+				return err
+			}
+		}
+	case *parse.IfNode:
+		return g.boundBranch(tree, &node.BranchNode, depth)
+	case *parse.WithNode:
+		return g.boundBranch(tree, &node.BranchNode, depth)
+	case *parse.RangeNode:
+		if depth == maxRangeDepth {
+			return g.nodeError(tree, node, fmt.Sprintf("range actions cannot be nested more than %d deep", maxRangeDepth))
+		}
+		if cmds := node.Pipe.Cmds; len(cmds) == 1 && len(cmds[0].Args) == 1 {
+			if number, ok := cmds[0].Args[0].(*parse.NumberNode); ok && number.IsInt && number.Int64 > maxLoopIterations {
+				return g.nodeError(tree, node, fmt.Sprintf("range actions cannot loop more than %d times", maxLoopIterations))
+			}
+		}
+		loop := parse.NewIdentifier(loopFunc).SetTree(tree).SetPos(node.Pos)
+		node.Pipe.Cmds = append(node.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: node.Pos, Args: []parse.Node{loop}})
+		return g.boundBranch(tree, &node.BranchNode, depth+1)
+	case *parse.TemplateNode:
+		return g.nodeError(tree, node, "templates cannot be called")
+	}
+	return nil
+}
+
+// boundBranch walks the lists of an if, range or with action
+func (g Generator) boundBranch(tree *parse.Tree, node *parse.BranchNode, depth int) error {
+	if err := g.boundLoops(tree, node.List, depth); err != nil {
+		return err
+	}
+	return g.boundLoops(tree, node.ElseList, depth)
+}
+
+// nodeError locates an error in the template
+func (g Generator) nodeError(tree *parse.Tree, node parse.Node, msg string) *Error {
+	location, _ := tree.ErrorContext(node)
+	return g.templateError(fmt.Errorf("template: %s: %s", location, msg), -1)
+}
+
+// templateError locates the errors of text/template in the template
+func (g Generator) templateError(err error, index int) *Error {
+	templateErr := &Error{Source: g.Source, Msg: templateExecuting.ReplaceAllString(err.Error(), "")}
+	// The function counting the iterations of range actions is not written in the template
+	templateErr.Msg = strings.Replace(templateErr.Msg, "at <"+loopFunc+">: ", "", 1)
+	if match := templateLocation.FindStringSubmatchIndex(templateErr.Msg); match != nil {
+		templateErr.Line, _ = strconv.Atoi(templateErr.Msg[match[2]:match[3]])
+		if match[4] >= 0 {
+			templateErr.Column, _ = strconv.Atoi(templateErr.Msg[match[4]:match[5]])
+		}
+		templateErr.Msg = templateErr.Msg[match[1]:]
+	}
+	if index >= 0 {
+		templateErr.Msg = fmt.Sprintf("record %d: %s", index+1, templateErr.Msg)
+	}
+	return templateErr
+}
+
+// limitedBuffer fails the writes past its limit, bounding the output of templates looping
+// over large ranges
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, fmt.Errorf("a record cannot be larger than %d bytes", b.limit)
+	}
+	return b.Buffer.Write(p)
+}
+
+// Word lists of the faker
+var (
+	firstNames = []string{
+		"Ada", "Alan", "Alice", "Amara", "Ana", "Ben", "Chen", "Chloe", "Daniel", "Diego",
+		"Elena", "Emma", "Farah", "Grace", "Hana", "Hugo", "Ines", "Ivan", "Jonas", "Julia",
+		"Kenji", "Lea", "Leo", "Lina", "Lucas", "Maya", "Mei", "Nadia", "Noah", "Omar",
+		"Priya", "Rosa", "Sam", "Sara", "Tariq", "Tom", "Uma", "Victor", "Yara", "Zoe",
+	}
+	lastNames = []string{
+		"Abe", "Bauer", "Costa", "Dubois", "Evans", "Fischer", "Garcia", "Haddad", "Ito", "Jensen",
+		"Kim", "Larsen", "Martin", "Nakamura", "Novak", "Okafor", "Patel", "Quinn", "Rossi", "Santos",
+		"Schmidt", "Silva", "Smith", "Tanaka", "Torres", "Usman", "Varga", "Wang", "Weber", "Zhang",
+	}
+	emailDomains = []string{"example.com", "example.net", "example.org"}
+	loremWords   = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod
+		tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation
+		ullamco laboris nisi aliquip ex ea commodo consequat duis aute irure in reprehenderit voluptate
+		velit esse cillum eu fugiat nulla pariatur excepteur sint occaecat cupidatat non proident sunt
+		culpa qui officia deserunt mollit anim id est laborum`)
+)
+
+// Dates are picked in a fixed range unless the template gives one, so that they do not depend
+// on when the records are generated
+var (
+	defaultFromDate = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	defaultToDate   = time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// faker provides the functions of the templates, drawing from a seeded generator
+type faker struct {
+	rand   *rand.Rand
+	counts map[string]int
+	// iterations counts the iterations of the range actions, up to maxLoopIterations
+	iterations int
+	// deadline is the time the records must be rendered by
+	deadline time.Time
+}
+
+// budgetError reports a template running past maxLoopIterations or maxGenerationTime
+type budgetError struct {
+	msg string
+}
+
+func (e *budgetError) Error() string {
+	return e.msg
+}
+
+// loop counts the iterations of the range action over the value, and returns the value
+func (f *faker) loop(value any) (any, error) {
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f.iterations += int(min(max(v.Int(), 0), maxLoopIterations+1))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f.iterations += int(min(v.Uint(), maxLoopIterations+1))
+	case reflect.Array, reflect.Slice, reflect.Map, reflect.String:
+		f.iterations += v.Len()
+	}
+	if f.iterations > maxLoopIterations {
+		return nil, &budgetError{msg: fmt.Sprintf("the range actions of the records cannot loop more than %d times", maxLoopIterations)}
+	}
+	return value, f.checkDeadline()
+}
+
+// checkDeadline fails once the records took longer than maxGenerationTime to render
+func (f *faker) checkDeadline() error {
+	if !f.deadline.IsZero() && time.Now().After(f.deadline) {
+		return &budgetError{msg: fmt.Sprintf("the records cannot take longer than %s to render", maxGenerationTime)}
+	}
+	return nil
+}
+
+// funcs returns the functions of the templates
+func (f *faker) funcs() template.FuncMap {
+	return template.FuncMap{
+		"firstName": func() string { return choose(f.rand, firstNames) },
+		"lastName":  func() string { return choose(f.rand, lastNames) },
+		"name":      func() string { return choose(f.rand, firstNames) + " " + choose(f.rand, lastNames) },
+		"email":     f.email,
+		"uuid":      f.uuid,
+		"date":      func(bounds ...string) (string, error) { return f.date(time.DateOnly, bounds) },
+		"datetime":  func(bounds ...string) (string, error) { return f.date(time.RFC3339, bounds) },
+		"word":      func() string { return choose(f.rand, loremWords) },
+		"words":     f.words,
+		"sentence":  f.sentence,
+		"paragraph": f.paragraph,
+		"int":       f.int,
+		"float":     f.float,
+		"bool":      func() bool { return f.rand.IntN(2) == 1 },
+		"pick":      f.pick,
+		"ref":       f.ref,
+		"json":      toJSON,
+		loopFunc:    f.loop,
+	}
+}
+
+// choose returns a random element of the list
+func choose(r *rand.Rand, list []string) string {
+	return list[r.IntN(len(list))]
+}
+
+// email returns the address of a random person, or of the given name
+func (f *faker) email(name ...string) string {
+	local := strings.Join(name, " ")
+	if local == "" {
+		local = choose(f.rand, firstNames) + " " + choose(f.rand, lastNames)
+	}
+	local = strings.Join(strings.Fields(strings.ToLower(local)), ".")
+	return fmt.Sprintf("%s@%s", local, choose(f.rand, emailDomains))
+}
+
+// uuid returns a random version 4 UUID
+func (f *faker) uuid() string {
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], f.rand.Uint64())
+	binary.LittleEndian.PutUint64(b[8:], f.rand.Uint64())
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// date returns a random time between two dates, 2020-01-01 and 2025-12-31 by default
+func (f *faker) date(layout string, bounds []string) (string, error) {
+	from, to := defaultFromDate, defaultToDate
+	switch len(bounds) {
+	case 0:
+	case 2:
+		var err error
+		if from, err = time.Parse(time.DateOnly, bounds[0]); err != nil {
+			return "", fmt.Errorf("invalid date %q, use YYYY-MM-DD", bounds[0])
+		}
+		if to, err = time.Parse(time.DateOnly, bounds[1]); err != nil {
+			return "", fmt.Errorf("invalid date %q, use YYYY-MM-DD", bounds[1])
+		}
+		if to.Before(from) {
+			return "", fmt.Errorf("%s is before %s", bounds[1], bounds[0])
+		}
+	default:
+		return "", fmt.Errorf("expected no bounds or two dates, got %d arguments", len(bounds))
+	}
+	seconds := int64(to.Sub(from) / time.Second)
+	return from.Add(time.Duration(f.rand.Int64N(seconds+1)) * time.Second).Format(layout), nil
+}
+
+// words returns n random lorem ipsum words
+func (f *faker) words(n int) (string, error) {
+	if n < 1 || n > 1000 {
+		return "", fmt.Errorf("expected between 1 and 1000 words, got %d", n)
+	}
+	words := make([]string, n)
+	for i := range words {
+		words[i] = choose(f.rand, loremWords)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// sentence returns a capitalized lorem ipsum sentence of 4 to 12 words, or of n words
+func (f *faker) sentence(n ...int) (string, error) {
+	count := 4 + f.rand.IntN(9)
+	if len(n) > 0 {
+		count = n[0]
+	}
+	words, err := f.words(count)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(words[:1]) + words[1:] + ".", nil
+}
+
+// paragraph returns 3 to 6 lorem ipsum sentences, or n sentences
+func (f *faker) paragraph(n ...int) (string, error) {
+	count := 3 + f.rand.IntN(4)
+	if len(n) > 0 {
+		count = n[0]
+	}
+	if count < 1 || count > 100 {
+		return "", fmt.Errorf("expected between 1 and 100 sentences, got %d", count)
+	}
+	sentences := make([]string, count)
+	for i := range sentences {
+		sentences[i], _ = f.sentence()
+	}
+	return strings.Join(sentences, " "), nil
+}
+
+// int returns a random integer between min and max, included
+func (f *faker) int(min, max int) (int, error) {
+	if max < min {
+		return 0, fmt.Errorf("%d is less than %d", max, min)
+	}
+	return min + f.rand.IntN(max-min+1), nil
+}
+
+// float returns a random number between min and max with two decimals
+func (f *faker) float(min, max float64) (json.Number, error) {
+	if max < min {
+		return "", fmt.Errorf("%g is less than %g", max, min)
+	}
+	return json.Number(strconv.FormatFloat(min+f.rand.Float64()*(max-min), 'f', 2, 64)), nil
+}
+
+// pick returns one of its arguments at random
+func (f *faker) pick(values ...any) (any, error) {
+	if len(values) == 0 {
+		return nil, errors.New("expected at least one value")
+	}
+	return values[f.rand.IntN(len(values))], nil
+}
+
+// ref returns the ID of a random record of another generated collection
+func (f *faker) ref(collection string) (int, error) {
+	count, ok := f.counts[collection]
+	if !ok {
+		return 0, fmt.Errorf("%q is not a generated collection", collection)
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("collection %q has no records", collection)
+	}
+	return 1 + f.rand.IntN(count), nil
+}
+
+// toJSON writes a value as JSON, e.g. to quote strings holding colons or quotes
+func toJSON(value any) (string, error) {
+	encoded, err := json.Marshal(value)
+	return string(encoded), err
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fixture
+
+import (
+	"encoding/json"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generate", func() {
+	users := Generator{
+		Source: "spec.generate[users].template",
+		Count:  20,
+		Seed:   42,
+		Template: `{"id": {{ .ID }}, "name": {{ name | json }}, "email": "{{ email }}", "uuid": "{{ uuid }}",
+"joined": "{{ date "2024-01-01" "2024-12-31" }}", "bio": {{ sentence | json }}, "teamId": {{ ref "teams" }}}`,
+	}
+	counts := map[string]int{"teams": 3, "users": 20}
+
+	It("renders the same records for the same seed", func() {
+		records, err := users.Generate(counts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records).To(HaveLen(20))
+		Expect(users.Generate(counts)).To(Equal(records))
+
+		reseeded := users
+		reseeded.Seed = 43
+		Expect(reseeded.Generate(counts)).NotTo(Equal(records))
+	})
+
+	It("renders the fake data and the references to other collections", func() {
+		records, err := users.Generate(counts)
+		Expect(err).NotTo(HaveOccurred())
+		for i, value := range records {
+			record := value.(map[string]any)
+			Expect(record["id"]).To(Equal(json.Number(strconv.Itoa(i + 1))))
+			Expect(record["email"]).To(MatchRegexp(`^[a-z]+\.[a-z]+@example\.(com|net|org)$`))
+			Expect(record["uuid"]).To(MatchRegexp(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`))
+			Expect(record["joined"]).To(MatchRegexp(`^2024-\d\d-\d\d$`))
+			Expect(record["teamId"]).To(BeElementOf(json.Number("1"), json.Number("2"), json.Number("3")))
+		}
+	})
+
+	It("reads records written as YAML", func() {
+		tags := Generator{Source: "tags", Count: 2, Template: "id: {{ .ID }}\nlabel: {{ word }}\n"}
+		records, err := tags.Generate(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records).To(HaveLen(2))
+		Expect(records[1]).To(HaveKeyWithValue("id", json.Number("2")))
+	})
+
+	It("locates the errors in the template", func() {
+		broken := Generator{Source: "spec.generate[users].template", Count: 1, Template: "id: {{ .ID }}\nname: {{ nickname }}\n"}
+		Expect(broken.Parse()).To(MatchError(`spec.generate[users].template: line 2: function "nickname" not defined`))
+
+		broken.Template = "id: {{ .ID }}\nteamId: {{ ref \"groups\" }}\n"
+		Expect(broken.Parse()).To(Succeed())
+		_, err := broken.Generate(counts)
+		Expect(err).To(MatchError(`spec.generate[users].template: line 2, column 11: record 1: at <ref "groups">: error calling ref: "groups" is not a generated collection`))
+	})
+
+	It("rejects records that are not objects or too large", func() {
+		list := Generator{Source: "tags", Count: 1, Template: "- {{ word }}"}
+		Expect(list.Generate(nil)).Error().To(MatchError("tags: record 1: records must be objects, got an array"))
+
+		large := Generator{Source: "tags", Count: 1, Template: "{{ range 100000 }}{{ word }} {{ end }}"}
+		Expect(large.Generate(nil)).Error().To(MatchError(ContainSubstring("a record cannot be larger than 65536 bytes")))
+	})
+
+	It("bounds the loops of the templates", func() {
+		looping := Generator{Source: "tags", Count: 1, Template: `{{ range 300000000 }}{{ end }}{"a": 1}`}
+		Expect(looping.Parse()).To(MatchError("tags: line 1, column 9: range actions cannot loop more than 1000000 times"))
+
+		looping.Template = `{{ range (int 300000000 300000000) }}{{ end }}{"a": 1}`
+		Expect(looping.Parse()).To(Succeed())
+		Expect(looping.Generate(nil)).Error().To(MatchError(
+			"tags: line 1, column 9: record 1: the range actions of the records cannot loop more than 1000000 times"))
+
+		looping.Count = 10000
+		looping.Template = `{{ range 1000 }}{{ end }}{"id": {{ .ID }}}`
+		Expect(looping.Generate(nil)).Error().To(MatchError(ContainSubstring(
+			"record 1001: the range actions of the records cannot loop more than 1000000 times")))
+
+		looping.Template = `{{ range 10 }}{{ range 10 }}{{ range 10 }}{{ end }}{{ end }}{{ end }}{"a": 1}`
+		Expect(looping.Parse()).To(MatchError(ContainSubstring("range actions cannot be nested more than 2 deep")))
+
+		looping.Template = `{{ define "loop" }}{{ template "loop" }}{{ end }}{{ template "loop" }}{"a": 1}`
+		Expect(looping.Parse()).To(MatchError("tags: templates cannot be defined"))
+		looping.Template = `{{ template "loop" }}{"a": 1}`
+		Expect(looping.Parse()).To(MatchError(ContainSubstring("templates cannot be called")))
+
+		looping.Count = 2
+		looping.Template = `{"tags": [{{ range $i := 3 }}{{ if $i }}, {{ end }}{{ word | json }}{{ end }}]}`
+		records, err := looping.Generate(nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(records[0]).To(HaveKeyWithValue("tags", HaveLen(3)))
+	})
+
+	It("derives the default seed from the name of the collection", func() {
+		Expect(DefaultSeed("users")).To(Equal(DefaultSeed("users")))
+		Expect(DefaultSeed("users")).NotTo(Equal(DefaultSeed("teams")))
+		Expect(DefaultSeed("users")).To(BeNumerically("<", int64(1)<<53))
+	})
+})
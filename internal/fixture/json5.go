@@ -0,0 +1,385 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fixture
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// jsonNumber matches the numbers of the JSON grammar
+var jsonNumber = regexp.MustCompile(`^(0|[1-9][0-9]*)(\.[0-9]+)?(e[+-]?[0-9]+)?$`)
+
+// json5Parser decodes JSON5 documents: JSON with comments, trailing commas, unquoted keys,
+// single-quoted strings and hexadecimal numbers, see https://spec.json5.org
+type json5Parser struct {
+	source string
+	data   string
+	pos    int
+}
+
+// decodeJSON5 decodes a JSON5 document into its JSON value
+func decodeJSON5(source, data string) (any, error) {
+	p := &json5Parser{source: source, data: data}
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	value, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.skipSpace(); err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.data) {
+		return nil, p.errorf("unexpected %s after the top-level value", p.describe())
+	}
+	return value, nil
+}
+
+// errorf returns an Error located at the current position
+func (p *json5Parser) errorf(format string, args ...any) *Error {
+	done := p.data[:min(p.pos, len(p.data))]
+	lineStart := strings.LastIndexByte(done, '\n') + 1
+	return &Error{
+		Source: p.source,
+		Line:   strings.Count(done, "\n") + 1,
+		Column: utf8.RuneCountInString(done[lineStart:]) + 1,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
+
+// describe names the character at the current position in errors
+func (p *json5Parser) describe() string {
+	if p.pos >= len(p.data) {
+		return "end of input"
+	}
+	r, _ := utf8.DecodeRuneInString(p.data[p.pos:])
+	return fmt.Sprintf("character %q", r)
+}
+
+// skipSpace skips the white space and the comments
+func (p *json5Parser) skipSpace() error {
+	for p.pos < len(p.data) {
+		r, size := utf8.DecodeRuneInString(p.data[p.pos:])
+		switch {
+		case unicode.IsSpace(r) || r == '\uFEFF':
+			p.pos += size
+		case strings.HasPrefix(p.data[p.pos:], "//"):
+			end := strings.IndexByte(p.data[p.pos:], '\n')
+			if end < 0 {
+				p.pos = len(p.data)
+			} else {
+				p.pos += end + 1
+			}
+		case strings.HasPrefix(p.data[p.pos:], "/*"):
+			end := strings.Index(p.data[p.pos+2:], "*/")
+			if end < 0 {
+				return p.errorf("unterminated comment")
+			}
+			p.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// value parses the value at the current position, which is not white space
+func (p *json5Parser) value() (any, error) {
+	if p.pos >= len(p.data) {
+		return nil, p.errorf("unexpected end of input, expecting a value")
+	}
+	switch c := p.data[p.pos]; {
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"' || c == '\'':
+		return p.string()
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		return p.number()
+	default:
+		word := p.identifier()
+		switch word {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		case "Infinity", "NaN":
+			p.pos -= len(word)
+			return nil, p.errorf("%s cannot be represented in JSON", word)
+		}
+		p.pos -= len(word)
+		return nil, p.errorf("unexpected %s, expecting a value", p.describe())
+	}
+}
+
+// object parses an object, whose keys are strings or identifiers
+func (p *json5Parser) object() (any, error) {
+	object := map[string]any{}
+	p.pos++
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == '}' {
+			p.pos++
+			return object, nil
+		}
+
+		var key string
+		if p.pos < len(p.data) && (p.data[p.pos] == '"' || p.data[p.pos] == '\'') {
+			value, err := p.string()
+			if err != nil {
+				return nil, err
+			}
+			key = value.(string)
+		} else if key = p.identifier(); key == "" {
+			return nil, p.errorf("unexpected %s, expecting a key or '}'", p.describe())
+		}
+
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+			return nil, p.errorf("unexpected %s, expecting ':' after key %q", p.describe(), key)
+		}
+		p.pos++
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		object[key] = value
+
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == '}' {
+			p.pos++
+			return object, nil
+		}
+		return nil, p.errorf("unexpected %s, expecting ',' or '}'", p.describe())
+	}
+}
+
+// array parses an array
+func (p *json5Parser) array() (any, error) {
+	array := []any{}
+	p.pos++
+	for {
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == ']' {
+			p.pos++
+			return array, nil
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+
+		if err := p.skipSpace(); err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if p.pos < len(p.data) && p.data[p.pos] == ']' {
+			p.pos++
+			return array, nil
+		}
+		return nil, p.errorf("unexpected %s, expecting ',' or ']'", p.describe())
+	}
+}
+
+// identifier consumes the identifier at the current position, empty when there is none
+func (p *json5Parser) identifier() string {
+	start := p.pos
+	for p.pos < len(p.data) {
+		r, size := utf8.DecodeRuneInString(p.data[p.pos:])
+		if r != '_' && r != '$' && !unicode.IsLetter(r) && (p.pos == start || !unicode.IsDigit(r)) {
+			break
+		}
+		p.pos += size
+	}
+	return p.data[start:p.pos]
+}
+
+// string parses a single- or double-quoted string
+func (p *json5Parser) string() (any, error) {
+	quote := p.data[p.pos]
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unterminated string")
+		}
+		c := p.data[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		case c == '\n' || c == '\r':
+			return nil, p.errorf("unterminated string, escape the line break with '\\'")
+		case c != '\\':
+			b.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		// Escape sequence
+		p.pos++
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unterminated string")
+		}
+		escaped := p.data[p.pos]
+		p.pos++
+		switch escaped {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case '0':
+			b.WriteByte(0)
+		case '\r':
+			// Line continuation, \r\n counts as one line break
+			if p.pos < len(p.data) && p.data[p.pos] == '\n' {
+				p.pos++
+			}
+		case '\n':
+			// Line continuation
+		case 'x', 'u':
+			digits := 2
+			if escaped == 'u' {
+				digits = 4
+			}
+			if p.pos+digits > len(p.data) {
+				return nil, p.errorf("invalid \\%c escape", escaped)
+			}
+			code, err := strconv.ParseUint(p.data[p.pos:p.pos+digits], 16, 32)
+			if err != nil {
+				return nil, p.errorf("invalid \\%c escape", escaped)
+			}
+			p.pos += digits
+			r := rune(code)
+			// Characters outside the Basic Multilingual Plane are escaped as surrogate pairs
+			if utf16.IsSurrogate(r) && strings.HasPrefix(p.data[p.pos:], "\\u") && p.pos+6 <= len(p.data) {
+				if low, err := strconv.ParseUint(p.data[p.pos+2:p.pos+6], 16, 32); err == nil {
+					if decoded := utf16.DecodeRune(r, rune(low)); decoded != utf8.RuneError {
+						r = decoded
+						p.pos += 6
+					}
+				}
+			}
+			b.WriteRune(r)
+		default:
+			// Any other character stands for itself, e.g. \' or \"
+			p.pos--
+			r, size := utf8.DecodeRuneInString(p.data[p.pos:])
+			b.WriteRune(r)
+			p.pos += size
+		}
+	}
+}
+
+// number parses a decimal or hexadecimal number, with an optional sign and optional digits
+// before or after the decimal point
+func (p *json5Parser) number() (any, error) {
+	start := p.pos
+	sign := ""
+	if c := p.data[p.pos]; c == '+' || c == '-' {
+		if c == '-' {
+			sign = "-"
+		}
+		p.pos++
+	}
+	word := p.identifier()
+	if word == "Infinity" || word == "NaN" {
+		literal := p.data[start:p.pos]
+		p.pos = start
+		return nil, p.errorf("%s cannot be represented in JSON", literal)
+	}
+	p.pos -= len(word)
+
+	rest := p.data[p.pos:]
+	if strings.HasPrefix(rest, "0x") || strings.HasPrefix(rest, "0X") {
+		p.pos += 2
+		digitsStart := p.pos
+		for p.pos < len(p.data) && strings.IndexByte("0123456789abcdefABCDEF", p.data[p.pos]) >= 0 {
+			p.pos++
+		}
+		value, ok := new(big.Int).SetString(p.data[digitsStart:p.pos], 16)
+		if !ok {
+			return nil, p.errorf("invalid hexadecimal number")
+		}
+		return json.Number(sign + value.String()), nil
+	}
+
+	digitsStart := p.pos
+	for p.pos < len(p.data) && strings.IndexByte("0123456789.eE+-", p.data[p.pos]) >= 0 {
+		// A sign only follows an exponent
+		if c := p.data[p.pos]; (c == '+' || c == '-') && p.data[p.pos-1] != 'e' && p.data[p.pos-1] != 'E' {
+			break
+		}
+		p.pos++
+	}
+	text := p.data[digitsStart:p.pos]
+
+	// Write the number the way JSON does: no leading or trailing decimal point
+	mantissa, exponent, hasExponent := strings.Cut(strings.ToLower(text), "e")
+	if strings.HasPrefix(mantissa, ".") {
+		mantissa = "0" + mantissa
+	}
+	mantissa = strings.TrimSuffix(mantissa, ".")
+	if hasExponent {
+		mantissa += "e" + exponent
+	}
+	if !jsonNumber.MatchString(mantissa) {
+		literal := p.data[start:p.pos]
+		p.pos = start
+		return nil, p.errorf("invalid number %q", literal)
+	}
+	return json.Number(sign + mantissa), nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fixture
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JSON5", func() {
+	It("decodes comments, trailing commas, unquoted keys and single-quoted strings", func() {
+		value, err := decodeJSON5("spec.jsonConfig", `// Fixtures of the checkout tests
+{
+  people: [
+    /* the admin */
+    {id: 1, name: 'Alice', 'quote': 'It\'s "fine"', $tag: "a\
+b",},
+  ],
+}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal(map[string]any{
+			"people": []any{map[string]any{"id": json.Number("1"), "name": "Alice", "quote": `It's "fine"`, "$tag": "ab"}},
+		}))
+	})
+
+	DescribeTable("decoding numbers",
+		func(input string, number json.Number) {
+			Expect(decodeJSON5("n", input)).To(Equal(number))
+		},
+		Entry("hexadecimal", "0x1F", json.Number("31")),
+		Entry("negative hexadecimal", "-0xff", json.Number("-255")),
+		Entry("leading decimal point", ".5", json.Number("0.5")),
+		Entry("trailing decimal point", "5.", json.Number("5")),
+		Entry("explicit plus sign", "+1.5e3", json.Number("1.5e3")),
+	)
+
+	It("decodes the escapes of the strings", func() {
+		Expect(decodeJSON5("s", `'\x41é😀\t\v\0'`)).To(Equal("Aé😀\t\v\x00"))
+	})
+
+	DescribeTable("locating errors",
+		func(input, message string) {
+			_, err := decodeJSON5("spec.jsonConfig", input)
+			Expect(err).To(MatchError(message))
+		},
+		Entry("missing comma", "{\n  a: 1\n  b: 2\n}", `spec.jsonConfig: line 3, column 3: unexpected character 'b', expecting ',' or '}'`),
+		Entry("Infinity", "{a: -Infinity}", "spec.jsonConfig: line 1, column 5: -Infinity cannot be represented in JSON"),
+		Entry("leading zero", "[007]", `spec.jsonConfig: line 1, column 2: invalid number "007"`),
+		Entry("unterminated comment", "{} /* done", "spec.jsonConfig: line 1, column 4: unterminated comment"),
+		Entry("line break in a string", "['a\nb']", `spec.jsonConfig: line 1, column 4: unterminated string, escape the line break with '\'`),
+		Entry("trailing value", "{} {}", `spec.jsonConfig: line 1, column 4: unexpected character '{' after the top-level value`),
+	)
+})
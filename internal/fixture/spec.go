@@ -0,0 +1,97 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fixture
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+// Converts reports whether the configuration of the spec is converted from another format or
+// completed with collections, rather than served as is
+func Converts(spec *examplev1.JsonServerSpec) bool {
+	return (spec.Format != "" && spec.Format != examplev1.FormatJSON) || len(spec.Collections) > 0 || len(spec.Generate) > 0
+}
+
+// CollectionPath is the field holding the data of a collection of the spec, which names the
+// collection in errors
+func CollectionPath(name string) *field.Path {
+	return field.NewPath("spec", "collections").Key(name).Child("data")
+}
+
+// Inline reports whether the collections of the spec are all written in the spec, rather than
+// read from other objects or rendered from templates
+func Inline(spec *examplev1.JsonServerSpec) bool {
+	if len(spec.Generate) > 0 {
+		return false
+	}
+	for _, collection := range spec.Collections {
+		if collection.Data == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// TemplatePath is the field holding the template of a generated collection of the spec
+func TemplatePath(name string) *field.Path {
+	return field.NewPath("spec", "generate").Key(name).Child("template")
+}
+
+// Generators returns the generators of the collections rendered from templates, seeded with
+// the seed of the spec or the default one of the collection
+func Generators(spec *examplev1.JsonServerSpec) map[string]Generator {
+	generators := make(map[string]Generator, len(spec.Generate))
+	for name, generate := range spec.Generate {
+		seed := DefaultSeed(name)
+		if generate.Seed != nil {
+			seed = *generate.Seed
+		}
+		generators[name] = Generator{
+			Source:   TemplatePath(name).String(),
+			Count:    int(generate.Count),
+			Seed:     seed,
+			Template: generate.Template,
+		}
+	}
+	return generators
+}
+
+// Collections returns the inputs of the collections written in the spec
+func Collections(spec *examplev1.JsonServerSpec) map[string]Input {
+	inputs := make(map[string]Input, len(spec.Collections))
+	for name, collection := range spec.Collections {
+		if collection.Data != "" {
+			inputs[name] = NewInput(CollectionPath(name).String(), collection.Format, collection.Data, collection.Columns)
+		}
+	}
+	return inputs
+}
+
+// NewInput returns the input of a collection written in the given format, with the types of
+// its CSV columns
+func NewInput(source, format, data string, columns []examplev1.CSVColumn) Input {
+	input := Input{Source: source, Format: Format(format), Data: data}
+	if len(columns) > 0 {
+		input.Columns = make(map[string]ColumnType, len(columns))
+		for _, column := range columns {
+			input.Columns[column.Name] = ColumnType(column.Type)
+		}
+	}
+	return input
+}
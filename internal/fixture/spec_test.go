@@ -0,0 +1,47 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fixture
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+var _ = Describe("Spec", func() {
+	It("should only convert configurations written in other formats or completed with collections", func() {
+		Expect(Converts(&examplev1.JsonServerSpec{})).To(BeFalse())
+		Expect(Converts(&examplev1.JsonServerSpec{Format: examplev1.FormatJSON})).To(BeFalse())
+		Expect(Converts(&examplev1.JsonServerSpec{Format: examplev1.FormatYAML})).To(BeTrue())
+		Expect(Converts(&examplev1.JsonServerSpec{Collections: map[string]examplev1.CollectionSource{"users": {Data: "[]"}}})).To(BeTrue())
+	})
+
+	It("should name the collections written in the spec after their field and type their CSV columns", func() {
+		spec := &examplev1.JsonServerSpec{Collections: map[string]examplev1.CollectionSource{
+			"users": {Format: examplev1.FormatCSV, Data: "id\n1\n", Columns: []examplev1.CSVColumn{{Name: "id", Type: examplev1.ColumnInteger}}},
+		}}
+		Expect(Inline(spec)).To(BeTrue())
+		Expect(Collections(spec)).To(Equal(map[string]Input{
+			"users": {Source: "spec.collections[users].data", Format: CSV, Data: "id\n1\n", Columns: map[string]ColumnType{"id": Integer}},
+		}))
+
+		spec.Collections["orders"] = examplev1.CollectionSource{FixtureRef: &examplev1.CollectionReference{Name: "shared"}}
+		Expect(Inline(spec)).To(BeFalse())
+		Expect(Collections(spec)).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,64 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package image parses the container image references of the json-server pods.
+package image
+
+import (
+	"path"
+	"strings"
+
+	"github.com/distribution/reference"
+)
+
+// Registry returns the registry an image is pulled from, "docker.io" for the images
+// of Docker Hub such as "backplane/json-server"
+func Registry(image string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "", err
+	}
+	return reference.Domain(named), nil
+}
+
+// RegistryAllowed reports whether the image is pulled from a registry matching one of
+// the glob patterns. Any registry is allowed when there is no pattern.
+func RegistryAllowed(image string, patterns []string) (bool, error) {
+	registry, err := Registry(image)
+	if err != nil {
+		return false, err
+	}
+	if len(patterns) == 0 {
+		return true, nil
+	}
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, registry); err == nil && matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Digest returns the repository digest of the image ID reported in a container status, such
+// as "docker.io/backplane/json-server@sha256:..." or "docker-pullable://...@sha256:...".
+// It returns an empty string when the image ID is a local image ID without repository digest.
+func Digest(imageID string) string {
+	i := strings.LastIndex(imageID, "@")
+	if i < 0 || !strings.HasPrefix(imageID[i+1:], "sha256:") {
+		return ""
+	}
+	return imageID[i+1:]
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package image
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Image references", func() {
+	DescribeTable("resolving the registry",
+		func(image, registry string) {
+			Expect(Registry(image)).To(Equal(registry))
+		},
+		Entry("Docker Hub", "backplane/json-server", "docker.io"),
+		Entry("Docker Hub with tag", "backplane/json-server:0.17.4", "docker.io"),
+		Entry("private registry", "registry.example.com/mirror/json-server:1.0", "registry.example.com"),
+		Entry("registry with port", "localhost:5000/json-server", "localhost:5000"),
+	)
+
+	It("rejects invalid references", func() {
+		_, err := Registry("Backplane/JSON-server")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("matches the registry against glob patterns", func() {
+		Expect(RegistryAllowed("registry.example.com/json-server", []string{"*.example.com"})).To(BeTrue())
+		Expect(RegistryAllowed("backplane/json-server", []string{"*.example.com"})).To(BeFalse())
+		Expect(RegistryAllowed("backplane/json-server", nil)).To(BeTrue())
+	})
+
+	DescribeTable("extracting the digest of an image ID",
+		func(imageID, digest string) {
+			Expect(Digest(imageID)).To(Equal(digest))
+		},
+		Entry("containerd", "docker.io/backplane/json-server@sha256:0123abcd", "sha256:0123abcd"),
+		Entry("docker", "docker-pullable://backplane/json-server@sha256:0123abcd", "sha256:0123abcd"),
+		Entry("local image", "sha256:0123abcd", ""),
+		Entry("empty", "", ""),
+	)
+})
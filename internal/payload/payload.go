@@ -0,0 +1,97 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package payload fits the documents served by json-server in ConfigMaps, whose data is
+// limited to 1 MiB. Documents are stored as is when they fit in one ConfigMap, compressed
+// with gzip and split across several ConfigMaps otherwise.
+package payload
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// ChunkSize is the largest document, or chunk of a compressed document, stored in one
+	// ConfigMap. It leaves room under the 1 MiB limit for the routes and the metadata.
+	ChunkSize = 768 * 1024
+	// MaxChunks is the largest number of ConfigMaps a compressed document is split across
+	MaxChunks = 8
+	// MaxCompressedSize is the largest size of a compressed document
+	MaxCompressedSize = MaxChunks * ChunkSize
+)
+
+// ErrTooLarge is returned for documents still larger than MaxCompressedSize once compressed
+var ErrTooLarge = errors.New("document too large")
+
+// Encode returns the chunks of the compressed document, in order, or nil when the document
+// fits in one ConfigMap as is
+func Encode(data string) ([][]byte, error) {
+	if len(data) <= ChunkSize {
+		return nil, nil
+	}
+	compressed, err := Compress(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(compressed) > MaxCompressedSize {
+		return nil, fmt.Errorf("%w: %d bytes compress to %d bytes, more than the %d bytes of %d ConfigMaps",
+			ErrTooLarge, len(data), len(compressed), MaxCompressedSize, MaxChunks)
+	}
+
+	chunks := make([][]byte, 0, (len(compressed)+ChunkSize-1)/ChunkSize)
+	for len(compressed) > 0 {
+		n := min(len(compressed), ChunkSize)
+		chunks = append(chunks, compressed[:n])
+		compressed = compressed[n:]
+	}
+	return chunks, nil
+}
+
+// Compress returns the gzip stream of the document. The stream has no name nor modification
+// time, compressing the same document always gives the same bytes.
+func Compress(data string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(writer, data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress returns the document of a gzip stream, such as the concatenated chunks returned
+// by Encode
+func Decompress(compressed []byte) (string, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close() //nolint:errcheck
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
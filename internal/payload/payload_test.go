@@ -0,0 +1,78 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package payload
+
+import (
+	"bytes"
+	"encoding/base64"
+	"math/rand/v2"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// incompressible returns a document of n bytes that gzip barely shrinks
+func incompressible(n int) string {
+	random := make([]byte, n*3/4)
+	rng := rand.NewChaCha8([32]byte{})
+	_, _ = rng.Read(random)
+	return `{"blobs":["` + base64.StdEncoding.EncodeToString(random) + `"]}`
+}
+
+var _ = Describe("Payloads", func() {
+	It("keeps the documents fitting in one ConfigMap as is", func() {
+		Expect(Encode(`{"people":[]}`)).To(BeNil())
+		Expect(Encode(strings.Repeat(" ", ChunkSize))).To(BeNil())
+	})
+
+	It("compresses larger documents into one chunk when they compress well", func() {
+		data := `{"people":[` + strings.Repeat(`{"name":"Alice"},`, 100000) + `{"name":"Bob"}]}`
+		chunks, err := Encode(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(chunks).To(HaveLen(1))
+		Expect(len(chunks[0])).To(BeNumerically("<", len(data)/10))
+		Expect(Decompress(chunks[0])).To(Equal(data))
+	})
+
+	It("splits compressed documents across chunks that reassemble into the document", func() {
+		data := incompressible(2 * ChunkSize)
+		chunks, err := Encode(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(len(chunks)).To(BeNumerically(">", 1))
+		for _, chunk := range chunks[:len(chunks)-1] {
+			Expect(chunk).To(HaveLen(ChunkSize))
+		}
+		Expect(Decompress(bytes.Join(chunks, nil))).To(Equal(data))
+	})
+
+	It("compresses the same document into the same bytes", func() {
+		data := incompressible(ChunkSize + 1)
+		Expect(Encode(data)).To(Equal(must(Encode(data))))
+	})
+
+	It("rejects documents larger than the chunks once compressed", func() {
+		_, err := Encode(incompressible(2 * MaxCompressedSize))
+		Expect(err).To(MatchError(ErrTooLarge))
+		Expect(err).To(MatchError(ContainSubstring("more than the 6291456 bytes of 8 ConfigMaps")))
+	})
+})
+
+func must(chunks [][]byte, err error) [][]byte {
+	Expect(err).NotTo(HaveOccurred())
+	return chunks
+}
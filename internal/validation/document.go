@@ -0,0 +1,266 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Options describe the json-server conventions a document is checked against
+type Options struct {
+	// IDField is the field identifying the records of a collection
+	IDField string
+	// ForeignKeySuffix marks the fields referencing a record of another collection,
+	// e.g. "postId" references the "posts" collection with the default "Id" suffix
+	ForeignKeySuffix string
+}
+
+// DefaultOptions are the json-server defaults
+var DefaultOptions = Options{
+	IDField:          "id",
+	ForeignKeySuffix: "Id",
+}
+
+// Error is a structural problem found at a location of the document
+type Error struct {
+	// Pointer is the RFC 6901 JSON pointer to the offending value
+	Pointer string
+	// Detail describes the problem
+	Detail string
+}
+
+func (e Error) Error() string {
+	if e.Pointer == "" {
+		return fmt.Sprintf("document root: %s", e.Detail)
+	}
+	return fmt.Sprintf("%s: %s", e.Pointer, e.Detail)
+}
+
+// ErrorList is the list of structural problems of a document
+type ErrorList []Error
+
+func (l ErrorList) Error() string {
+	messages := make([]string, 0, len(l))
+	for _, err := range l {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks that input is valid JSON with the shape json-server expects.
+// It returns a *SyntaxError if the document cannot be parsed, an ErrorList if
+// it has structural problems and nil otherwise.
+func Validate(input string, opts Options) error {
+	if err := ValidateJSON(input); err != nil {
+		return err
+	}
+	if errs := ValidateDocument(input, opts); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateDocument checks that a syntactically valid JSON document is a top-level
+// object whose keys are collections (arrays of objects with unique ids of a single
+// type) or singular resources (objects), and that the foreign keys of the records
+// reference existing records.
+func ValidateDocument(input string, opts Options) ErrorList {
+	if opts.IDField == "" {
+		opts.IDField = DefaultOptions.IDField
+	}
+	if opts.ForeignKeySuffix == "" {
+		opts.ForeignKeySuffix = DefaultOptions.ForeignKeySuffix
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(input)))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return ErrorList{{Pointer: "", Detail: err.Error()}}
+	}
+
+	root, ok := document.(map[string]interface{})
+	if !ok {
+		return ErrorList{{Pointer: "", Detail: fmt.Sprintf("must be an object of collections, got %s", typeName(document))}}
+	}
+
+	var errs ErrorList
+	collections := map[string]map[string]bool{}
+	for _, name := range sortedKeys(root) {
+		pointer := "/" + escapePointer(name)
+		switch value := root[name].(type) {
+		case []interface{}:
+			var collectionErrs ErrorList
+			collections[name], collectionErrs = validateCollection(pointer, value, opts)
+			errs = append(errs, collectionErrs...)
+		case map[string]interface{}:
+			// Singular resource, served as is
+		default:
+			errs = append(errs, Error{Pointer: pointer,
+				Detail: fmt.Sprintf("must be a collection (array of objects) or a singular resource (object), got %s", typeName(value))})
+		}
+	}
+
+	for _, name := range sortedKeys(root) {
+		records, ok := root[name].([]interface{})
+		if !ok {
+			continue
+		}
+		errs = append(errs, validateForeignKeys("/"+escapePointer(name), records, collections, opts)...)
+	}
+
+	return errs
+}
+
+// validateCollection checks the records of a collection and returns the set of their ids
+func validateCollection(pointer string, records []interface{}, opts Options) (map[string]bool, ErrorList) {
+	var errs ErrorList
+	ids := map[string]bool{}
+	idType := ""
+	for i, value := range records {
+		recordPointer := fmt.Sprintf("%s/%d", pointer, i)
+		record, ok := value.(map[string]interface{})
+		if !ok {
+			errs = append(errs, Error{Pointer: recordPointer, Detail: fmt.Sprintf("must be an object, got %s", typeName(value))})
+			continue
+		}
+
+		id, ok := record[opts.IDField]
+		if !ok {
+			continue
+		}
+		idPointer := recordPointer + "/" + escapePointer(opts.IDField)
+		switch id.(type) {
+		case string, json.Number:
+		default:
+			errs = append(errs, Error{Pointer: idPointer, Detail: fmt.Sprintf("must be a string or a number, got %s", typeName(id))})
+			continue
+		}
+
+		if idType == "" {
+			idType = typeName(id)
+		} else if typeName(id) != idType {
+			errs = append(errs, Error{Pointer: idPointer,
+				Detail: fmt.Sprintf("is a %s but previous ids of the collection are %ss", typeName(id), idType)})
+		}
+
+		key := fmt.Sprint(id)
+		if ids[key] {
+			errs = append(errs, Error{Pointer: idPointer, Detail: fmt.Sprintf("duplicate id %s", formatID(id))})
+		}
+		ids[key] = true
+	}
+	return ids, errs
+}
+
+// validateForeignKeys checks that the foreign keys of the records reference existing records
+func validateForeignKeys(pointer string, records []interface{}, collections map[string]map[string]bool, opts Options) ErrorList {
+	var errs ErrorList
+	for i, value := range records {
+		record, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, field := range sortedKeys(record) {
+			if field == opts.IDField || !strings.HasSuffix(field, opts.ForeignKeySuffix) || len(field) == len(opts.ForeignKeySuffix) {
+				continue
+			}
+			target, ids := referencedCollection(strings.TrimSuffix(field, opts.ForeignKeySuffix), collections)
+			if ids == nil || record[field] == nil {
+				continue
+			}
+			switch ref := record[field].(type) {
+			case string, json.Number:
+				if !ids[fmt.Sprint(ref)] {
+					errs = append(errs, Error{Pointer: fmt.Sprintf("%s/%d/%s", pointer, i, escapePointer(field)),
+						Detail: fmt.Sprintf("references missing record %s of collection %q", formatID(ref), target)})
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// referencedCollection returns the collection (and its ids) a foreign key prefix such as
+// "post" refers to, following the pluralization json-server applies
+func referencedCollection(prefix string, collections map[string]map[string]bool) (string, map[string]bool) {
+	for _, name := range []string{pluralize(prefix), prefix} {
+		if ids, ok := collections[name]; ok {
+			return name, ids
+		}
+	}
+	return "", nil
+}
+
+// pluralize returns the plural of an english noun for the common cases
+func pluralize(noun string) string {
+	lower := strings.ToLower(noun)
+	switch {
+	case strings.HasSuffix(lower, "y") && len(lower) > 1 && !strings.ContainsAny(lower[len(lower)-2:len(lower)-1], "aeiou"):
+		return noun[:len(noun)-1] + "ies"
+	case strings.HasSuffix(lower, "s"), strings.HasSuffix(lower, "x"), strings.HasSuffix(lower, "z"),
+		strings.HasSuffix(lower, "ch"), strings.HasSuffix(lower, "sh"):
+		return noun + "es"
+	default:
+		return noun + "s"
+	}
+}
+
+// typeName returns the JSON type name of a decoded value
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// formatID renders an id the way it is written in the document
+func formatID(id interface{}) string {
+	if s, ok := id.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(id)
+}
+
+// escapePointer escapes a reference token of a JSON pointer
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// sortedKeys returns the keys of an object in a stable order
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
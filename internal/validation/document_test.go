@@ -0,0 +1,106 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateDocument", func() {
+	It("accepts collections and singular resources", func() {
+		Expect(ValidateDocument(`{
+			"posts": [{"id": 1, "title": "json-server"}, {"id": 2, "title": "operators"}],
+			"comments": [{"id": "a", "body": "nice", "postId": 1}, {"id": "b", "postId": null}],
+			"profile": {"name": "typicode"}
+		}`, DefaultOptions)).To(BeEmpty())
+	})
+
+	It("requires a top-level object", func() {
+		errs := ValidateDocument(`[{"id": 1}]`, DefaultOptions)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Pointer).To(Equal(""))
+		Expect(errs[0].Error()).To(ContainSubstring("document root"))
+	})
+
+	It("rejects top-level values that are neither collections nor singular resources", func() {
+		errs := ValidateDocument(`{"version": 3}`, DefaultOptions)
+		Expect(errs).To(ConsistOf(Error{Pointer: "/version",
+			Detail: "must be a collection (array of objects) or a singular resource (object), got number"}))
+	})
+
+	It("flags non-object members of a collection", func() {
+		errs := ValidateDocument(`{"tags": [{"id": 1}, "featured"]}`, DefaultOptions)
+		Expect(errs).To(ConsistOf(Error{Pointer: "/tags/1", Detail: "must be an object, got string"}))
+	})
+
+	It("flags duplicate ids", func() {
+		errs := ValidateDocument(`{"posts": [{"id": 1}, {"id": 2}, {"id": 1}]}`, DefaultOptions)
+		Expect(errs).To(ConsistOf(Error{Pointer: "/posts/2/id", Detail: "duplicate id 1"}))
+	})
+
+	It("flags mixed id types", func() {
+		errs := ValidateDocument(`{"posts": [{"id": 1}, {"id": "2"}, {"id": true}]}`, DefaultOptions)
+		Expect(errs).To(ConsistOf(
+			Error{Pointer: "/posts/1/id", Detail: "is a string but previous ids of the collection are numbers"},
+			Error{Pointer: "/posts/2/id", Detail: "must be a string or a number, got boolean"},
+		))
+	})
+
+	It("flags foreign keys referencing missing records", func() {
+		errs := ValidateDocument(`{
+			"categories": [{"id": 1}],
+			"posts": [{"id": 1, "categoryId": 1}, {"id": 2, "categoryId": 7}],
+			"comments": [{"id": 1, "postId": 3}, {"id": 2, "authorId": 5}]
+		}`, DefaultOptions)
+		Expect(errs).To(ConsistOf(
+			Error{Pointer: "/comments/0/postId", Detail: `references missing record 3 of collection "posts"`},
+			Error{Pointer: "/posts/1/categoryId", Detail: `references missing record 7 of collection "categories"`},
+		))
+	})
+
+	It("honours custom id fields and foreign key suffixes", func() {
+		errs := ValidateDocument(`{
+			"users": [{"_id": "u1"}, {"_id": "u1"}],
+			"posts": [{"_id": "p1", "user_id": "u2"}]
+		}`, Options{IDField: "_id", ForeignKeySuffix: "_id"})
+		Expect(errs).To(ConsistOf(
+			Error{Pointer: "/posts/0/user_id", Detail: `references missing record "u2" of collection "users"`},
+			Error{Pointer: "/users/1/_id", Detail: `duplicate id "u1"`},
+		))
+	})
+
+	It("escapes JSON pointer tokens", func() {
+		errs := ValidateDocument(`{"a/b~c": 1}`, DefaultOptions)
+		Expect(errs).To(HaveLen(1))
+		Expect(errs[0].Pointer).To(Equal("/a~1b~0c"))
+	})
+})
+
+var _ = Describe("Validate", func() {
+	It("returns syntax errors before structural ones", func() {
+		Expect(Validate(`{"posts": [`, DefaultOptions)).To(BeAssignableToTypeOf(&SyntaxError{}))
+	})
+
+	It("returns structural errors as an ErrorList", func() {
+		Expect(Validate(`{"posts": [1]}`, DefaultOptions)).To(BeAssignableToTypeOf(ErrorList{}))
+	})
+
+	It("accepts valid documents", func() {
+		Expect(Validate(`{"posts": []}`, DefaultOptions)).To(Succeed())
+	})
+})
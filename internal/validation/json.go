@@ -0,0 +1,103 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package validation checks that a JsonServer configuration can be served by
+// json-server. It is shared by the admission webhook and the controller.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// snippetRadius is the number of characters shown on each side of a syntax error
+const snippetRadius = 20
+
+// SyntaxError describes where a JSON document failed to parse
+type SyntaxError struct {
+	// Offset is the byte offset of the error in the document
+	Offset int64
+	// Line is the 1-based line of the error
+	Line int
+	// Column is the 1-based column (in characters) of the error
+	Column int
+	// Snippet is the text surrounding the error on its line
+	Snippet string
+	// Msg is the parser error message
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at line %d, column %d (byte offset %d) near %q", e.Msg, e.Line, e.Column, e.Offset, e.Snippet)
+}
+
+// ValidateJSON checks that input is a syntactically valid JSON document and
+// returns a *SyntaxError pointing at the first error otherwise
+func ValidateJSON(input string) error {
+	var raw json.RawMessage
+	err := json.Unmarshal([]byte(input), &raw)
+	if err == nil {
+		return nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return err
+	}
+	return newSyntaxError(input, syntaxErr.Offset, syntaxErr.Error())
+}
+
+// newSyntaxError locates offset in input and builds the SyntaxError for it
+func newSyntaxError(input string, offset int64, msg string) *SyntaxError {
+	// encoding/json reports the offset after the offending byte
+	position := int(offset) - 1
+	if position < 0 {
+		position = 0
+	}
+	if position > len(input) {
+		position = len(input)
+	}
+
+	lineStart := strings.LastIndexByte(input[:position], '\n') + 1
+	lineEnd := strings.IndexByte(input[position:], '\n')
+	if lineEnd < 0 {
+		lineEnd = len(input)
+	} else {
+		lineEnd += position
+	}
+
+	return &SyntaxError{
+		Offset:  int64(position),
+		Line:    strings.Count(input[:position], "\n") + 1,
+		Column:  utf8.RuneCountInString(input[lineStart:position]) + 1,
+		Snippet: snippet(input[lineStart:position], input[position:lineEnd]),
+		Msg:     msg,
+	}
+}
+
+// snippet joins the end of before and the start of after, trimmed to snippetRadius characters each
+func snippet(before, after string) string {
+	if runes := []rune(before); len(runes) > snippetRadius {
+		before = "..." + string(runes[len(runes)-snippetRadius:])
+	}
+	if runes := []rune(after); len(runes) > snippetRadius {
+		after = string(runes[:snippetRadius]) + "..."
+	}
+	return strings.TrimSpace(before + after)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateJSON", func() {
+	It("accepts a valid document", func() {
+		Expect(ValidateJSON(`{"people": [{"id": 1}]}`)).To(Succeed())
+	})
+
+	It("points at the line and column of a syntax error", func() {
+		err := ValidateJSON("{\n  \"people\": [\n    {\"id\": 1,}\n  ]\n}")
+		Expect(err).To(HaveOccurred())
+
+		var syntaxErr *SyntaxError
+		Expect(err).To(BeAssignableToTypeOf(syntaxErr))
+		syntaxErr = err.(*SyntaxError)
+		Expect(syntaxErr.Line).To(Equal(3))
+		Expect(syntaxErr.Column).To(Equal(14))
+		Expect(syntaxErr.Offset).To(BeEquivalentTo(29))
+		Expect(syntaxErr.Snippet).To(Equal(`{"id": 1,}`))
+		Expect(syntaxErr.Error()).To(ContainSubstring("line 3, column 14"))
+	})
+
+	It("reports unterminated documents at their end", func() {
+		err := ValidateJSON(`{ "invalid json here }`)
+		Expect(err).To(HaveOccurred())
+
+		syntaxErr, ok := err.(*SyntaxError)
+		Expect(ok).To(BeTrue())
+		Expect(syntaxErr.Line).To(Equal(1))
+		Expect(syntaxErr.Msg).To(ContainSubstring("unexpected end of JSON input"))
+	})
+
+	It("reports empty documents", func() {
+		err := ValidateJSON("")
+		Expect(err).To(HaveOccurred())
+
+		syntaxErr, ok := err.(*SyntaxError)
+		Expect(ok).To(BeTrue())
+		Expect(syntaxErr.Line).To(Equal(1))
+		Expect(syntaxErr.Column).To(Equal(1))
+	})
+
+	It("trims long lines around the error", func() {
+		err := ValidateJSON(`{"a": "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", x "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"}`)
+		Expect(err).To(HaveOccurred())
+
+		syntaxErr, ok := err.(*SyntaxError)
+		Expect(ok).To(BeTrue())
+		Expect(syntaxErr.Snippet).To(HavePrefix("..."))
+		Expect(syntaxErr.Snippet).To(HaveSuffix("..."))
+		Expect(syntaxErr.Snippet).To(ContainSubstring(`", x "`))
+	})
+})
@@ -0,0 +1,198 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ValidateRoute checks a rewrite rule of json-server's routes.json. The pattern is an
+// Express path where ":name" captures a segment and "*" captures the rest of the path;
+// the target is the path it is rewritten to, referencing the named captures as ":name"
+// and all the captures by position as "$1", "$2"...
+func ValidateRoute(pattern, target string) error {
+	if !strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("pattern must start with /")
+	}
+	if !strings.HasPrefix(target, "/") {
+		return fmt.Errorf("target must start with /")
+	}
+	if strings.ContainsFunc(pattern+target, unicode.IsSpace) {
+		return fmt.Errorf("pattern and target must not contain whitespace")
+	}
+
+	params, groups, err := parsePattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(target); i++ {
+		switch target[i] {
+		case ':':
+			name := identifierAt(target, i+1)
+			if name != "" && !params[name] {
+				return fmt.Errorf("target references :%s, which the pattern does not capture", name)
+			}
+			i += len(name)
+		case '$':
+			digits := digitsAt(target, i+1)
+			if digits == "" {
+				continue
+			}
+			if n, _ := strconv.Atoi(digits); n == 0 || n > groups {
+				return fmt.Errorf("target references $%s but the pattern has %d captures", digits, groups)
+			}
+			i += len(digits)
+		}
+	}
+	return nil
+}
+
+// RouteMatches reports whether the pattern of a rewrite rule matches the path, the way
+// json-server's rewriter does: "*" is a "(.*)" group, ":name" captures a segment, the
+// parameters and groups take the "/" before them and the "?", "*" and "+" modifiers after
+// them, and the path matches case-insensitively, with or without a trailing slash. Patterns
+// whose custom expressions are not Go regular expressions never match.
+func RouteMatches(pattern, path string) bool {
+	pattern = strings.ReplaceAll(pattern, "*", "(.*)")
+	var expr strings.Builder
+	expr.WriteString("(?i)^")
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '\\' && i+1 < len(pattern) {
+			i++
+			expr.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			continue
+		}
+		prefix, delimiter := "", "/"
+		if (pattern[i] == '/' || pattern[i] == '.') && i+1 < len(pattern) && (pattern[i+1] == ':' || pattern[i+1] == '(') {
+			prefix = regexp.QuoteMeta(pattern[i : i+1])
+			delimiter = prefix
+			i++
+		}
+		capture := ""
+		switch {
+		case pattern[i] == ':' && identifierAt(pattern, i+1) != "":
+			i += len(identifierAt(pattern, i+1))
+			capture = "[^" + delimiter + "]+?"
+			if i+1 < len(pattern) && pattern[i+1] == '(' {
+				capture, i = groupAt(pattern, i+1)
+			}
+		case pattern[i] == '(':
+			capture, i = groupAt(pattern, i)
+		default:
+			expr.WriteString(prefix + regexp.QuoteMeta(pattern[i:i+1]))
+			continue
+		}
+		modifier := byte(0)
+		if i+1 < len(pattern) && strings.IndexByte("?*+", pattern[i+1]) >= 0 {
+			i++
+			modifier = pattern[i]
+		}
+		if modifier == '*' || modifier == '+' {
+			capture = fmt.Sprintf("%s(?:%s%s)*", capture, prefix, capture)
+		}
+		if modifier == '?' || modifier == '*' {
+			fmt.Fprintf(&expr, "(?:%s(%s))?", prefix, capture)
+		} else {
+			fmt.Fprintf(&expr, "%s(%s)", prefix, capture)
+		}
+	}
+	expr.WriteString("/?$")
+
+	re, err := regexp.Compile(expr.String())
+	return err == nil && re.MatchString(path)
+}
+
+// groupAt returns the expression of the group opening at offset i of the pattern and the
+// offset of its closing parenthesis
+func groupAt(pattern string, i int) (string, int) {
+	end := i + 1
+	for end < len(pattern) && pattern[end] != ')' {
+		if pattern[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	return pattern[i+1 : min(end, len(pattern))], end
+}
+
+// parsePattern returns the named parameters and the number of captures of a pattern
+func parsePattern(pattern string) (map[string]bool, int, error) {
+	params := map[string]bool{}
+	groups := 0
+	depth := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			// Escaped character, e.g. "\?" matching a literal question mark
+			i++
+		case ':':
+			name := identifierAt(pattern, i+1)
+			if name == "" {
+				return nil, 0, fmt.Errorf("pattern has a parameter without name at offset %d", i)
+			}
+			if params[name] {
+				return nil, 0, fmt.Errorf("pattern captures :%s twice", name)
+			}
+			params[name] = true
+			groups++
+			i += len(name)
+			// A custom expression of the parameter, e.g. ":id(\\d+)", is not another capture
+			if i+1 < len(pattern) && pattern[i+1] == '(' {
+				depth++
+				i++
+			}
+		case '*':
+			groups++
+		case '(':
+			depth++
+			groups++
+		case ')':
+			if depth == 0 {
+				return nil, 0, fmt.Errorf("pattern has an unbalanced ) at offset %d", i)
+			}
+			depth--
+		}
+	}
+	if depth != 0 {
+		return nil, 0, fmt.Errorf("pattern has an unbalanced (")
+	}
+	return params, groups, nil
+}
+
+// identifierAt returns the identifier starting at offset i of s
+func identifierAt(s string, i int) string {
+	end := i
+	for end < len(s) && (s[end] == '_' || s[end] >= 'a' && s[end] <= 'z' || s[end] >= 'A' && s[end] <= 'Z' ||
+		end > i && s[end] >= '0' && s[end] <= '9') {
+		end++
+	}
+	return s[i:end]
+}
+
+// digitsAt returns the digits starting at offset i of s
+func digitsAt(s string, i int) string {
+	end := i
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	return s[i:end]
+}
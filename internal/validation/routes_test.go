@@ -0,0 +1,70 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validation
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateRoute", func() {
+	DescribeTable("accepts the json-server route examples",
+		func(pattern, target string) {
+			Expect(ValidateRoute(pattern, target)).To(Succeed())
+		},
+		Entry("wildcard", "/api/*", "/$1"),
+		Entry("named parameters", "/:resource/:id/show", "/:resource/:id"),
+		Entry("query string", "/posts/:category", "/posts?category=:category"),
+		Entry("escaped query string", `/articles\?id=:id`, "/posts/:id"),
+		Entry("custom parameter expression", `/users/:id(\d+)/*`, "/users/$1/$2"),
+	)
+
+	DescribeTable("rejects invalid routes",
+		func(pattern, target, message string) {
+			err := ValidateRoute(pattern, target)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(message))
+		},
+		Entry("relative pattern", "api/*", "/$1", "pattern must start with /"),
+		Entry("relative target", "/api/*", "$1", "target must start with /"),
+		Entry("whitespace", "/api /*", "/$1", "whitespace"),
+		Entry("unknown parameter", "/:resource", "/:resource/:id", "does not capture"),
+		Entry("capture out of range", "/api/*", "/$2", "has 1 captures"),
+		Entry("duplicate parameter", "/:id/:id", "/$1", "twice"),
+		Entry("parameter without name", "/:/x", "/x", "without name"),
+		Entry("unbalanced group", "/(a", "/$1", "unbalanced"),
+	)
+})
+
+var _ = Describe("RouteMatches", func() {
+	DescribeTable("matches the paths json-server rewrites",
+		func(pattern, path string, matches bool) {
+			Expect(RouteMatches(pattern, path)).To(Equal(matches))
+		},
+		Entry("wildcard", "/*", "/db", true),
+		Entry("prefixed wildcard", "/api/*", "/db", false),
+		Entry("prefixed wildcard matching", "/api/*", "/api/people/1", true),
+		Entry("parameter", "/:resource", "/db", true),
+		Entry("parameter with trailing slash", "/:resource", "/db/", true),
+		Entry("two parameters", "/:resource/:id", "/db", false),
+		Entry("optional parameter", "/:resource/:id?", "/db", true),
+		Entry("custom parameter expression", `/:id(\d+)`, "/db", false),
+		Entry("literal", "/DB", "/db", true),
+		Entry("escaped query string", `/db\?id=:id`, "/db", false),
+		Entry("group", "/(db|people)", "/db", true),
+	)
+})
@@ -19,15 +19,32 @@ package v1
 import (
 	"context"
 	"fmt"
+	"maps"
+	"net/netip"
+	"net/url"
+	"slices"
 	"strings"
 
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/config"
+	"jsonserver-operator/internal/fixture"
+	"jsonserver-operator/internal/image"
+	"jsonserver-operator/internal/payload"
+	"jsonserver-operator/internal/validation"
 )
 
 // nolint:unused
@@ -35,9 +52,9 @@ import (
 var jsonserverlog = logf.Log.WithName("jsonserver-resource")
 
 // SetupJsonServerWebhookWithManager registers the webhook for JsonServer in the manager.
-func SetupJsonServerWebhookWithManager(mgr ctrl.Manager) error {
+func SetupJsonServerWebhookWithManager(mgr ctrl.Manager, cfg config.Config) error {
 	return ctrl.NewWebhookManagedBy(mgr).For(&examplev1.JsonServer{}).
-		WithValidator(&JsonServerCustomValidator{}).
+		WithValidator(&JsonServerCustomValidator{Client: mgr.GetClient(), Config: cfg, patterns: &policyPatterns{}}).
 		WithDefaulter(&JsonServerCustomDefaulter{}).
 		Complete()
 }
@@ -82,7 +99,14 @@ func (d *JsonServerCustomDefaulter) Default(ctx context.Context, obj runtime.Obj
 // NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
 // as this struct is used only for temporary operations and does not need to be deeply copied.
 type JsonServerCustomValidator struct {
-	// TODO(user): Add more fields as needed for validation
+	// Client reads the JsonServerPolicies and Namespaces from the manager cache
+	Client client.Reader
+	// Config holds the default image and the registries allowed operator-wide
+	Config config.Config
+
+	// patterns caches the compiled name patterns of the JsonServerPolicies. Without it, the
+	// patterns are compiled on every admission.
+	patterns *policyPatterns
 }
 
 var _ webhook.CustomValidator = &JsonServerCustomValidator{}
@@ -95,12 +119,7 @@ func (v *JsonServerCustomValidator) ValidateCreate(ctx context.Context, obj runt
 	}
 	jsonserverlog.Info("Validation for JsonServer upon creation", "name", jsonserver.GetName())
 
-	// Validating webhook to block creation of objects not following naming convention
-	if !strings.HasPrefix(jsonserver.GetName(), "app-") {
-		return nil, fmt.Errorf("JsonServer name must follow the convention 'app-${name}'")
-	}
-
-	return nil, nil
+	return v.validateJsonServer(ctx, jsonserver)
 }
 
 // ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type JsonServer.
@@ -109,14 +128,19 @@ func (v *JsonServerCustomValidator) ValidateUpdate(ctx context.Context, oldObj,
 	if !ok {
 		return nil, fmt.Errorf("expected a JsonServer object for the newObj but got %T", newObj)
 	}
+	oldJsonServer, ok := oldObj.(*examplev1.JsonServer)
+	if !ok {
+		return nil, fmt.Errorf("expected a JsonServer object for the oldObj but got %T", oldObj)
+	}
 	jsonserverlog.Info("Validation for JsonServer upon update", "name", jsonserver.GetName())
 
-	// Validating webhook to block update of objects not following naming convention
-	if !strings.HasPrefix(jsonserver.GetName(), "app-") {
-		return nil, fmt.Errorf("JsonServer name must follow the convention 'app-${name}'")
+	// The annotations written by the operator and the activator, such as the activation of idle
+	// JsonServers, are never denied, even when the JsonServer no longer follows tightened policies
+	if equality.Semantic.DeepEqual(oldJsonServer.Spec, jsonserver.Spec) &&
+		equality.Semantic.DeepEqual(oldJsonServer.Labels, jsonserver.Labels) {
+		return nil, nil
 	}
-
-	return nil, nil
+	return v.validateJsonServer(ctx, jsonserver)
 }
 
 // ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type JsonServer.
@@ -129,3 +153,383 @@ func (v *JsonServerCustomValidator) ValidateDelete(ctx context.Context, obj runt
 
 	return nil, nil
 }
+
+// validateJsonServer runs the checks shared by ValidateCreate and ValidateUpdate
+func (v *JsonServerCustomValidator) validateJsonServer(ctx context.Context, jsonserver *examplev1.JsonServer) (admission.Warnings, error) {
+	namespace := jsonserver.Namespace
+	if namespace == "" {
+		if req, err := admission.RequestFromContext(ctx); err == nil {
+			namespace = req.Namespace
+		}
+	}
+
+	// Validating webhook to block objects not following the policies of their namespace
+	policies, err := matchingPolicies(ctx, v.Client, v.patterns, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonImage := jsonserver.Spec.Image
+	if jsonImage == "" {
+		jsonImage = v.Config.DefaultImage
+	}
+	if jsonImage == "" {
+		jsonImage = examplev1.DefaultImage
+	}
+
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, v.validateImage(jsonserver, jsonImage)...)
+	allErrs = append(allErrs, v.validateDataFrom(jsonserver.Spec.DataFrom, field.NewPath("spec", "dataFrom"))...)
+	if len(policies) == 0 {
+		allErrs = append(allErrs, validateDefaultPolicy(jsonserver)...)
+	}
+	for i := range policies {
+		allErrs = append(allErrs, validatePolicy(&policies[i], v.patterns, jsonserver, jsonImage)...)
+	}
+
+	allErrs = append(allErrs, validateServer(jsonserver.Spec.Server, field.NewPath("spec", "server"))...)
+	allErrs = append(allErrs, validateResetSchedule(jsonserver.Spec.ResetSchedule, field.NewPath("spec", "resetSchedule"))...)
+	allErrs = append(allErrs, validateAccess(jsonserver.Spec.Access, field.NewPath("spec", "access"))...)
+	allErrs = append(allErrs, validateCollections(jsonserver, field.NewPath("spec", "collections"))...)
+	allErrs = append(allErrs, validateGenerate(jsonserver, field.NewPath("spec", "generate"))...)
+	// Data read from spec.dataFrom, or collections read from other objects, are only known at
+	// reconcile time, the controller validates them
+	var warnings admission.Warnings
+	if jsonserver.Spec.DataFrom == nil && fixture.Inline(&jsonserver.Spec) {
+		document, errs := composeJsonConfig(jsonserver)
+		allErrs = append(allErrs, errs...)
+		if len(errs) == 0 {
+			allErrs = append(allErrs, validateJsonConfig(document, documentOptions(jsonserver), field.NewPath("spec", "jsonConfig"))...)
+			// Compressing large documents is costly, they are encoded once for both checks
+			chunks, encodeErr := payload.Encode(document)
+			allErrs = append(allErrs, validateJsonConfigSize(document, encodeErr, field.NewPath("spec", "jsonConfig"))...)
+			warnings = append(warnings, jsonConfigSizeWarnings(document, chunks)...)
+		}
+	}
+	warnings = append(warnings, consistencyWarnings(jsonserver)...)
+	warnings = append(warnings, autoscalingWarnings(jsonserver)...)
+	warnings = append(warnings, disruptionBudgetWarnings(jsonserver)...)
+	warnings = append(warnings, accessWarnings(jsonserver)...)
+	if len(allErrs) > 0 {
+		return warnings, apierrors.NewInvalid(examplev1.GroupVersion.WithKind("JsonServer").GroupKind(), jsonserver.Name, allErrs)
+	}
+
+	return warnings, nil
+}
+
+// validateImage checks the json-server image is a valid reference pulled from one of the
+// registries allowed operator-wide
+func (v *JsonServerCustomValidator) validateImage(jsonserver *examplev1.JsonServer, jsonImage string) field.ErrorList {
+	allowed, err := image.RegistryAllowed(jsonImage, v.Config.AllowedRegistries)
+	if err != nil {
+		return field.ErrorList{field.Invalid(imagePath(jsonserver), jsonImage, fmt.Sprintf("invalid image reference: %v", err))}
+	}
+	if !allowed {
+		return field.ErrorList{field.Forbidden(imagePath(jsonserver),
+			fmt.Sprintf("json-server image %q must be pulled from one of %s: denied by the operator configuration",
+				jsonImage, strings.Join(v.Config.AllowedRegistries, ", ")))}
+	}
+	return nil
+}
+
+// validateDataFrom checks the URL of an HTTP source is allowed operator-wide. The controller
+// checks the addresses its host resolves to when downloading it.
+func (v *JsonServerCustomValidator) validateDataFrom(dataFrom *examplev1.DataSource, fldPath *field.Path) field.ErrorList {
+	if dataFrom == nil || dataFrom.HTTP == nil {
+		return nil
+	}
+	urlPath := fldPath.Child("http", "url")
+	hostAllowed, err := v.Config.CheckURL(dataFrom.HTTP.URL)
+	if err == nil {
+		u, _ := url.Parse(dataFrom.HTTP.URL)
+		if addr, parseErr := netip.ParseAddr(u.Hostname()); parseErr == nil {
+			err = config.CheckAddress(addr, hostAllowed)
+		}
+	}
+	if err != nil {
+		return field.ErrorList{field.Forbidden(urlPath, fmt.Sprintf("%s cannot be downloaded: %v: denied by the operator configuration",
+			dataFrom.HTTP.URL, err))}
+	}
+	return nil
+}
+
+// consistencyWarnings warns about replicas keeping diverging copies of writable data
+func consistencyWarnings(jsonserver *examplev1.JsonServer) admission.Warnings {
+	replicasPath, replicas := "spec.replicas", jsonserver.Spec.Replicas
+	if autoscaling := jsonserver.Spec.Autoscaling; autoscaling != nil {
+		replicasPath, replicas = "spec.autoscaling.maxReplicas", autoscaling.MaxReplicas
+	}
+	if replicas > 1 && jsonserver.Spec.Consistency == "" {
+		return admission.Warnings{fmt.Sprintf(
+			"%s is %d but spec.consistency is not set: each replica keeps its own copy of the data "+
+				"and writes are only visible on the replica that received them, set spec.consistency to %s, %s or %s",
+			replicasPath, replicas, examplev1.ConsistencySingleWriter, examplev1.ConsistencySticky, examplev1.ConsistencyReadOnly)}
+	}
+	return nil
+}
+
+// autoscalingWarnings warns about utilization targets the HorizontalPodAutoscaler cannot
+// compute, the json-server container requesting none of the resource
+func autoscalingWarnings(jsonserver *examplev1.JsonServer) admission.Warnings {
+	autoscaling := jsonserver.Spec.Autoscaling
+	if autoscaling == nil {
+		return nil
+	}
+
+	var requests corev1.ResourceList
+	if override := jsonserver.Spec.PodTemplate; override != nil && override.Resources != nil {
+		requests = override.Resources.Requests
+	}
+	var warnings admission.Warnings
+	targets := []struct {
+		name   corev1.ResourceName
+		target bool
+	}{
+		{corev1.ResourceCPU, autoscaling.TargetCPUUtilizationPercentage != nil || autoscaling.TargetMemoryUtilizationPercentage == nil},
+		{corev1.ResourceMemory, autoscaling.TargetMemoryUtilizationPercentage != nil},
+	}
+	for _, t := range targets {
+		if _, ok := requests[t.name]; t.target && !ok {
+			warnings = append(warnings, fmt.Sprintf(
+				"spec.autoscaling targets the %[1]s utilization but spec.podTemplate.resources.requests sets no %[1]s: "+
+					"the HorizontalPodAutoscaler cannot compute the utilization and will not scale", t.name))
+		}
+	}
+	return warnings
+}
+
+// disruptionBudgetWarnings warns about disruption budgets allowing no eviction, which block
+// the drains of the nodes running the pods
+func disruptionBudgetWarnings(jsonserver *examplev1.JsonServer) admission.Warnings {
+	budget := jsonserver.Spec.DisruptionBudget
+	if budget == nil {
+		return nil
+	}
+
+	replicas := int(jsonserver.Spec.Replicas)
+	if autoscaling := jsonserver.Spec.Autoscaling; autoscaling != nil {
+		replicas = 1
+		if autoscaling.MinReplicas != nil {
+			replicas = int(*autoscaling.MinReplicas)
+		}
+	}
+	// The disruption controller rounds percentages up
+	allowed := 1
+	if budget.MinAvailable != nil {
+		minAvailable, err := intstr.GetScaledValueFromIntOrPercent(budget.MinAvailable, replicas, true)
+		if err != nil {
+			return nil
+		}
+		allowed = replicas - minAvailable
+	} else if budget.MaxUnavailable != nil {
+		maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(budget.MaxUnavailable, replicas, true)
+		if err != nil {
+			return nil
+		}
+		allowed = maxUnavailable
+	}
+	if allowed <= 0 {
+		return admission.Warnings{fmt.Sprintf(
+			"spec.disruptionBudget allows no eviction of the %d replicas: draining the nodes running them is blocked", replicas)}
+	}
+	return nil
+}
+
+// validateServer rejects rewrite rules json-server cannot apply. routes.json is a JSON object,
+// so each pattern can only be listed once, and the rule of the base path is rendered first.
+// The rules must leave /db as is, the read replicas and the snapshots read the data from it.
+func validateServer(server *examplev1.ServerSpec, fldPath *field.Path) field.ErrorList {
+	if server == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	basePathRule := ""
+	if server.BasePath != "" {
+		basePathRule = server.BasePath + "/*"
+	}
+	patterns := make(map[string]bool, len(server.Routes))
+	for i, route := range server.Routes {
+		routePath := fldPath.Child("routes").Index(i)
+		switch {
+		case patterns[route.Pattern]:
+			allErrs = append(allErrs, field.Duplicate(routePath.Child("pattern"), route.Pattern))
+		case route.Pattern == basePathRule:
+			allErrs = append(allErrs, field.Invalid(routePath.Child("pattern"), route.Pattern,
+				"the rule of spec.server.basePath already rewrites this pattern"))
+		default:
+			if err := validation.ValidateRoute(route.Pattern, route.Target); err != nil {
+				allErrs = append(allErrs, field.Invalid(routePath, route.Target, err.Error()))
+			} else if validation.RouteMatches(route.Pattern, "/db") {
+				allErrs = append(allErrs, field.Invalid(routePath.Child("pattern"), route.Pattern,
+					"must not match /db, which the read replicas and the snapshots read the data from"))
+			}
+		}
+		patterns[route.Pattern] = true
+	}
+	return allErrs
+}
+
+// validateResetSchedule rejects reset schedules that are not cron expressions the controller can run
+func validateResetSchedule(schedule string, fldPath *field.Path) field.ErrorList {
+	if schedule == "" {
+		return nil
+	}
+	if _, err := cron.ParseStandard(schedule); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, schedule, fmt.Sprintf("invalid cron expression: %v", err))}
+	}
+	return nil
+}
+
+// validateAccess rejects the label selectors of the access section a NetworkPolicy cannot use
+func validateAccess(access *examplev1.AccessSpec, fldPath *field.Path) field.ErrorList {
+	if access == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	opts := metav1validation.LabelSelectorValidationOptions{}
+	for i, peer := range access.From {
+		peerPath := fldPath.Child("from").Index(i)
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(peer.NamespaceSelector, opts, peerPath.Child("namespaceSelector"))...)
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(peer.PodSelector, opts, peerPath.Child("podSelector"))...)
+	}
+	return allErrs
+}
+
+// accessWarnings warns about exposed JsonServers whose ingress controller or gateway is not
+// let through by the access section
+func accessWarnings(jsonserver *examplev1.JsonServer) admission.Warnings {
+	access := jsonserver.Spec.Access
+	if access == nil || jsonserver.Spec.Expose == nil || len(access.From) > 0 {
+		return nil
+	}
+	return admission.Warnings{"spec.expose is set but spec.access.from lists no peer: " +
+		"the pods of the ingress controller or gateway must be allowed to reach the JsonServer"}
+}
+
+// documentOptions returns the json-server conventions the data of the JsonServer follows
+func documentOptions(jsonserver *examplev1.JsonServer) validation.Options {
+	if server := jsonserver.Spec.Server; server != nil {
+		return validation.Options{IDField: server.IDField, ForeignKeySuffix: server.ForeignKeySuffix}
+	}
+	return validation.DefaultOptions
+}
+
+// maxReportedDocumentErrors caps the number of structural errors returned to the client
+const maxReportedDocumentErrors = 10
+
+// validateJsonConfig rejects documents json-server cannot parse or serve, pointing at
+// the syntax error or at the offending values of the document
+func validateJsonConfig(jsonConfig string, opts validation.Options, fldPath *field.Path) field.ErrorList {
+	err := validation.Validate(jsonConfig, opts)
+	if err == nil {
+		return nil
+	}
+
+	switch err := err.(type) {
+	case *validation.SyntaxError:
+		return field.ErrorList{field.Invalid(fldPath, err.Snippet,
+			fmt.Sprintf("invalid JSON at line %d, column %d (byte offset %d): %s",
+				err.Line, err.Column, err.Offset, err.Msg))}
+	case validation.ErrorList:
+		var allErrs field.ErrorList
+		for i, documentErr := range err {
+			if i == maxReportedDocumentErrors {
+				allErrs = append(allErrs, field.Invalid(fldPath, "",
+					fmt.Sprintf("%d more errors not shown", len(err)-maxReportedDocumentErrors)))
+				break
+			}
+			allErrs = append(allErrs, field.Invalid(fldPath, documentErr.Pointer, documentErr.Detail))
+		}
+		return allErrs
+	default:
+		return field.ErrorList{field.Invalid(fldPath, "", err.Error())}
+	}
+}
+
+// validateCollections rejects collections a JsonServer reads from the configuration it serves
+func validateCollections(jsonserver *examplev1.JsonServer, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, name := range slices.Sorted(maps.Keys(jsonserver.Spec.Collections)) {
+		if ref := jsonserver.Spec.Collections[name].JsonServerRef; ref != nil && ref.Name == jsonserver.Name {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(name).Child("jsonServerRef", "name"), ref.Name,
+				"a JsonServer cannot read the collections it serves itself"))
+		}
+	}
+	return allErrs
+}
+
+// validateGenerate rejects templates that do not parse and generated collections already
+// defined by spec.collections. The records are only rendered by the controller.
+func validateGenerate(jsonserver *examplev1.JsonServer, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	generators := fixture.Generators(&jsonserver.Spec)
+	for _, name := range slices.Sorted(maps.Keys(generators)) {
+		if _, ok := jsonserver.Spec.Collections[name]; ok {
+			allErrs = append(allErrs, field.Invalid(fldPath.Key(name), name, "collection is already defined by spec.collections"))
+			continue
+		}
+		if err := generators[name].Parse(); err != nil {
+			fixtureErr, ok := err.(*fixture.Error)
+			if !ok {
+				allErrs = append(allErrs, field.InternalError(fixture.TemplatePath(name), err))
+				continue
+			}
+			allErrs = append(allErrs, field.Invalid(fixture.TemplatePath(name), fixtureErr.Location(), fixtureErr.Msg))
+		}
+	}
+	return allErrs
+}
+
+// composeJsonConfig returns the db.json served for spec.jsonConfig: the configuration as is,
+// or converted from the format of the spec and completed with its collections. Conversion
+// errors point at the line and column of the field they were found in.
+func composeJsonConfig(jsonserver *examplev1.JsonServer) (string, field.ErrorList) {
+	spec := &jsonserver.Spec
+	if !fixture.Converts(spec) {
+		return spec.JsonConfig, nil
+	}
+	jsonConfigPath := field.NewPath("spec", "jsonConfig")
+	document := fixture.Input{Source: jsonConfigPath.String(), Format: fixture.Format(spec.Format), Data: spec.JsonConfig}
+	composed, err := fixture.Compose(document, fixture.Collections(spec))
+	if err == nil {
+		return composed, nil
+	}
+
+	fixtureErr, ok := err.(*fixture.Error)
+	if !ok {
+		return "", field.ErrorList{field.InternalError(jsonConfigPath, err)}
+	}
+	fldPath := jsonConfigPath
+	for name := range spec.Collections {
+		if path := fixture.CollectionPath(name); path.String() == fixtureErr.Source {
+			fldPath = path
+		}
+	}
+	return "", field.ErrorList{field.Invalid(fldPath, fixtureErr.Location(), fixtureErr.Msg)}
+}
+
+// validateJsonConfigSize rejects documents the operator cannot store in ConfigMaps, even
+// compressed and split across several of them. encodeErr is the error payload.Encode
+// returned for the document.
+func validateJsonConfigSize(jsonConfig string, encodeErr error, fldPath *field.Path) field.ErrorList {
+	if encodeErr != nil {
+		return field.ErrorList{field.Invalid(fldPath, fmt.Sprintf("%d bytes", len(jsonConfig)),
+			fmt.Sprintf("cannot be stored in ConfigMaps: %v", encodeErr))}
+	}
+	return nil
+}
+
+// jsonConfigSizeWarnings warns about documents that do not fit in one ConfigMap once
+// compressed, which are split across several of them. chunks are the chunks payload.Encode
+// returned for the document.
+func jsonConfigSizeWarnings(document string, chunks [][]byte) admission.Warnings {
+	if len(chunks) <= 1 {
+		return nil
+	}
+	return admission.Warnings{fmt.Sprintf(
+		"spec.jsonConfig is %d bytes and still does not fit in one ConfigMap once compressed: it is split across %d ConfigMaps "+
+			"and the json-server pods reassemble it when they start, consider reading it from spec.dataFrom",
+		len(document), len(chunks))}
+}
@@ -17,10 +17,23 @@ limitations under the License.
 package v1
 
 import (
+	"encoding/base64"
+	"math/rand/v2"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/config"
+	"jsonserver-operator/internal/payload"
 	// TODO (user): Add any additional imports if needed
 )
 
@@ -35,7 +48,11 @@ var _ = Describe("JsonServer Webhook", func() {
 	BeforeEach(func() {
 		obj = &examplev1.JsonServer{}
 		oldObj = &examplev1.JsonServer{}
-		validator = JsonServerCustomValidator{}
+		validator = JsonServerCustomValidator{
+			Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(&corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			}).Build(),
+		}
 		Expect(validator).NotTo(BeNil(), "Expected validator to be initialized")
 		defaulter = JsonServerCustomDefaulter{}
 		Expect(defaulter).NotTo(BeNil(), "Expected defaulter to be initialized")
@@ -62,26 +79,330 @@ var _ = Describe("JsonServer Webhook", func() {
 	})
 
 	Context("When creating or updating JsonServer under Validating Webhook", func() {
-		// TODO (user): Add logic for validating webhooks
-		// Example:
-		// It("Should deny creation if a required field is missing", func() {
-		//     By("simulating an invalid creation scenario")
-		//     obj.SomeRequiredField = ""
-		//     Expect(validator.ValidateCreate(ctx, obj)).Error().To(HaveOccurred())
-		// })
-		//
-		// It("Should admit creation if all required fields are present", func() {
-		//     By("simulating an invalid creation scenario")
-		//     obj.SomeRequiredField = "valid_value"
-		//     Expect(validator.ValidateCreate(ctx, obj)).To(BeNil())
-		// })
-		//
-		// It("Should validate updates correctly", func() {
-		//     By("simulating a valid update scenario")
-		//     oldObj.SomeRequiredField = "updated_value"
-		//     obj.SomeRequiredField = "updated_value"
-		//     Expect(validator.ValidateUpdate(ctx, oldObj, obj)).To(BeNil())
-		// })
+		BeforeEach(func() {
+			obj.Name = "app-sample"
+			obj.Namespace = "default"
+			obj.Spec.Replicas = 1
+			obj.Spec.JsonConfig = `{"people": [{"id": 1, "name": "Person A"}]}`
+			oldObj = obj.DeepCopy()
+		})
+
+		It("Should admit creation of a valid JsonServer", func() {
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny creation if the name does not follow the convention", func() {
+			obj.Name = "invalid-name"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring("app-${name}")))
+		})
+
+		It("Should deny creation if the jsonConfig is not valid JSON", func() {
+			obj.Spec.JsonConfig = "{\n  \"people\": [\n    {\"id\": 1,}\n  ]\n}"
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("spec.jsonConfig"))
+			Expect(err.Error()).To(ContainSubstring("line 3, column 14"))
+			Expect(err.Error()).To(ContainSubstring(`{\"id\": 1,}`))
+		})
+
+		It("Should deny updates that break the jsonConfig", func() {
+			obj.Spec.JsonConfig = `{ "invalid json here }`
+			_, err := validator.ValidateUpdate(ctx, oldObj, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("unexpected end of JSON input"))
+		})
+
+		It("Should deny creation if the jsonConfig cannot be served by json-server", func() {
+			obj.Spec.JsonConfig = `{"posts": [{"id": 1}, {"id": 1}], "comments": [{"id": 1, "postId": 2}], "tags": ["a"]}`
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(apierrors.IsInvalid(err)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring(`"/posts/1/id": duplicate id 1`))
+			Expect(err.Error()).To(ContainSubstring(`"/comments/0/postId": references missing record 2 of collection "posts"`))
+			Expect(err.Error()).To(ContainSubstring(`"/tags/0": must be an object, got string`))
+		})
+
+		It("Should leave the validation of external data sources to the controller", func() {
+			obj.Spec.JsonConfig = ""
+			obj.Spec.DataFrom = &examplev1.DataSource{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "fixtures"},
+					Key:                  "db.json",
+				},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny routes json-server cannot apply", func() {
+			obj.Spec.Server = &examplev1.ServerSpec{Routes: []examplev1.Route{
+				{Pattern: "/api/*", Target: "/$1"},
+				{Pattern: "/:resource", Target: "/:resource/:id"},
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`spec.server.routes[1]`))
+			Expect(err.Error()).To(ContainSubstring("target references :id, which the pattern does not capture"))
+			Expect(err.Error()).NotTo(ContainSubstring("spec.server.routes[0]"))
+		})
+
+		It("Should deny routes listed twice or rewriting the base path", func() {
+			obj.Spec.Server = &examplev1.ServerSpec{
+				BasePath: "/api",
+				Routes: []examplev1.Route{
+					{Pattern: "/:resource/:id/show", Target: "/:resource/:id"},
+					{Pattern: "/api/*", Target: "/v1/$1"},
+					{Pattern: "/:resource/:id/show", Target: "/:resource"},
+				},
+			}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`spec.server.routes[1].pattern: Invalid value: "/api/*"`))
+			Expect(err.Error()).To(ContainSubstring(`spec.server.routes[2].pattern: Duplicate value: "/:resource/:id/show"`))
+			Expect(err.Error()).NotTo(ContainSubstring("spec.server.routes[0]"))
+		})
+
+		It("Should deny routes rewriting the /db endpoint the data is read from", func() {
+			obj.Spec.Server = &examplev1.ServerSpec{Routes: []examplev1.Route{
+				{Pattern: "/blog/*", Target: "/posts/$1"},
+				{Pattern: "/*", Target: "/api/$1"},
+			}}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(MatchError(ContainSubstring(`spec.server.routes[1].pattern: Invalid value: "/*": must not match /db`)))
+			Expect(err.Error()).NotTo(ContainSubstring("spec.server.routes[0]"))
+		})
+
+		It("Should check the jsonConfig against the id field of the server section", func() {
+			obj.Spec.JsonConfig = `{"people": [{"uuid": "a"}, {"uuid": "a"}]}`
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Server = &examplev1.ServerSpec{IDField: "uuid"}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring(`"/people/1/uuid": duplicate id "a"`)))
+		})
+
+		It("Should deny reset schedules that are not cron expressions", func() {
+			obj.Spec.ResetSchedule = "0 3 * * *"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+			obj.Spec.ResetSchedule = "CRON_TZ=Europe/Paris @daily"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.ResetSchedule = "every night"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring("spec.resetSchedule: Invalid value")))
+		})
+
+		It("Should warn when several replicas keep their own copy of the data", func() {
+			obj.Spec.Replicas = 3
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(warnings).To(ConsistOf(ContainSubstring("spec.consistency is not set")))
+
+			obj.Spec.Consistency = examplev1.ConsistencySingleWriter
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeEmpty())
+		})
+
+		It("Should warn about autoscaling targets and disruption budgets that cannot work", func() {
+			obj.Spec.Consistency = examplev1.ConsistencyReadOnly
+			obj.Spec.Autoscaling = &examplev1.AutoscalingSpec{MaxReplicas: 4, TargetMemoryUtilizationPercentage: ptr.To[int32](70)}
+			Expect(validator.ValidateCreate(ctx, obj)).To(ConsistOf(ContainSubstring("sets no memory")))
+
+			obj.Spec.PodTemplate = &examplev1.PodTemplateOverride{Resources: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+			}}
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeEmpty())
+
+			obj.Spec.DisruptionBudget = &examplev1.DisruptionBudgetSpec{MinAvailable: ptr.To(intstr.FromString("100%"))}
+			Expect(validator.ValidateCreate(ctx, obj)).To(ConsistOf(ContainSubstring("spec.disruptionBudget allows no eviction of the 1 replicas")))
+
+			obj.Spec.Autoscaling.MinReplicas = ptr.To[int32](2)
+			obj.Spec.DisruptionBudget = &examplev1.DisruptionBudgetSpec{MinAvailable: ptr.To(intstr.FromInt32(1))}
+			Expect(validator.ValidateCreate(ctx, obj)).To(BeEmpty())
+		})
+
+		It("Should deny access selectors a NetworkPolicy cannot use", func() {
+			obj.Spec.Access = &examplev1.AccessSpec{From: []examplev1.AccessPeer{
+				{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}}},
+				{PodSelector: &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "role", Operator: metav1.LabelSelectorOpIn},
+				}}},
+			}}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring("spec.access.from[1].podSelector.matchExpressions[0].values")))
+
+			obj.Spec.Access.From = obj.Spec.Access.From[:1]
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should warn about documents split across ConfigMaps and deny those that still do not fit", func() {
+			document := func(size int) string {
+				random := make([]byte, size)
+				_, _ = rand.NewChaCha8([32]byte{}).Read(random)
+				return `{"blobs": [{"id": 1, "data": "` + base64.StdEncoding.EncodeToString(random) + `"}]}`
+			}
+
+			obj.Spec.JsonConfig = document(1152 * 1024)
+			Expect(validator.ValidateCreate(ctx, obj)).To(ConsistOf(ContainSubstring("it is split across 2 ConfigMaps")))
+
+			obj.Spec.JsonConfig = document(payload.MaxCompressedSize + 1024)
+			warnings, err := validator.ValidateCreate(ctx, obj)
+			Expect(warnings).To(BeEmpty())
+			Expect(err).To(MatchError(ContainSubstring("spec.jsonConfig: Invalid value: \"%d bytes\": cannot be stored in ConfigMaps", len(obj.Spec.JsonConfig))))
+		})
+
+		It("Should convert fixtures written in other formats and point at the errors in their field", func() {
+			obj.Spec.JsonConfig = "posts:\n  - id: 1\n    title: Hello\n"
+			obj.Spec.Format = examplev1.FormatYAML
+			obj.Spec.Collections = map[string]examplev1.CollectionSource{
+				"users": {Format: examplev1.FormatJSON5, Data: "[{id: 1, name: 'Person A'},]"},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Collections["users"] = examplev1.CollectionSource{Format: examplev1.FormatJSON5, Data: "[\n  {id: 1, name: Person A},\n]"}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring(
+				`spec.collections[users].data: Invalid value: "line 2, column 17": unexpected character 'P', expecting a value`)))
+
+			obj.Spec.Collections = map[string]examplev1.CollectionSource{"posts": {Format: examplev1.FormatNDJSON, Data: `{"id": 2}`}}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring(
+				`spec.collections[posts].data: Invalid value: "": collection "posts" is already defined by spec.jsonConfig`)))
+		})
+
+		It("Should leave collections read from other objects to the controller but deny reading its own", func() {
+			obj.Spec.JsonConfig = `{"posts": []}`
+			obj.Spec.Collections = map[string]examplev1.CollectionSource{
+				"posts": {FixtureRef: &examplev1.CollectionReference{Name: "shared"}},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Collections["users"] = examplev1.CollectionSource{JsonServerRef: &examplev1.CollectionReference{Name: obj.Name}}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring(
+				"spec.collections[users].jsonServerRef.name: Invalid value: %q: a JsonServer cannot read the collections it serves itself", obj.Name)))
+		})
+
+		It("Should deny generated collections whose template does not parse or that are already defined", func() {
+			obj.Spec.Generate = map[string]examplev1.GeneratedCollection{
+				"users": {Count: 10, Template: "id: {{ .ID }}\nname: {{ name }}\n"},
+			}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Generate["users"] = examplev1.GeneratedCollection{Count: 10, Template: "id: {{ .ID }}\nname: {{ nickname }}\n"}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring(
+				`spec.generate[users].template: Invalid value: "line 2": function "nickname" not defined`)))
+
+			obj.Spec.Generate["users"] = examplev1.GeneratedCollection{Count: 10, Template: "id: {{ .ID }}\n"}
+			obj.Spec.Collections = map[string]examplev1.CollectionSource{"users": {Data: "[]"}}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring(
+				`spec.generate[users]: Invalid value: "users": collection is already defined by spec.collections`)))
+		})
+
+		It("Should deny images that are invalid or pulled from registries the operator does not allow", func() {
+			validator.Config = config.Config{DefaultImage: "registry.example.com/json-server", AllowedRegistries: []string{"*.example.com"}}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.Image = "backplane/json-server:0.17.4"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(
+				ContainSubstring(`spec.image: Forbidden: json-server image "backplane/json-server:0.17.4" must be pulled from one of *.example.com`)))
+
+			obj.Spec.Image = "registry.example.com/JSON-server"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring("invalid image reference")))
+		})
+
+		It("Should deny URLs the operator does not allow to download", func() {
+			obj.Spec.JsonConfig = ""
+			obj.Spec.DataFrom = &examplev1.DataSource{HTTP: &examplev1.HTTPSource{URL: "https://fixtures.example.com/db.json"}}
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+
+			obj.Spec.DataFrom.HTTP.URL = "http://169.254.169.254/latest/meta-data"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring(
+				`spec.dataFrom.http.url: Forbidden: http://169.254.169.254/latest/meta-data cannot be downloaded: address 169.254.169.254 is not allowed`)))
+
+			validator.Config = config.Config{DefaultImage: examplev1.DefaultImage, AllowedURLHosts: []string{"*.example.com"}}
+			obj.Spec.DataFrom.HTTP.URL = "https://example.org/db.json"
+			Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring(
+				`host "example.org" does not match one of *.example.com`)))
+		})
+
+		Context("with JsonServerPolicies", func() {
+			BeforeEach(func() {
+				validator.Client = fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+					&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "payments", Labels: map[string]string{"team": "payments"}}},
+					&examplev1.JsonServerPolicy{
+						ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+						Spec: examplev1.JsonServerPolicySpec{
+							NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+							NamePatterns:      []string{"pay-.*"},
+							MaxReplicas:       ptr.To[int32](2),
+							MaxConfigSize:     ptr.To(resource.MustParse("64")),
+							AllowedImages:     []string{"registry.example.com/*"},
+							AllowedRegistries: []string{"registry.example.com"},
+							RequiredLabels:    []string{"owner"},
+						},
+					},
+				).Build()
+				obj.Namespace = "payments"
+			})
+
+			It("Should deny objects violating the policy of their namespace and name the policy", func() {
+				obj.Name = "app-sample"
+				obj.Spec.Replicas = 3
+				obj.Spec.JsonConfig = `{"people": [{"id": 1, "name": "Person A"}, {"id": 2, "name": "Person B"}]}`
+				_, err := validator.ValidateCreate(ctx, obj)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(`metadata.name: Invalid value: "app-sample": must match one of pay-.*`))
+				Expect(err.Error()).To(ContainSubstring(`spec.replicas: Invalid value: 3: must be at most 2`))
+				Expect(err.Error()).To(ContainSubstring(`spec.jsonConfig: Invalid value: "74 bytes": must be at most 64`))
+				Expect(err.Error()).To(ContainSubstring(`json-server image "backplane/json-server:0.17.4" must match one of registry.example.com/*`))
+				Expect(err.Error()).To(ContainSubstring(`json-server image "backplane/json-server:0.17.4" must be pulled from one of registry.example.com`))
+				Expect(err.Error()).To(ContainSubstring(`metadata.labels[owner]: Required value`))
+				Expect(err.Error()).To(ContainSubstring(`denied by JsonServerPolicy "payments"`))
+			})
+
+			It("Should match the name patterns against whole names", func() {
+				obj.Name = "xpay-sample"
+				Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring(
+					`metadata.name: Invalid value: "xpay-sample": must match one of pay-.*`)))
+			})
+
+			It("Should allow annotating objects created before the policy was tightened", func() {
+				obj.Name = "app-sample"
+				oldObj = obj.DeepCopy()
+				obj.Annotations = map[string]string{examplev1.ActivatedAtAnnotation: "2025-01-01T00:00:00Z"}
+				Expect(validator.ValidateUpdate(ctx, oldObj, obj)).Error().NotTo(HaveOccurred())
+
+				obj.Spec.Replicas = 2
+				Expect(validator.ValidateUpdate(ctx, oldObj, obj)).Error().To(MatchError(ContainSubstring(`denied by JsonServerPolicy "payments"`)))
+			})
+
+			It("Should cap the autoscaled replicas", func() {
+				obj.Name = "pay-sample"
+				obj.Labels = map[string]string{"owner": "payments"}
+				obj.Spec.Image = "registry.example.com/json-server"
+				obj.Spec.Consistency = examplev1.ConsistencyReadOnly
+				obj.Spec.Autoscaling = &examplev1.AutoscalingSpec{MaxReplicas: 5}
+				_, err := validator.ValidateCreate(ctx, obj)
+				Expect(err).To(MatchError(ContainSubstring(`spec.autoscaling.maxReplicas: Invalid value: 5: must be at most 2`)))
+
+				obj.Spec.Autoscaling.MaxReplicas = 2
+				_, err = validator.ValidateCreate(ctx, obj)
+				Expect(err).NotTo(HaveOccurred())
+			})
+
+			It("Should replace the default naming convention", func() {
+				obj.Name = "pay-sample"
+				_, err := validator.ValidateCreate(ctx, obj)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).NotTo(ContainSubstring("app-${name}"))
+			})
+
+			It("Should keep the default naming convention in namespaces no policy selects", func() {
+				obj.Namespace = "default"
+				obj.Name = "pay-sample"
+				Expect(validator.ValidateCreate(ctx, obj)).Error().To(MatchError(ContainSubstring("app-${name}")))
+			})
+		})
+
+		It("Should validate updates correctly", func() {
+			obj.Spec.Replicas = 2
+			Expect(validator.ValidateUpdate(ctx, oldObj, obj)).Error().NotTo(HaveOccurred())
+		})
 	})
 
 })
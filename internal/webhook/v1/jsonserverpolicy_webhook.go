@@ -0,0 +1,122 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+// nolint:unused
+// log is for logging in this package.
+var jsonserverpolicylog = logf.Log.WithName("jsonserverpolicy-resource")
+
+// SetupJsonServerPolicyWebhookWithManager registers the webhook for JsonServerPolicy in the manager.
+func SetupJsonServerPolicyWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&examplev1.JsonServerPolicy{}).
+		WithValidator(&JsonServerPolicyCustomValidator{}).
+		Complete()
+}
+
+// NOTE: The 'path' attribute must follow a specific pattern and should not be modified directly here.
+// Modifying the path for an invalid path can cause API server errors; failing to locate the webhook.
+// +kubebuilder:webhook:path=/validate-example-example-com-v1-jsonserverpolicy,mutating=false,failurePolicy=fail,sideEffects=None,groups=example.example.com,resources=jsonserverpolicies,verbs=create;update,versions=v1,name=vjsonserverpolicy-v1.kb.io,admissionReviewVersions=v1
+
+// JsonServerPolicyCustomValidator struct is responsible for validating the JsonServerPolicy resource
+// when it is created or updated, so that an invalid policy never fails the admission of the
+// JsonServers it selects.
+//
+// NOTE: The +kubebuilder:object:generate=false marker prevents controller-gen from generating DeepCopy methods,
+// as this struct is used only for temporary operations and does not need to be deeply copied.
+type JsonServerPolicyCustomValidator struct{}
+
+var _ webhook.CustomValidator = &JsonServerPolicyCustomValidator{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type JsonServerPolicy.
+func (v *JsonServerPolicyCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	policy, ok := obj.(*examplev1.JsonServerPolicy)
+	if !ok {
+		return nil, fmt.Errorf("expected a JsonServerPolicy object but got %T", obj)
+	}
+	jsonserverpolicylog.Info("Validation for JsonServerPolicy upon creation", "name", policy.GetName())
+
+	return nil, validateJsonServerPolicy(policy)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type JsonServerPolicy.
+func (v *JsonServerPolicyCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	policy, ok := newObj.(*examplev1.JsonServerPolicy)
+	if !ok {
+		return nil, fmt.Errorf("expected a JsonServerPolicy object for the newObj but got %T", newObj)
+	}
+	jsonserverpolicylog.Info("Validation for JsonServerPolicy upon update", "name", policy.GetName())
+
+	return nil, validateJsonServerPolicy(policy)
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type JsonServerPolicy.
+func (v *JsonServerPolicyCustomValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validateJsonServerPolicy checks the selector and the patterns of the policy can be used
+func validateJsonServerPolicy(policy *examplev1.JsonServerPolicy) error {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if policy.Spec.NamespaceSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(policy.Spec.NamespaceSelector,
+			metav1validation.LabelSelectorValidationOptions{}, specPath.Child("namespaceSelector"))...)
+	}
+	for i, pattern := range policy.Spec.NamePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("namePatterns").Index(i), pattern,
+				fmt.Sprintf("must be a regular expression: %v", err)))
+		}
+	}
+	allErrs = append(allErrs, validateGlobPatterns(policy.Spec.AllowedImages, specPath.Child("allowedImages"))...)
+	allErrs = append(allErrs, validateGlobPatterns(policy.Spec.AllowedRegistries, specPath.Child("allowedRegistries"))...)
+
+	if len(allErrs) > 0 {
+		return apierrors.NewInvalid(examplev1.GroupVersion.WithKind("JsonServerPolicy").GroupKind(), policy.Name, allErrs)
+	}
+	return nil
+}
+
+// validateGlobPatterns checks the patterns are valid glob patterns, invalid patterns never match
+func validateGlobPatterns(patterns []string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), pattern, "must be a glob pattern"))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	examplev1 "jsonserver-operator/api/v1"
+)
+
+var _ = Describe("JsonServerPolicy Webhook", func() {
+	var (
+		obj       *examplev1.JsonServerPolicy
+		validator JsonServerPolicyCustomValidator
+	)
+
+	BeforeEach(func() {
+		obj = &examplev1.JsonServerPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "payments"},
+			Spec: examplev1.JsonServerPolicySpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "payments"}},
+				NamePatterns:      []string{"pay-.*"},
+				AllowedImages:     []string{"registry.example.com/*"},
+				AllowedRegistries: []string{"registry.example.com"},
+			},
+		}
+		validator = JsonServerPolicyCustomValidator{}
+	})
+
+	Context("When creating or updating JsonServerPolicy under Validating Webhook", func() {
+		It("Should admit valid policies", func() {
+			Expect(validator.ValidateCreate(ctx, obj)).Error().NotTo(HaveOccurred())
+			Expect(validator.ValidateUpdate(ctx, obj.DeepCopy(), obj)).Error().NotTo(HaveOccurred())
+		})
+
+		It("Should deny patterns and selectors the JsonServer admission cannot use", func() {
+			obj.Spec.NamePatterns = []string{"pay-.*", "(pay"}
+			obj.Spec.AllowedImages = []string{"registry.example.com/[*"}
+			obj.Spec.NamespaceSelector.MatchLabels = map[string]string{"team": "pay ments"}
+			_, err := validator.ValidateCreate(ctx, obj)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(`spec.namePatterns[1]: Invalid value: "(pay": must be a regular expression`))
+			Expect(err.Error()).To(ContainSubstring(`spec.allowedImages[0]: Invalid value: "registry.example.com/[*": must be a glob pattern`))
+			Expect(err.Error()).To(ContainSubstring(`spec.namespaceSelector.matchLabels`))
+
+			Expect(validator.ValidateUpdate(ctx, obj.DeepCopy(), obj)).Error().To(HaveOccurred())
+		})
+	})
+
+	It("Should compile the name patterns once per generation of the policy", func() {
+		patterns := &policyPatterns{}
+		obj.UID, obj.Generation = "policy-uid", 1
+		compiled, err := patterns.namePatterns(obj)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(patterns.namePatterns(obj)).To(HaveExactElements(BeIdenticalTo(compiled[0])))
+
+		obj.Generation = 2
+		obj.Spec.NamePatterns = []string{"(pay"}
+		_, err = patterns.namePatterns(obj)
+		Expect(err).To(MatchError(ContainSubstring(`invalid name pattern "(pay"`)))
+
+		patterns.retain(nil)
+		Expect(patterns.policies).To(BeEmpty())
+	})
+})
@@ -0,0 +1,250 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/image"
+)
+
+// defaultNamePrefix is the naming convention enforced in namespaces no JsonServerPolicy selects
+const defaultNamePrefix = "app-"
+
+// +kubebuilder:rbac:groups=example.example.com,resources=jsonserverpolicies,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
+
+// policyPatterns holds the name patterns of the JsonServerPolicies, compiled once per
+// generation of each policy rather than on every admission
+type policyPatterns struct {
+	mu       sync.Mutex
+	policies map[types.UID]compiledPatterns
+}
+
+// compiledPatterns are the name patterns of a generation of a JsonServerPolicy
+type compiledPatterns struct {
+	generation int64
+	patterns   []*regexp.Regexp
+	err        error
+}
+
+// namePatterns returns the compiled name patterns of the policy
+func (p *policyPatterns) namePatterns(policy *examplev1.JsonServerPolicy) ([]*regexp.Regexp, error) {
+	if p == nil {
+		return compileNamePatterns(policy.Spec.NamePatterns)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if compiled, ok := p.policies[policy.UID]; ok && compiled.generation == policy.Generation {
+		return compiled.patterns, compiled.err
+	}
+	compiled := compiledPatterns{generation: policy.Generation}
+	compiled.patterns, compiled.err = compileNamePatterns(policy.Spec.NamePatterns)
+	if p.policies == nil {
+		p.policies = map[types.UID]compiledPatterns{}
+	}
+	p.policies[policy.UID] = compiled
+	return compiled.patterns, compiled.err
+}
+
+// retain forgets the patterns of the policies that no longer exist
+func (p *policyPatterns) retain(policies []examplev1.JsonServerPolicy) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.policies) == 0 {
+		return
+	}
+	existing := make(map[types.UID]bool, len(policies))
+	for _, policy := range policies {
+		existing[policy.UID] = true
+	}
+	for uid := range p.policies {
+		if !existing[uid] {
+			delete(p.policies, uid)
+		}
+	}
+}
+
+// compileNamePatterns compiles the name patterns of a JsonServerPolicy, anchored so that they
+// match whole names
+func compileNamePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// matchingPolicies returns the JsonServerPolicies selecting the namespace
+func matchingPolicies(ctx context.Context, reader client.Reader, patterns *policyPatterns, namespace string) ([]examplev1.JsonServerPolicy, error) {
+	policies := &examplev1.JsonServerPolicyList{}
+	if err := reader.List(ctx, policies); err != nil {
+		return nil, fmt.Errorf("failed to list JsonServerPolicies: %w", err)
+	}
+	patterns.retain(policies.Items)
+	if len(policies.Items) == 0 {
+		return nil, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := reader.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return nil, fmt.Errorf("failed to get namespace %q: %w", namespace, err)
+	}
+
+	var matching []examplev1.JsonServerPolicy
+	for _, policy := range policies.Items {
+		selector := labels.Everything()
+		if policy.Spec.NamespaceSelector != nil {
+			var err error
+			selector, err = metav1.LabelSelectorAsSelector(policy.Spec.NamespaceSelector)
+			if err != nil {
+				return nil, fmt.Errorf("JsonServerPolicy %q has an invalid namespaceSelector: %w", policy.Name, err)
+			}
+		}
+		if selector.Matches(labels.Set(ns.Labels)) {
+			matching = append(matching, policy)
+		}
+	}
+	return matching, nil
+}
+
+// validateDefaultPolicy enforces the naming convention of namespaces no JsonServerPolicy selects
+func validateDefaultPolicy(jsonserver *examplev1.JsonServer) field.ErrorList {
+	if !strings.HasPrefix(jsonserver.Name, defaultNamePrefix) {
+		return field.ErrorList{field.Invalid(field.NewPath("metadata", "name"), jsonserver.Name,
+			fmt.Sprintf("JsonServer name must follow the convention '%s${name}'", defaultNamePrefix))}
+	}
+	return nil
+}
+
+// validatePolicy checks the JsonServer against the constraints of a JsonServerPolicy.
+// Every error names the policy that rejected the object.
+func validatePolicy(policy *examplev1.JsonServerPolicy, patterns *policyPatterns, jsonserver *examplev1.JsonServer, jsonImage string) field.ErrorList {
+	var allErrs field.ErrorList
+	deniedBy := fmt.Sprintf("denied by JsonServerPolicy %q", policy.Name)
+
+	if len(policy.Spec.NamePatterns) > 0 {
+		namePath := field.NewPath("metadata", "name")
+		// Only policies created while the JsonServerPolicy webhook was not running can hold
+		// invalid patterns
+		namePatterns, err := patterns.namePatterns(policy)
+		if err != nil {
+			allErrs = append(allErrs, field.InternalError(namePath, fmt.Errorf("JsonServerPolicy %q: %w", policy.Name, err)))
+		}
+		matched := false
+		for _, re := range namePatterns {
+			if re.MatchString(jsonserver.Name) {
+				matched = true
+				break
+			}
+		}
+		if err == nil && !matched {
+			allErrs = append(allErrs, field.Invalid(namePath, jsonserver.Name,
+				fmt.Sprintf("must match one of %s: %s", strings.Join(policy.Spec.NamePatterns, ", "), deniedBy)))
+		}
+	}
+
+	if policy.Spec.MaxReplicas != nil && jsonserver.Spec.Replicas > *policy.Spec.MaxReplicas {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "replicas"), jsonserver.Spec.Replicas,
+			fmt.Sprintf("must be at most %d: %s", *policy.Spec.MaxReplicas, deniedBy)))
+	}
+	if autoscaling := jsonserver.Spec.Autoscaling; policy.Spec.MaxReplicas != nil && autoscaling != nil &&
+		autoscaling.MaxReplicas > *policy.Spec.MaxReplicas {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "autoscaling", "maxReplicas"), autoscaling.MaxReplicas,
+			fmt.Sprintf("must be at most %d: %s", *policy.Spec.MaxReplicas, deniedBy)))
+	}
+
+	if size := inlineConfigSize(jsonserver); policy.Spec.MaxConfigSize != nil && int64(size) > policy.Spec.MaxConfigSize.Value() {
+		fldPath := field.NewPath("spec", "jsonConfig")
+		if len(jsonserver.Spec.Collections) > 0 {
+			fldPath = field.NewPath("spec")
+		}
+		allErrs = append(allErrs, field.Invalid(fldPath, fmt.Sprintf("%d bytes", size),
+			fmt.Sprintf("must be at most %s: %s", policy.Spec.MaxConfigSize.String(), deniedBy)))
+	}
+
+	if len(policy.Spec.AllowedImages) > 0 && !imageAllowed(jsonImage, policy.Spec.AllowedImages) {
+		allErrs = append(allErrs, field.Forbidden(imagePath(jsonserver),
+			fmt.Sprintf("json-server image %q must match one of %s: %s", jsonImage, strings.Join(policy.Spec.AllowedImages, ", "), deniedBy)))
+	}
+
+	if len(policy.Spec.AllowedRegistries) > 0 {
+		// Unparseable images are reported by validateImage
+		if allowed, err := image.RegistryAllowed(jsonImage, policy.Spec.AllowedRegistries); err == nil && !allowed {
+			allErrs = append(allErrs, field.Forbidden(imagePath(jsonserver),
+				fmt.Sprintf("json-server image %q must be pulled from one of %s: %s", jsonImage, strings.Join(policy.Spec.AllowedRegistries, ", "), deniedBy)))
+		}
+	}
+
+	for _, key := range policy.Spec.RequiredLabels {
+		if _, ok := jsonserver.Labels[key]; !ok {
+			allErrs = append(allErrs, field.Required(field.NewPath("metadata", "labels").Key(key), deniedBy))
+		}
+	}
+
+	return allErrs
+}
+
+// imagePath is the field the json-server image comes from, spec when the operator default is used
+func imagePath(jsonserver *examplev1.JsonServer) *field.Path {
+	if jsonserver.Spec.Image == "" {
+		return field.NewPath("spec")
+	}
+	return field.NewPath("spec", "image")
+}
+
+// inlineConfigSize returns the size of the configuration written in the spec: jsonConfig and
+// the data of the collections
+func inlineConfigSize(jsonserver *examplev1.JsonServer) int {
+	size := len(jsonserver.Spec.JsonConfig)
+	for _, collection := range jsonserver.Spec.Collections {
+		size += len(collection.Data)
+	}
+	return size
+}
+
+// imageAllowed reports whether the image matches one of the glob patterns
+func imageAllowed(jsonImage string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, jsonImage); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
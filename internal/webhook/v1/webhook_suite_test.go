@@ -40,6 +40,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	examplev1 "jsonserver-operator/api/v1"
+	"jsonserver-operator/internal/config"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -109,7 +110,10 @@ var _ = BeforeSuite(func() {
 	})
 	Expect(err).NotTo(HaveOccurred())
 
-	err = SetupJsonServerWebhookWithManager(mgr)
+	err = SetupJsonServerWebhookWithManager(mgr, config.Default())
+	Expect(err).NotTo(HaveOccurred())
+
+	err = SetupJsonServerPolicyWebhookWithManager(mgr)
 	Expect(err).NotTo(HaveOccurred())
 
 	// +kubebuilder:scaffold:webhook